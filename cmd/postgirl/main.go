@@ -47,6 +47,17 @@ func main() {
 			tui = true
 		case "web":
 			web = true
+		case "save-response":
+			// save-response <response-id> <file>
+			if len(args) != 3 {
+				fmt.Println("Usage: postgirl save-response <response-id> <file>")
+				os.Exit(2)
+			}
+			if err := saveResponse(args[1], args[2]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
 		}
 	}
 
@@ -95,7 +106,10 @@ func startWebInterface(port int) {
 	}
 	
 	// Initialize service
-	service := app.NewService(storageInstance)
+	service, err := app.NewService(storageInstance)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
 	
 	// Create and start web server
 	server := web.NewServer(service, port, webAssets)
@@ -105,6 +119,28 @@ func startWebInterface(port int) {
 	}
 }
 
+// saveResponse writes the body of a stored response to a file
+func saveResponse(id, path string) error {
+	sqliteStorage, err := sqlite.NewSQLiteStorage("postgirl.db")
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer sqliteStorage.Close()
+
+	// Only stored responses are needed, so settings that fail to load don't matter
+	service, _ := app.NewService(sqliteStorage)
+	resp, err := service.GetResponse(id)
+	if err != nil {
+		return fmt.Errorf("response not found: %s", id)
+	}
+	if err := service.SaveResponseBody(resp, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved %d bytes (%s) to %s\n", resp.Size, resp.ContentType, path)
+	return nil
+}
+
 // showInteractiveMenu shows an interactive menu to choose interface
 func showInteractiveMenu() {
 	clearScreen()
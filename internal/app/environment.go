@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"postgirl/internal/models"
@@ -11,6 +12,7 @@ import (
 
 // EnvironmentService handles environment variable operations
 type EnvironmentService struct {
+	mu           sync.RWMutex
 	environments map[string]*models.Environment
 }
 
@@ -23,11 +25,15 @@ func NewEnvironmentService() *EnvironmentService {
 
 // SetEnvironment sets an environment
 func (es *EnvironmentService) SetEnvironment(env *models.Environment) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
 	es.environments[env.ID] = env
 }
 
 // GetEnvironment gets an environment by ID
 func (es *EnvironmentService) GetEnvironment(id string) (*models.Environment, error) {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
 	env, exists := es.environments[id]
 	if !exists {
 		return nil, fmt.Errorf("environment not found: %s", id)
@@ -37,6 +43,8 @@ func (es *EnvironmentService) GetEnvironment(id string) (*models.Environment, er
 
 // ListEnvironments returns all environments
 func (es *EnvironmentService) ListEnvironments() []*models.Environment {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
 	var envs []*models.Environment
 	for _, env := range es.environments {
 		envs = append(envs, env)
@@ -62,8 +70,8 @@ func (es *EnvironmentService) SubstituteVariables(text string, envID string) (st
 		// Extract variable name from {{variable}}
 		variableName := strings.Trim(match, "{}")
 		
-		// Check if variable exists in environment
-		if value, exists := env.Variables[variableName]; exists {
+		// Check if an enabled variable exists in environment
+		if value, exists := env.Variables.Get(variableName); exists {
 			return value
 		}
 		
@@ -85,19 +93,13 @@ func (es *EnvironmentService) SubstituteRequestVariables(req *models.Request, en
 	}
 
 	// Substitute headers
-	for key, value := range req.Headers {
-		req.Headers[key], err = es.SubstituteVariables(value, envID)
-		if err != nil {
-			return fmt.Errorf("failed to substitute header variables: %w", err)
-		}
+	if err := es.substituteKeyValues(req.Headers, envID); err != nil {
+		return fmt.Errorf("failed to substitute header variables: %w", err)
 	}
 
 	// Substitute query parameters
-	for key, value := range req.QueryParams {
-		req.QueryParams[key], err = es.SubstituteVariables(value, envID)
-		if err != nil {
-			return fmt.Errorf("failed to substitute query parameter variables: %w", err)
-		}
+	if err := es.substituteKeyValues(req.QueryParams, envID); err != nil {
+		return fmt.Errorf("failed to substitute query parameter variables: %w", err)
 	}
 
 	// Substitute body content
@@ -106,6 +108,51 @@ func (es *EnvironmentService) SubstituteRequestVariables(req *models.Request, en
 		if err != nil {
 			return fmt.Errorf("failed to substitute body variables: %w", err)
 		}
+		req.Body.FilePath, err = es.SubstituteVariables(req.Body.FilePath, envID)
+		if err != nil {
+			return fmt.Errorf("failed to substitute body variables: %w", err)
+		}
+		for _, field := range []*string{&req.Body.Query, &req.Body.Variables, &req.Body.OperationName} {
+			*field, err = es.SubstituteVariables(*field, envID)
+			if err != nil {
+				return fmt.Errorf("failed to substitute body variables: %w", err)
+			}
+		}
+		for i := range req.Body.Parts {
+			part := &req.Body.Parts[i]
+			for _, field := range []*string{&part.Name, &part.Value, &part.FilePath, &part.ContentType, &part.Filename} {
+				*field, err = es.SubstituteVariables(*field, envID)
+				if err != nil {
+					return fmt.Errorf("failed to substitute body variables: %w", err)
+				}
+			}
+		}
+		if err := es.substituteKeyValues(req.Body.Fields, envID); err != nil {
+			return fmt.Errorf("failed to substitute body variables: %w", err)
+		}
+	}
+
+	// Substitute the paths of gRPC proto files
+	if req.GRPC != nil {
+		for _, paths := range [][]string{req.GRPC.ProtoFiles, req.GRPC.ImportPaths} {
+			for i, path := range paths {
+				paths[i], err = es.SubstituteVariables(path, envID)
+				if err != nil {
+					return fmt.Errorf("failed to substitute gRPC variables: %w", err)
+				}
+			}
+		}
+	}
+
+	// Substitute WebSocket subprotocols; message templates are substituted
+	// when they are sent
+	if req.WebSocket != nil {
+		for i, subprotocol := range req.WebSocket.Subprotocols {
+			req.WebSocket.Subprotocols[i], err = es.SubstituteVariables(subprotocol, envID)
+			if err != nil {
+				return fmt.Errorf("failed to substitute subprotocol variables: %w", err)
+			}
+		}
 	}
 
 	// Substitute auth config
@@ -121,15 +168,33 @@ func (es *EnvironmentService) SubstituteRequestVariables(req *models.Request, en
 	return nil
 }
 
+// substituteKeyValues substitutes variables in the keys and values of the
+// enabled entries of a list, in place
+func (es *EnvironmentService) substituteKeyValues(kvs models.KeyValues, envID string) error {
+	var err error
+	for i := range kvs {
+		if !kvs[i].Enabled {
+			continue
+		}
+		if kvs[i].Key, err = es.SubstituteVariables(kvs[i].Key, envID); err != nil {
+			return err
+		}
+		if kvs[i].Value, err = es.SubstituteVariables(kvs[i].Value, envID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CreateDefaultEnvironment creates a default environment
 func (es *EnvironmentService) CreateDefaultEnvironment() *models.Environment {
 	return &models.Environment{
 		ID:        "default",
 		Name:      "Default Environment",
-		Variables: map[string]string{
-			"base_url": "https://api.example.com",
-			"api_key":  "your-api-key-here",
-			"timeout":  "30",
+		Variables: models.EnvironmentVariables{
+			{Key: "base_url", Value: "https://api.example.com", Enabled: true},
+			{Key: "api_key", Value: "your-api-key-here", Enabled: true},
+			{Key: "timeout", Value: "30", Enabled: true},
 		},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -141,10 +206,10 @@ func (es *EnvironmentService) CreateDevelopmentEnvironment() *models.Environment
 	return &models.Environment{
 		ID:        "development",
 		Name:      "Development Environment",
-		Variables: map[string]string{
-			"base_url": "http://localhost:3000",
-			"api_key":  "dev-api-key",
-			"timeout":  "10",
+		Variables: models.EnvironmentVariables{
+			{Key: "base_url", Value: "http://localhost:3000", Enabled: true},
+			{Key: "api_key", Value: "dev-api-key", Enabled: true},
+			{Key: "timeout", Value: "10", Enabled: true},
 		},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -156,10 +221,10 @@ func (es *EnvironmentService) CreateProductionEnvironment() *models.Environment
 	return &models.Environment{
 		ID:        "production",
 		Name:      "Production Environment",
-		Variables: map[string]string{
-			"base_url": "https://api.production.com",
-			"api_key":  "prod-api-key",
-			"timeout":  "30",
+		Variables: models.EnvironmentVariables{
+			{Key: "base_url", Value: "https://api.production.com", Enabled: true},
+			{Key: "api_key", Value: "prod-api-key", Enabled: true},
+			{Key: "timeout", Value: "30", Enabled: true},
 		},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
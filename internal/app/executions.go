@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// executions tracks in-flight request executions so they can be cancelled by ID
+type executions struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// newExecutions creates an empty execution registry
+func newExecutions() *executions {
+	return &executions{cancels: make(map[string]context.CancelFunc)}
+}
+
+// start registers an execution and returns its cancellable context and a
+// function to call once it has finished
+func (e *executions) start(ctx context.Context, id string) (context.Context, func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.cancels[id]; exists {
+		cancel()
+		return nil, nil, fmt.Errorf("execution already running: %s", id)
+	}
+	e.cancels[id] = cancel
+
+	return ctx, func() {
+		e.mu.Lock()
+		delete(e.cancels, id)
+		e.mu.Unlock()
+		cancel()
+	}, nil
+}
+
+// cancel cancels a running execution
+func (e *executions) cancel(id string) error {
+	e.mu.Lock()
+	cancel, exists := e.cancels[id]
+	e.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("execution not found: %s", id)
+	}
+	cancel()
+	return nil
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"postgirl/internal/graphql"
+	"postgirl/internal/http"
+	"postgirl/internal/models"
+)
+
+// FetchGraphQLSchema runs an introspection query against the endpoint a
+// request is sent to and caches the schema in storage
+func (s *Service) FetchGraphQLSchema(ctx context.Context, req *models.Request) (*models.GraphQLSchema, error) {
+	requestCopy, environment, err := s.prepareRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := s.httpClient.Introspect(ctx, requestCopy, environment)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.SaveGraphQLSchema(schema); err != nil {
+		return nil, fmt.Errorf("failed to save schema: %w", err)
+	}
+	return schema, nil
+}
+
+// GetGraphQLSchema returns the cached schema of the endpoint at a URL, or nil
+// if it has not been fetched
+func (s *Service) GetGraphQLSchema(url string) (*models.GraphQLSchema, error) {
+	return s.storage.GetGraphQLSchema(http.GraphQLEndpoint(url))
+}
+
+// CompleteGraphQL suggests what may be typed at a byte offset of a query sent
+// to the endpoint at a URL. There are no suggestions until its schema is fetched.
+func (s *Service) CompleteGraphQL(url, query string, offset int) (graphql.Completion, error) {
+	schema, err := s.GetGraphQLSchema(url)
+	if err != nil {
+		return graphql.Completion{}, err
+	}
+	if schema == nil {
+		return graphql.Completion{Suggestions: []graphql.Suggestion{}}, nil
+	}
+	return graphql.Complete(schema, query, offset), nil
+}
+
+// validateGraphQL checks the query of a run's GraphQL request against the
+// cached schema of its endpoint. Queries to endpoints without a cached schema
+// are sent as they are, as are those whose schema fails to load, with a warning.
+func (s *Service) validateGraphQL(exec *models.Execution) error {
+	req := exec.Request
+	if req.Body == nil || req.Body.Type != "graphql" {
+		return nil
+	}
+	schema, err := s.GetGraphQLSchema(req.URL)
+	if err != nil {
+		exec.Warnings = append(exec.Warnings, fmt.Sprintf("failed to load GraphQL schema: %v", err))
+		return nil
+	}
+	if schema == nil {
+		return nil
+	}
+	if err := graphql.Validate(schema, req.Body); err != nil {
+		return fmt.Errorf("invalid GraphQL query: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package app
+
+import (
+	"context"
+
+	"postgirl/internal/models"
+)
+
+// DescribeGRPC lists the services and methods of the server a gRPC request
+// is sent to, from the request's proto files or by server reflection. gRPC
+// calls themselves go through ExecuteRequest like HTTP requests.
+func (s *Service) DescribeGRPC(ctx context.Context, req *models.Request) ([]models.GRPCService, error) {
+	requestCopy, environment, err := s.prepareRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.httpClient.DescribeGRPC(ctx, requestCopy, environment)
+}
@@ -0,0 +1,500 @@
+// The Postman pm API for test scripts. newPostman builds a pm object for one
+// run; record(name, status, message) receives the outcome of each pm.test.
+(function () {
+    'use strict';
+
+    class AssertionError extends Error {
+        constructor(message) {
+            super(message);
+            this.name = 'AssertionError';
+        }
+    }
+
+    // inspect formats a value for assertion messages the way chai does
+    function inspect(value) {
+        if (typeof value === 'string') {
+            return "'" + value + "'";
+        }
+        if (value === undefined || typeof value === 'function' || typeof value === 'symbol') {
+            return String(value);
+        }
+        if (value instanceof RegExp) {
+            return value.toString();
+        }
+        try {
+            return JSON.stringify(value);
+        } catch (e) {
+            return String(value);
+        }
+    }
+
+    function typeOf(value) {
+        if (value === null) {
+            return 'null';
+        }
+        if (Array.isArray(value)) {
+            return 'array';
+        }
+        if (value instanceof RegExp) {
+            return 'regexp';
+        }
+        if (value instanceof Date) {
+            return 'date';
+        }
+        return typeof value;
+    }
+
+    function deepEqual(a, b) {
+        if (a === b) {
+            return a !== 0 || 1 / a === 1 / b;
+        }
+        if (a !== a && b !== b) {
+            return true; // NaN
+        }
+        if (typeOf(a) !== typeOf(b) || typeof a !== 'object') {
+            return false;
+        }
+        if (a instanceof Date) {
+            return a.getTime() === b.getTime();
+        }
+        const keysA = Object.keys(a);
+        const keysB = Object.keys(b);
+        if (keysA.length !== keysB.length) {
+            return false;
+        }
+        return keysA.every(key => Object.prototype.hasOwnProperty.call(b, key) && deepEqual(a[key], b[key]));
+    }
+
+    function isEmpty(value) {
+        if (typeof value === 'string' || Array.isArray(value)) {
+            return value.length === 0;
+        }
+        if (value instanceof Map || value instanceof Set) {
+            return value.size === 0;
+        }
+        if (value !== null && typeof value === 'object') {
+            return Object.keys(value).length === 0;
+        }
+        throw new TypeError('.empty was passed non-string primitive ' + inspect(value));
+    }
+
+    // isResponse reports whether a value is pm.response, which some
+    // assertions such as status and header apply to
+    function isResponse(value) {
+        return value !== null && typeof value === 'object' && typeof value.code === 'number' && value.headers && typeof value.headers.get === 'function';
+    }
+
+    // Assertion is a chai-style expect chain
+    class Assertion {
+        constructor(subject, message) {
+            this._subject = subject;
+            this._message = message;
+            this._negate = false;
+            this._deep = false;
+        }
+
+        // assert throws unless ok holds, or holds negated after .not
+        assert(ok, message, negatedMessage) {
+            if (this._negate ? ok : !ok) {
+                const text = this._negate ? negatedMessage : message;
+                throw new AssertionError(this._message ? this._message + ': ' + text : text);
+            }
+            return this;
+        }
+
+        get not() {
+            this._negate = !this._negate;
+            return this;
+        }
+
+        get deep() {
+            this._deep = true;
+            return this;
+        }
+
+        equal(expected) {
+            const s = this._subject;
+            const ok = this._deep ? deepEqual(s, expected) : s === expected;
+            const to = this._deep ? 'deeply equal' : 'equal';
+            return this.assert(ok, `expected ${inspect(s)} to ${to} ${inspect(expected)}`, `expected ${inspect(s)} to not ${to} ${inspect(expected)}`);
+        }
+
+        eql(expected) {
+            const s = this._subject;
+            return this.assert(deepEqual(s, expected), `expected ${inspect(s)} to deeply equal ${inspect(expected)}`, `expected ${inspect(s)} to not deeply equal ${inspect(expected)}`);
+        }
+
+        a(type) {
+            const s = this._subject;
+            const article = /^[aeiou]/i.test(type) ? 'an' : 'a';
+            return this.assert(typeOf(s) === type.toLowerCase(), `expected ${inspect(s)} to be ${article} ${type}`, `expected ${inspect(s)} not to be ${article} ${type}`);
+        }
+
+        include(expected) {
+            const s = this._subject;
+            let ok;
+            if (typeof s === 'string') {
+                ok = s.indexOf(expected) !== -1;
+            } else if (Array.isArray(s)) {
+                ok = s.some(item => this._deep ? deepEqual(item, expected) : item === expected);
+            } else if (s !== null && typeof s === 'object') {
+                ok = Object.keys(expected).every(key => this._deep ? deepEqual(s[key], expected[key]) : s[key] === expected[key]);
+            } else {
+                throw new AssertionError(`object tested must be an array, an object, or a string, but ${typeOf(s)} given`);
+            }
+            return this.assert(ok, `expected ${inspect(s)} to include ${inspect(expected)}`, `expected ${inspect(s)} to not include ${inspect(expected)}`);
+        }
+
+        // property asserts the subject has a property, optionally with a
+        // value, and moves the chain on to the property's value
+        property(name, ...value) {
+            const s = this._subject;
+            const has = s !== null && s !== undefined && name in Object(s);
+            if (value.length > 0) {
+                const ok = has && (this._deep ? deepEqual(s[name], value[0]) : s[name] === value[0]);
+                this.assert(ok, `expected ${inspect(s)} to have property '${name}' of ${inspect(value[0])}, but got ${inspect(has ? s[name] : undefined)}`,
+                    `expected ${inspect(s)} to not have property '${name}' of ${inspect(value[0])}`);
+            } else {
+                this.assert(has, `expected ${inspect(s)} to have property '${name}'`, `expected ${inspect(s)} to not have property '${name}'`);
+            }
+            if (has) {
+                this._subject = s[name];
+            }
+            return this;
+        }
+
+        lengthOf(n) {
+            const s = this._subject;
+            const length = s instanceof Map || s instanceof Set ? s.size : (s === null || s === undefined ? undefined : s.length);
+            return this.assert(length === n, `expected ${inspect(s)} to have a length of ${n} but got ${length}`, `expected ${inspect(s)} to not have a length of ${n}`);
+        }
+
+        above(n) {
+            return this.compare(this._subject > n, 'above', n);
+        }
+
+        least(n) {
+            return this.compare(this._subject >= n, 'at least', n);
+        }
+
+        below(n) {
+            return this.compare(this._subject < n, 'below', n);
+        }
+
+        most(n) {
+            return this.compare(this._subject <= n, 'at most', n);
+        }
+
+        within(low, high) {
+            const s = this._subject;
+            return this.assert(s >= low && s <= high, `expected ${inspect(s)} to be within ${low}..${high}`, `expected ${inspect(s)} to not be within ${low}..${high}`);
+        }
+
+        compare(ok, relation, n) {
+            const s = this._subject;
+            return this.assert(ok, `expected ${inspect(s)} to be ${relation} ${n}`, `expected ${inspect(s)} to not be ${relation} ${n}`);
+        }
+
+        match(pattern) {
+            const s = this._subject;
+            return this.assert(pattern.test(s), `expected ${inspect(s)} to match ${pattern}`, `expected ${inspect(s)} not to match ${pattern}`);
+        }
+
+        string(expected) {
+            const s = this._subject;
+            return this.assert(typeof s === 'string' && s.indexOf(expected) !== -1, `expected ${inspect(s)} to contain ${inspect(expected)}`, `expected ${inspect(s)} to not contain ${inspect(expected)}`);
+        }
+
+        oneOf(list) {
+            const s = this._subject;
+            const ok = list.some(item => this._deep ? deepEqual(item, s) : item === s);
+            return this.assert(ok, `expected ${inspect(s)} to be one of ${inspect(list)}`, `expected ${inspect(s)} to not be one of ${inspect(list)}`);
+        }
+
+        keys(...keys) {
+            if (keys.length === 1 && Array.isArray(keys[0])) {
+                keys = keys[0];
+            }
+            const s = this._subject;
+            const actual = s !== null && typeof s === 'object' ? Object.keys(s) : [];
+            const ok = keys.every(key => actual.indexOf(key) !== -1);
+            return this.assert(ok, `expected ${inspect(s)} to have keys ${inspect(keys)}`, `expected ${inspect(s)} to not have keys ${inspect(keys)}`);
+        }
+
+        members(expected) {
+            const s = this._subject;
+            const ok = Array.isArray(s) && s.length === expected.length &&
+                expected.every(item => s.some(member => this._deep ? deepEqual(member, item) : member === item));
+            return this.assert(ok, `expected ${inspect(s)} to have the same members as ${inspect(expected)}`, `expected ${inspect(s)} to not have the same members as ${inspect(expected)}`);
+        }
+
+        instanceOf(constructor) {
+            const s = this._subject;
+            return this.assert(s instanceof constructor, `expected ${inspect(s)} to be an instance of ${constructor.name}`, `expected ${inspect(s)} to not be an instance of ${constructor.name}`);
+        }
+
+        // status asserts the status code of pm.response, or its reason
+        // phrase when given a string
+        status(expected) {
+            const s = this.response('status');
+            const actual = typeof expected === 'string' ? s.status : s.code;
+            return this.assert(actual === expected, `expected response to have status ${inspect(expected)} but got ${inspect(actual)}`, `expected response to not have status ${inspect(expected)}`);
+        }
+
+        header(name, ...value) {
+            const s = this.response('header');
+            const has = s.headers.has(name);
+            if (value.length > 0) {
+                const actual = s.headers.get(name);
+                return this.assert(has && actual === value[0], `expected response to have header '${name}' of ${inspect(value[0])} but got ${inspect(actual)}`,
+                    `expected response to not have header '${name}' of ${inspect(value[0])}`);
+            }
+            return this.assert(has, `expected response to have header '${name}'`, `expected response to not have header '${name}'`);
+        }
+
+        body(...expected) {
+            const s = this.response('body');
+            const text = s.text();
+            if (expected.length === 0) {
+                return this.assert(text.length > 0, 'expected response to have a body', 'expected response to not have a body');
+            }
+            const ok = expected[0] instanceof RegExp ? expected[0].test(text) : text === expected[0];
+            return this.assert(ok, `expected response body to be ${inspect(expected[0])} but got ${inspect(text)}`, `expected response body to not be ${inspect(expected[0])}`);
+        }
+
+        // jsonBody asserts the response has a JSON body, optionally with a
+        // value at a dotted path such as 'data.items.0.id'
+        jsonBody(path, ...value) {
+            const s = this.response('jsonBody');
+            let json;
+            try {
+                json = s.json();
+            } catch (e) {
+                return this.assert(false, 'expected response body to be valid JSON', '');
+            }
+            if (path === undefined) {
+                return this.assert(true, '', 'expected response to not have a JSON body');
+            }
+            let current = json;
+            let found = true;
+            for (const key of String(path).split('.')) {
+                if (current === null || typeof current !== 'object' || !(key in current)) {
+                    found = false;
+                    break;
+                }
+                current = current[key];
+            }
+            if (value.length > 0) {
+                return this.assert(found && deepEqual(current, value[0]), `expected response JSON to have ${inspect(value[0])} at '${path}' but got ${inspect(found ? current : undefined)}`,
+                    `expected response JSON to not have ${inspect(value[0])} at '${path}'`);
+            }
+            return this.assert(found, `expected response JSON to have '${path}'`, `expected response JSON to not have '${path}'`);
+        }
+
+        // response returns the subject, which must be pm.response
+        response(assertion) {
+            if (!isResponse(this._subject)) {
+                throw new AssertionError(`.${assertion} applies to pm.response, not ${inspect(this._subject)}`);
+            }
+            return this._subject;
+        }
+
+        statusClass(low, name) {
+            const code = this.response(name).code;
+            return this.assert(code >= low && code < low + 100, `expected response code to be ${low / 100}XX but found ${code}`, `expected response code to not be ${low / 100}XX but found ${code}`);
+        }
+    }
+
+    // Words that only make chains read well
+    for (const word of ['to', 'be', 'been', 'is', 'that', 'which', 'and', 'has', 'have', 'with', 'at', 'of', 'same', 'but', 'does', 'still', 'also']) {
+        Object.defineProperty(Assertion.prototype, word, { get() { return this; } });
+    }
+
+    // Aliases, as in chai
+    const aliases = {
+        equal: ['equals', 'eq'],
+        eql: ['eqls'],
+        a: ['an'],
+        include: ['includes', 'contain', 'contains'],
+        lengthOf: ['length'],
+        above: ['gt', 'greaterThan'],
+        least: ['gte', 'greaterThanOrEqual'],
+        below: ['lt', 'lessThan'],
+        most: ['lte', 'lessThanOrEqual'],
+        keys: ['key'],
+        instanceOf: ['instanceof'],
+    };
+    for (const name of Object.keys(aliases)) {
+        for (const alias of aliases[name]) {
+            Assertion.prototype[alias] = Assertion.prototype[name];
+        }
+    }
+
+    // Assertions made by reading a property, such as .to.be.true
+    const properties = {
+        ok: s => [!!s, 'truthy'],
+        true: s => [s === true, 'true'],
+        false: s => [s === false, 'false'],
+        null: s => [s === null, 'null'],
+        undefined: s => [s === undefined, 'undefined'],
+        NaN: s => [s !== s, 'NaN'],
+        exist: s => [s !== null && s !== undefined, 'exist'],
+        empty: s => [isEmpty(s), 'empty'],
+    };
+    for (const name of Object.keys(properties)) {
+        Object.defineProperty(Assertion.prototype, name, {
+            get() {
+                if (name === 'ok' && isResponse(this._subject)) {
+                    return this.statusClass(200, 'ok');
+                }
+                const [ok, what] = properties[name](this._subject);
+                const verb = name === 'exist' ? '' : 'be ';
+                return this.assert(ok, `expected ${inspect(this._subject)} to ${verb}${what}`, `expected ${inspect(this._subject)} to not ${verb}${what}`);
+            },
+        });
+    }
+
+    // Response status classes, as in pm.response.to.be.success
+    const statusClasses = {
+        info: 100,
+        success: 200,
+        redirection: 300,
+        clientError: 400,
+        serverError: 500,
+    };
+    for (const name of Object.keys(statusClasses)) {
+        Object.defineProperty(Assertion.prototype, name, {
+            get() { return this.statusClass(statusClasses[name], name); },
+        });
+    }
+    Object.defineProperty(Assertion.prototype, 'error', {
+        get() {
+            const code = this.response('error').code;
+            return this.assert(code >= 400 && code < 600, `expected response code to be 4XX or 5XX but found ${code}`, `expected response code to not be 4XX or 5XX but found ${code}`);
+        },
+    });
+
+    // HeaderList reads a list of {key, value} headers by case-insensitive name
+    class HeaderList {
+        constructor(headers) {
+            this._headers = headers || [];
+        }
+
+        get(name) {
+            const lower = String(name).toLowerCase();
+            const header = this._headers.find(h => h.key.toLowerCase() === lower);
+            return header ? header.value : undefined;
+        }
+
+        has(name, value) {
+            const actual = this.get(name);
+            return actual !== undefined && (value === undefined || actual === value);
+        }
+
+        all() {
+            return this._headers.map(h => ({ key: h.key, value: h.value }));
+        }
+
+        toObject() {
+            const object = {};
+            for (const h of this._headers) {
+                object[h.key.toLowerCase()] = h.value;
+            }
+            return object;
+        }
+
+        count() {
+            return this._headers.length;
+        }
+    }
+
+    // VariableScope reads and sets variables for the rest of the run
+    class VariableScope {
+        constructor(values) {
+            this._values = Object.assign({}, values);
+        }
+
+        get(key) {
+            return this._values[key];
+        }
+
+        has(key) {
+            return Object.prototype.hasOwnProperty.call(this._values, key);
+        }
+
+        set(key, value) {
+            this._values[key] = value;
+        }
+
+        unset(key) {
+            delete this._values[key];
+        }
+
+        toObject() {
+            return Object.assign({}, this._values);
+        }
+    }
+
+    function newResponse(data) {
+        const response = {
+            code: data.code,
+            status: data.status,
+            headers: new HeaderList(data.headers),
+            responseTime: data.responseTime,
+            responseSize: data.responseSize,
+            text() {
+                return data.body;
+            },
+            json() {
+                return JSON.parse(data.body);
+            },
+        };
+        Object.defineProperty(response, 'to', { get() { return new Assertion(response).to; } });
+        return response;
+    }
+
+    function newPostman(data, record) {
+        const test = function (name, fn) {
+            if (typeof fn !== 'function') {
+                record(String(name), 'error', 'pm.test needs a function');
+                return test;
+            }
+            try {
+                fn();
+                record(String(name), 'passed', '');
+            } catch (e) {
+                if (e instanceof AssertionError) {
+                    record(String(name), 'failed', e.message);
+                } else {
+                    record(String(name), 'error', String(e));
+                }
+            }
+            return test;
+        };
+        test.skip = function (name) {
+            record(String(name), 'skipped', '');
+            return test;
+        };
+
+        const environment = new VariableScope(data.environment);
+        return {
+            test: test,
+            expect: (value, message) => new Assertion(value, message),
+            response: newResponse(data.response),
+            request: {
+                url: data.request.url,
+                method: data.request.method,
+                headers: new HeaderList(data.request.headers),
+                body: data.request.body,
+            },
+            environment: environment,
+            variables: environment,
+            info: {
+                requestId: data.request.id,
+                requestName: data.request.name,
+            },
+        };
+    }
+
+    return { newPostman: newPostman, AssertionError: AssertionError };
+})()
@@ -1,32 +1,65 @@
 package app
 
 import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/dop251/goja"
 	"postgirl/internal/models"
 )
 
+// postmanScript defines the pm API for test scripts
+//
+//go:embed pm.js
+var postmanScript string
+
 // ScriptEngine handles JavaScript execution
 type ScriptEngine struct {
 	vm *goja.Runtime
+	mu sync.Mutex // the runtime runs one script at a time
+
+	newPostman     goja.Callable // builds pm from postmanData and a record function
+	assertionError *goja.Object  // the constructor of pm.expect's failures
+	console        *consoleSink  // where the running script's console output goes
+}
+
+// consoleContextKey carries where scripts' console output goes on a context
+type consoleContextKey struct{}
+
+// consoleSink collects the console output of a run's scripts, each entry
+// labelled with the script that logged it
+type consoleSink struct {
+	entries *[]models.ConsoleEntry
+	script  string
+}
+
+// withConsole collects the console output of scripts run with ctx into
+// entries rather than printing it
+func withConsole(ctx context.Context, entries *[]models.ConsoleEntry, script string) context.Context {
+	return context.WithValue(ctx, consoleContextKey{}, &consoleSink{entries: entries, script: script})
 }
 
 // NewScriptEngine creates a new script engine
 func NewScriptEngine() *ScriptEngine {
 	vm := goja.New()
+	se := &ScriptEngine{vm: vm}
 	
 	// Add common utilities
-	vm.Set("console", map[string]interface{}{
-		"log": func(args ...interface{}) {
-			fmt.Println(args...)
-		},
-		"error": func(args ...interface{}) {
-			fmt.Print("ERROR: ")
-			fmt.Println(args...)
-		},
-	})
+	console := vm.NewObject()
+	for _, level := range []string{"log", "info", "warn", "error", "debug"} {
+		console.Set(level, func(call goja.FunctionCall) goja.Value {
+			se.log(level, call.Arguments)
+			return goja.Undefined()
+		})
+	}
+	vm.Set("console", console)
 	
 	// Add setTimeout and setInterval
 	vm.Set("setTimeout", func(callback goja.Callable, delay int) {
@@ -45,14 +78,24 @@ func NewScriptEngine() *ScriptEngine {
 		}()
 	})
 	
-	return &ScriptEngine{vm: vm}
+	postman, err := vm.RunScript("pm.js", postmanScript)
+	if err != nil {
+		// pm.js is part of the program, so it always runs
+		panic(fmt.Sprintf("pm.js: %v", err))
+	}
+	se.newPostman, _ = goja.AssertFunction(postman.ToObject(vm).Get("newPostman"))
+	se.assertionError = postman.ToObject(vm).Get("AssertionError").ToObject(vm)
+	return se
 }
 
 // ExecutePreScript executes a pre-request script
-func (se *ScriptEngine) ExecutePreScript(script string, request *models.Request, environment *models.Environment) error {
+func (se *ScriptEngine) ExecutePreScript(ctx context.Context, script string, request *models.Request, environment *models.Environment) error {
 	if script == "" {
 		return nil
 	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
 	
 	// Set up script context
 	se.vm.Set("request", map[string]interface{}{
@@ -60,8 +103,8 @@ func (se *ScriptEngine) ExecutePreScript(script string, request *models.Request,
 		"name":        request.Name,
 		"method":      request.Method,
 		"url":         request.URL,
-		"headers":     request.Headers,
-		"queryParams": request.QueryParams,
+		"headers":     request.Headers.Map(),
+		"queryParams": request.QueryParams.Map(),
 		"body":        request.Body,
 		"auth":        request.Auth,
 	})
@@ -70,13 +113,12 @@ func (se *ScriptEngine) ExecutePreScript(script string, request *models.Request,
 		se.vm.Set("environment", map[string]interface{}{
 			"id":        environment.ID,
 			"name":      environment.Name,
-			"variables": environment.Variables,
+			"variables": environment.Variables.Map(),
 		})
 	}
 	
 	// Execute the script
-	_, err := se.vm.RunString(script)
-	if err != nil {
+	if err := se.run(ctx, script); err != nil {
 		return fmt.Errorf("pre-script execution failed: %w", err)
 	}
 	
@@ -87,10 +129,13 @@ func (se *ScriptEngine) ExecutePreScript(script string, request *models.Request,
 }
 
 // ExecutePostScript executes a post-response script
-func (se *ScriptEngine) ExecutePostScript(script string, request *models.Request, response *models.Response, environment *models.Environment) error {
+func (se *ScriptEngine) ExecutePostScript(ctx context.Context, script string, request *models.Request, response *models.Response, environment *models.Environment) error {
 	if script == "" {
 		return nil
 	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
 	
 	// Set up script context
 	se.vm.Set("request", map[string]interface{}{
@@ -98,8 +143,8 @@ func (se *ScriptEngine) ExecutePostScript(script string, request *models.Request
 		"name":        request.Name,
 		"method":      request.Method,
 		"url":         request.URL,
-		"headers":     request.Headers,
-		"queryParams": request.QueryParams,
+		"headers":     request.Headers.Map(),
+		"queryParams": request.QueryParams.Map(),
 		"body":        request.Body,
 		"auth":        request.Auth,
 	})
@@ -107,7 +152,7 @@ func (se *ScriptEngine) ExecutePostScript(script string, request *models.Request
 	se.vm.Set("response", map[string]interface{}{
 		"id":         response.ID,
 		"statusCode": response.StatusCode,
-		"headers":    response.Headers,
+		"headers":    response.Headers.Map(),
 		"body":       response.Body,
 		"size":       response.Size,
 		"duration":   response.Duration.Milliseconds(),
@@ -118,24 +163,26 @@ func (se *ScriptEngine) ExecutePostScript(script string, request *models.Request
 		se.vm.Set("environment", map[string]interface{}{
 			"id":        environment.ID,
 			"name":      environment.Name,
-			"variables": environment.Variables,
+			"variables": environment.Variables.Map(),
 		})
 	}
 	
-	// Execute the script
-	_, err := se.vm.RunString(script)
-	if err != nil {
-		return fmt.Errorf("post-script execution failed: %w", err)
-	}
-	
-	return nil
+	// Execute the script; the run records which script failed
+	return se.run(ctx, script)
 }
 
-// ExecuteTestScript executes a test script and returns test results
-func (se *ScriptEngine) ExecuteTestScript(script string, request *models.Request, response *models.Response, environment *models.Environment) (*TestResult, error) {
-	if script == "" {
-		return &TestResult{Passed: true, Message: "No tests to run"}, nil
+// ExecuteTestScript runs a test's script with the Postman pm API and returns
+// the outcome of each pm.test it declares. A script that declares none, such
+// as one asserting with pm.expect directly, has a single result named after
+// the test; so does a script that throws outside its tests. The error is only
+// for a run stopped by ctx.
+func (se *ScriptEngine) ExecuteTestScript(ctx context.Context, test models.Test, request *models.Request, response *models.Response, environment *models.Environment) ([]models.TestResult, error) {
+	if test.Script == "" {
+		return nil, nil
 	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
 	
 	// Set up script context
 	se.vm.Set("request", map[string]interface{}{
@@ -143,8 +190,8 @@ func (se *ScriptEngine) ExecuteTestScript(script string, request *models.Request
 		"name":        request.Name,
 		"method":      request.Method,
 		"url":         request.URL,
-		"headers":     request.Headers,
-		"queryParams": request.QueryParams,
+		"headers":     request.Headers.Map(),
+		"queryParams": request.QueryParams.Map(),
 		"body":        request.Body,
 		"auth":        request.Auth,
 	})
@@ -152,68 +199,174 @@ func (se *ScriptEngine) ExecuteTestScript(script string, request *models.Request
 	se.vm.Set("response", map[string]interface{}{
 		"id":         response.ID,
 		"statusCode": response.StatusCode,
-		"headers":    response.Headers,
+		"headers":    response.Headers.Map(),
 		"body":       response.Body,
 		"size":       response.Size,
 		"duration":   response.Duration.Milliseconds(),
 		"timestamp":  response.CreatedAt,
 	})
 	
+	variables := map[string]string{}
 	if environment != nil {
+		variables = environment.Variables.Map()
 		se.vm.Set("environment", map[string]interface{}{
 			"id":        environment.ID,
 			"name":      environment.Name,
-			"variables": environment.Variables,
+			"variables": variables,
 		})
 	}
 	
-	// Add test utilities
-	se.vm.Set("pm", map[string]interface{}{
-		"test": func(name string, condition bool) {
-			// Test assertion
-		},
-		"expect": func(value interface{}) map[string]interface{} {
-			return map[string]interface{}{
-				"to": map[string]interface{}{
-					"be": func(expected interface{}) bool {
-						return value == expected
-					},
-					"equal": func(expected interface{}) bool {
-						return value == expected
-					},
-					"contain": func(substring string) bool {
-						if str, ok := value.(string); ok {
-							return contains(str, substring)
-						}
-						return false
-					},
-				},
-			}
-		},
-	})
-	
+	// Add the pm API, recording each test as it runs
+	var results []models.TestResult
+	record := func(name, status, message string) {
+		results = append(results, models.TestResult{Test: test.Name, Name: name, Status: status, Message: message})
+	}
+	pm, err := se.newPostman(goja.Undefined(), se.vm.ToValue(postmanData(request, response, variables)), se.vm.ToValue(record))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up pm: %w", err)
+	}
+	se.vm.Set("pm", pm)
+	// Older scripts set tests["name"] = condition instead of calling pm.test
+	tests := se.vm.NewObject()
+	se.vm.Set("tests", tests)
+
 	// Execute the script
-	_, err := se.vm.RunString(script)
+	err = se.run(ctx, test.Script)
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	for _, name := range tests.Keys() {
+		status := models.TestPassed
+		if !tests.Get(name).ToBoolean() {
+			status = models.TestFailed
+		}
+		record(name, status, "")
+	}
 	if err != nil {
-		return &TestResult{
-			Passed:  false,
-			Message: fmt.Sprintf("Test execution failed: %v", err),
-		}, nil
-	}
-	
-	// For now, return a basic test result
-	// In a real implementation, you'd capture test results from the script
-	return &TestResult{
-		Passed:  true,
-		Message: "All tests passed",
-	}, nil
+		status, message := se.describeError(err)
+		return append(results, models.TestResult{Test: test.Name, Name: test.Name, Status: status, Message: message}), nil
+	}
+	if len(results) == 0 {
+		record(test.Name, models.TestPassed, "")
+	}
+	return results, nil
+}
+
+// postmanData is what newPostman builds pm.request, pm.response and
+// pm.environment from
+func postmanData(request *models.Request, response *models.Response, variables map[string]string) map[string]interface{} {
+	var body string
+	if request.Body != nil {
+		body = request.Body.Content
+	}
+	return map[string]interface{}{
+		"request": map[string]interface{}{
+			"id":      request.ID,
+			"name":    request.Name,
+			"url":     request.URL,
+			"method":  request.Method,
+			"headers": headerObjects(request.Headers.Enabled()),
+			"body":    body,
+		},
+		"response": map[string]interface{}{
+			"code":         response.StatusCode,
+			"status":       http.StatusText(response.StatusCode),
+			"headers":      headerObjects(response.Headers),
+			"body":         response.Body,
+			"responseTime": response.Duration.Milliseconds(),
+			"responseSize": response.Size,
+		},
+		"environment": variables,
+	}
+}
+
+// headerObjects lists headers as {key, value} objects for scripts
+func headerObjects(headers models.KeyValues) []interface{} {
+	objects := make([]interface{}, len(headers))
+	for i, header := range headers {
+		objects[i] = map[string]interface{}{"key": header.Key, "value": header.Value}
+	}
+	return objects
+}
+
+// describeError tells a failed assertion thrown outside pm.test from any
+// other error a script throws, and describes it as pm.test would
+func (se *ScriptEngine) describeError(err error) (string, string) {
+	var exception *goja.Exception
+	if !errors.As(err, &exception) {
+		return models.TestError, err.Error()
+	}
+	object, ok := exception.Value().(*goja.Object)
+	if !ok {
+		return models.TestError, exception.Value().String()
+	}
+	if se.vm.InstanceOf(object, se.assertionError) {
+		return models.TestFailed, object.Get("message").String()
+	}
+	return models.TestError, object.String()
+}
+
+// log records a line of console output. Scripts run without a console to
+// collect it have their output dropped.
+func (se *ScriptEngine) log(level string, args []goja.Value) {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = formatConsoleValue(arg)
+	}
+	message := strings.Join(parts, " ")
+
+	if se.console == nil {
+		return
+	}
+	*se.console.entries = append(*se.console.entries, models.ConsoleEntry{Script: se.console.script, Level: level, Message: message})
+}
+
+// formatConsoleValue formats a logged value: strings as they are, other
+// values as JSON where they have a JSON form
+func formatConsoleValue(value goja.Value) string {
+	if goja.IsUndefined(value) || goja.IsNull(value) {
+		return value.String()
+	}
+	exported := value.Export()
+	if s, ok := exported.(string); ok {
+		return s
+	}
+	if _, ok := exported.(error); !ok {
+		if data, err := json.Marshal(exported); err == nil {
+			return string(data)
+		}
+	}
+	return value.String()
 }
 
-// TestResult represents the result of a test execution
-type TestResult struct {
-	Passed  bool   `json:"passed"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+// run executes a script, interrupting it if ctx is done first
+func (se *ScriptEngine) run(ctx context.Context, script string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	se.console, _ = ctx.Value(consoleContextKey{}).(*consoleSink)
+	defer func() { se.console = nil }()
+
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		se.vm.Interrupt(ctx.Err())
+		close(interrupted)
+	})
+	defer func() {
+		// An interrupt that raced with the script finishing must not hit the next one
+		if !stop() {
+			<-interrupted
+			se.vm.ClearInterrupt()
+		}
+	}()
+
+	_, err := se.vm.RunString(script)
+	var interruptErr *goja.InterruptedError
+	if errors.As(err, &interruptErr) && ctx.Err() != nil {
+		return fmt.Errorf("script interrupted: %w", ctx.Err())
+	}
+	return err
 }
 
 // updateRequestFromScript updates the request with any modifications made by the script
@@ -221,8 +374,3 @@ func (se *ScriptEngine) updateRequestFromScript(request *models.Request) {
 	// This would extract any modifications made to the request object in the script
 	// For now, it's a placeholder
 }
-
-// contains checks if a string contains a substring
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && s[:len(substr)] == substr
-}
@@ -1,7 +1,11 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"postgirl/internal/http"
@@ -9,16 +13,28 @@ import (
 	"postgirl/internal/storage"
 )
 
+// ErrCertificateNotFound is returned when updating a certificate or CA bundle
+// that does not exist
+var ErrCertificateNotFound = http.ErrCertificateNotFound
+
+// proxySettingKey is the storage setting holding the global proxy settings
+const proxySettingKey = "proxy"
+
 // Service represents the main application service
 type Service struct {
 	httpClient        *http.Client
 	storage           storage.Storage
 	environmentService *EnvironmentService
 	scriptEngine      *ScriptEngine
+	executions        *executions
+	webSockets        *webSockets
 }
 
-// NewService creates a new service instance
-func NewService(storage storage.Storage) *Service {
+// NewService creates a new service instance. Stored settings that fail to
+// load are reported in the error; the service is still usable without them.
+func NewService(storage storage.Storage) (*Service, error) {
+	var errs []error
+
 	// Create environment service
 	envService := NewEnvironmentService()
 	
@@ -26,84 +42,202 @@ func NewService(storage storage.Storage) *Service {
 	envService.SetEnvironment(envService.CreateDefaultEnvironment())
 	envService.SetEnvironment(envService.CreateDevelopmentEnvironment())
 	envService.SetEnvironment(envService.CreateProductionEnvironment())
+
+	// Stored environments, including edits to the defaults, replace them
+	if stored, err := storage.GetAllEnvironments(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to load environments: %w", err))
+	} else {
+		for _, env := range stored {
+			envService.SetEnvironment(env)
+		}
+	}
 	
 	// Create script engine
 	scriptEngine := NewScriptEngine()
 	
+	// Create the cookie jar backed by storage
+	jar, err := http.NewCookieJar(storage)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	
+	// Load client certificates and CA bundles
+	certs, err := http.NewCertificateManager(storage)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	
+	config := http.DefaultConfig()
+	config.CookieJar = jar
+	config.Certificates = certs
+	
+	// Load the global proxy settings
+	if value, err := storage.GetSetting(proxySettingKey); err != nil {
+		errs = append(errs, fmt.Errorf("failed to load proxy settings: %w", err))
+	} else if value != "" {
+		var proxy models.ProxyConfig
+		if err := json.Unmarshal([]byte(value), &proxy); err != nil {
+			errs = append(errs, fmt.Errorf("invalid proxy settings: %w", err))
+		} else {
+			config.Proxy = &proxy
+		}
+	}
+	
 	return &Service{
-		httpClient:        http.NewClient(nil),
+		httpClient:        http.NewClient(config),
 		storage:           storage,
 		environmentService: envService,
 		scriptEngine:      scriptEngine,
-	}
+		executions:        newExecutions(),
+		webSockets:        newWebSockets(),
+	}, errors.Join(errs...)
 }
 
-// ExecuteRequest executes an HTTP request and returns the response
-func (s *Service) ExecuteRequest(req *models.Request) (*models.Response, error) {
-	// Create a copy of the request to avoid modifying the original
-	requestCopy := *req
-	
-	// Get environment if specified
-	var environment *models.Environment
-	if req.EnvironmentID != "" {
-		var err error
-		environment, err = s.environmentService.GetEnvironment(req.EnvironmentID)
-		if err != nil {
-			// Try to get from database
-			environment, err = s.storage.GetEnvironment(req.EnvironmentID)
-			if err != nil {
-				return nil, fmt.Errorf("environment not found: %s", req.EnvironmentID)
-			}
-		}
+// ExecuteRequest executes an HTTP request and returns the run: the request
+// as sent, the response, and the results of its scripts and tests. It stops
+// early when ctx is done, recording the run in history as cancelled.
+func (s *Service) ExecuteRequest(ctx context.Context, req *models.Request) (*models.Execution, error) {
+	started := time.Now()
+
+	requestCopy, environment, err := s.prepareRequest(req)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Apply environment variable substitution if environment is specified
-	if environment != nil {
-		if err := s.environmentService.SubstituteRequestVariables(&requestCopy, req.EnvironmentID); err != nil {
-			return nil, fmt.Errorf("failed to substitute environment variables: %w", err)
-		}
+	exec := &models.Execution{
+		Request:      requestCopy,
+		TestResults:  []models.TestResult{},
+		Console:      []models.ConsoleEntry{},
+		ScriptErrors: []models.ScriptError{},
 	}
 	
 	// Execute pre-request script
 	if req.PreScript != "" {
-		if err := s.scriptEngine.ExecutePreScript(req.PreScript, &requestCopy, environment); err != nil {
+		scriptCtx := withConsole(ctx, &exec.Console, "pre-request")
+		if err := s.scriptEngine.ExecutePreScript(scriptCtx, req.PreScript, requestCopy, environment); err != nil {
+			if ctx.Err() != nil {
+				return nil, s.recordCancelled(exec, started, ctx.Err())
+			}
 			return nil, fmt.Errorf("pre-script execution failed: %w", err)
 		}
 	}
 	
-	// Execute the HTTP request
-	resp, err := s.httpClient.Execute(&requestCopy)
+	// Check GraphQL queries against the endpoint's schema, if it is known
+	if err := s.validateGraphQL(exec); err != nil {
+		return nil, err
+	}
+
+	// Execute the HTTP request, or call the gRPC method
+	var resp *models.Response
+	if requestCopy.IsGRPC() {
+		resp, err = s.httpClient.InvokeGRPC(ctx, requestCopy, environment)
+	} else {
+		resp, err = s.httpClient.Execute(ctx, requestCopy, environment)
+	}
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, s.recordCancelled(exec, started, ctx.Err())
+		}
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
+	exec.ID = resp.ID
+	exec.Response = resp
+	if jar := s.httpClient.CookieJar(); jar != nil {
+		if err := jar.StoreError(); err != nil {
+			exec.Warnings = append(exec.Warnings, fmt.Sprintf("failed to save cookies: %v", err))
+		}
+	}
 	
-	// Execute post-response script
+	// Execute post-response script; the run goes on without it if it fails
 	if req.PostScript != "" {
-		if err := s.scriptEngine.ExecutePostScript(req.PostScript, &requestCopy, resp, environment); err != nil {
-			// Log error but don't fail the request
-			fmt.Printf("Warning: post-script execution failed: %v\n", err)
+		scriptCtx := withConsole(ctx, &exec.Console, "post-response")
+		if err := s.scriptEngine.ExecutePostScript(scriptCtx, req.PostScript, requestCopy, resp, environment); err != nil && ctx.Err() == nil {
+			exec.ScriptErrors = append(exec.ScriptErrors, models.ScriptError{Script: "post-response", Message: err.Error()})
 		}
 	}
 	
 	// Execute test scripts
-	if len(req.Tests) > 0 {
-		for _, test := range req.Tests {
-			testResult, err := s.scriptEngine.ExecuteTestScript(test.Script, &requestCopy, resp, environment)
-			if err != nil {
-				fmt.Printf("Warning: test execution failed: %v\n", err)
-			} else {
-				fmt.Printf("Test '%s': %s\n", test.Name, testResult.Message)
-			}
+	for _, test := range req.Tests {
+		scriptCtx := withConsole(ctx, &exec.Console, test.Name)
+		results, err := s.scriptEngine.ExecuteTestScript(scriptCtx, test, requestCopy, resp, environment)
+		if err != nil {
+			break
+		}
+		exec.TestResults = append(exec.TestResults, results...)
+	}
+
+	// Scripts are interrupted on cancellation, so the run did not complete
+	if ctx.Err() != nil {
+		return nil, s.recordCancelled(exec, started, ctx.Err())
+	}
+
+	// Save the run to history
+	if err := s.storage.SaveExecution(exec); err != nil {
+		// The run still completed, so report it rather than fail it
+		exec.Warnings = append(exec.Warnings, fmt.Sprintf("failed to save the run to history: %v", err))
+	}
+
+	return exec, nil
+}
+
+// prepareRequest returns a copy of a request with its environment's variables
+// substituted, and the environment if it has one
+func (s *Service) prepareRequest(req *models.Request) (*models.Request, *models.Environment, error) {
+	// Create a copy of the request to avoid modifying the original
+	requestCopy := req.Clone()
+	if req.EnvironmentID == "" {
+		return requestCopy, nil, nil
+	}
+
+	environment, err := s.environmentService.GetEnvironment(req.EnvironmentID)
+	if err != nil {
+		// Try to get from database
+		environment, err = s.storage.GetEnvironment(req.EnvironmentID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("environment not found: %s", req.EnvironmentID)
+		}
+	}
+	if err := s.environmentService.SubstituteRequestVariables(requestCopy, req.EnvironmentID); err != nil {
+		return nil, nil, fmt.Errorf("failed to substitute environment variables: %w", err)
+	}
+	return requestCopy, environment, nil
+}
+
+// recordCancelled saves a cancelled run in history, with the response if one
+// had arrived, and returns the error to report for it
+func (s *Service) recordCancelled(exec *models.Execution, started time.Time, cause error) error {
+	if exec.Response == nil {
+		exec.Response = &models.Response{
+			ID:        generateID(),
+			RequestID: exec.Request.ID,
+			Duration:  time.Since(started),
+			CreatedAt: time.Now(),
 		}
+		exec.ID = exec.Response.ID
 	}
+	exec.Response.Cancelled = true
+	exec.Response.Error = cause.Error()
 
-	// Save the response to storage
-	if err := s.storage.SaveResponse(resp); err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Warning: failed to save response: %v\n", err)
+	if err := s.storage.SaveExecution(exec); err != nil {
+		return fmt.Errorf("request cancelled: %w (failed to save it to history: %v)", cause, err)
 	}
+	return fmt.Errorf("request cancelled: %w", cause)
+}
+
+// StartExecution registers a cancellable execution under id. Pass the
+// returned context to ExecuteRequest and call done once it returns.
+func (s *Service) StartExecution(ctx context.Context, id string) (context.Context, func(), error) {
+	return s.executions.start(ctx, id)
+}
 
-	return resp, nil
+// WithEventHandler passes the events of a streamed response to handler as
+// they arrive during ExecuteRequest
+func WithEventHandler(ctx context.Context, handler func(models.ServerSentEvent)) context.Context {
+	return http.WithEventHandler(ctx, handler)
+}
+
+// CancelExecution cancels a running execution by ID
+func (s *Service) CancelExecution(id string) error {
+	return s.executions.cancel(id)
 }
 
 // SaveRequest saves a request to storage
@@ -122,6 +256,60 @@ func (s *Service) GetResponses(requestID string) ([]*models.Response, error) {
 	return s.storage.GetResponsesForRequest(requestID)
 }
 
+// GetExecution retrieves a run from history by the ID of its response
+func (s *Service) GetExecution(id string) (*models.Execution, error) {
+	return s.storage.GetExecution(id)
+}
+
+// GetResponse retrieves a response by ID
+func (s *Service) GetResponse(id string) (*models.Response, error) {
+	return s.storage.GetResponse(id)
+}
+
+// ResponseBody returns the raw body of a response, loading it from storage
+// when it is not held in memory
+func (s *Service) ResponseBody(resp *models.Response) ([]byte, error) {
+	if resp.RawBody != nil {
+		return resp.RawBody, nil
+	}
+	if resp.BlobID == "" && !resp.Binary {
+		return []byte(resp.Body), nil
+	}
+	return s.storage.GetResponseBody(resp.ID)
+}
+
+// ResponseWireBody returns the body of a response as it was received, before
+// its Content-Encoding was decoded. Bodies that were not encoded are the same
+// either way.
+func (s *Service) ResponseWireBody(resp *models.Response) ([]byte, error) {
+	if resp.ContentEncoding == "" {
+		return s.ResponseBody(resp)
+	}
+	if resp.WireBody != nil {
+		return resp.WireBody, nil
+	}
+	wire, err := s.storage.GetResponseWireBody(resp.ID)
+	if err != nil {
+		return nil, err
+	}
+	if wire == nil {
+		return nil, fmt.Errorf("response %s body was too large to keep as received", resp.ID)
+	}
+	return wire, nil
+}
+
+// SaveResponseBody writes the raw body of a response to a file
+func (s *Service) SaveResponseBody(resp *models.Response, path string) error {
+	body, err := s.ResponseBody(resp)
+	if err != nil {
+		return fmt.Errorf("failed to load response body: %w", err)
+	}
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return fmt.Errorf("failed to save response body: %w", err)
+	}
+	return nil
+}
+
 // CreateNewRequest creates a new request with default values
 func (s *Service) CreateNewRequest() *models.Request {
 	return &models.Request{
@@ -129,8 +317,8 @@ func (s *Service) CreateNewRequest() *models.Request {
 		Name:        "New Request",
 		Method:      "GET",
 		URL:         "",
-		Headers:     make(map[string]string),
-		QueryParams: make(map[string]string),
+		Headers:     models.KeyValues{},
+		QueryParams: models.KeyValues{},
 		Body:        nil,
 		Auth:        nil,
 		PreScript:   "",
@@ -187,6 +375,15 @@ func (s *Service) DeleteCollection(id string) error {
 	return s.storage.DeleteCollection(id)
 }
 
+// ValidateEnvironment checks an environment's proxy override, host overrides
+// and DNS server
+func (s *Service) ValidateEnvironment(env *models.Environment) error {
+	if err := http.ValidateProxyConfig(env.Proxy); err != nil {
+		return err
+	}
+	return http.ValidateResolution(env.Hosts, env.DNSServer)
+}
+
 // SaveEnvironmentToDB saves an environment to the database
 func (s *Service) SaveEnvironmentToDB(env *models.Environment) error {
 	env.UpdatedAt = time.Now()
@@ -208,6 +405,84 @@ func (s *Service) DeleteEnvironmentFromDB(id string) error {
 	return s.storage.DeleteEnvironment(id)
 }
 
+// ListCookies returns all cookies in the cookie jar
+func (s *Service) ListCookies() []models.Cookie {
+	return s.httpClient.CookieJar().List()
+}
+
+// SaveCookie adds or replaces a cookie in the cookie jar
+func (s *Service) SaveCookie(cookie models.Cookie) error {
+	return s.httpClient.CookieJar().Save(cookie)
+}
+
+// DeleteCookie deletes a cookie from the cookie jar
+func (s *Service) DeleteCookie(domain, path, name string) error {
+	return s.httpClient.CookieJar().Delete(domain, path, name)
+}
+
+// ClearCookies deletes all cookies, or only those for a domain when one is given
+func (s *Service) ClearCookies(domain string) error {
+	return s.httpClient.CookieJar().Clear(domain)
+}
+
+// ListCertificates returns the client certificates and per-host TLS settings
+func (s *Service) ListCertificates() []models.Certificate {
+	return s.httpClient.Certificates().Certificates()
+}
+
+// SaveCertificate adds or replaces a client certificate and its host settings
+func (s *Service) SaveCertificate(cert *models.Certificate) error {
+	return s.httpClient.Certificates().SaveCertificate(cert)
+}
+
+// DeleteCertificate deletes a client certificate by ID
+func (s *Service) DeleteCertificate(id string) error {
+	return s.httpClient.Certificates().DeleteCertificate(id)
+}
+
+// ListCACertificates returns the extra trusted CA bundles
+func (s *Service) ListCACertificates() []models.CACertificate {
+	return s.httpClient.Certificates().CACertificates()
+}
+
+// SaveCACertificate adds or replaces a trusted CA bundle
+func (s *Service) SaveCACertificate(ca *models.CACertificate) error {
+	return s.httpClient.Certificates().SaveCACertificate(ca)
+}
+
+// DeleteCACertificate deletes a trusted CA bundle by ID
+func (s *Service) DeleteCACertificate(id string) error {
+	return s.httpClient.Certificates().DeleteCACertificate(id)
+}
+
+// ProxySettings returns the global proxy settings, or nil when the
+// HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables are used
+func (s *Service) ProxySettings() *models.ProxyConfig {
+	return s.httpClient.Proxy()
+}
+
+// SaveProxySettings validates, stores and applies the global proxy settings.
+// A password left empty keeps the stored one for the same username.
+func (s *Service) SaveProxySettings(proxy *models.ProxyConfig) error {
+	proxy.KeepPassword(s.httpClient.Proxy())
+	if err := http.ValidateProxyConfig(proxy); err != nil {
+		return err
+	}
+	if proxy != nil && proxy.Mode == "" {
+		proxy.Mode = "system"
+	}
+	
+	data, err := json.Marshal(proxy)
+	if err != nil {
+		return err
+	}
+	if err := s.storage.SaveSetting(proxySettingKey, string(data)); err != nil {
+		return fmt.Errorf("failed to save proxy settings: %w", err)
+	}
+	
+	return s.httpClient.SetProxy(proxy)
+}
+
 // generateID generates a unique ID
 func generateID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"postgirl/internal/models"
+	"postgirl/internal/storage/sqlite"
+)
+
+func TestEnvironmentEditsSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "postgirl.db")
+
+	store, err := sqlite.NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := NewService(store)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	env, err := service.GetEnvironment("development")
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := *env
+	edited.Variables = models.EnvironmentVariables{{Key: "base_url", Value: "http://localhost:4000", Enabled: true}}
+	edited.Proxy = &models.ProxyConfig{Mode: "manual", URL: "http://proxy.test:3128", Username: "u", Password: "p"}
+	edited.Hosts = map[string]string{"api.test": "127.0.0.1"}
+	edited.DNSServer = "1.1.1.1:53"
+	if err := service.SaveEnvironmentToDB(&edited); err != nil {
+		t.Fatalf("SaveEnvironmentToDB: %v", err)
+	}
+	service.SetEnvironment(&edited)
+	store.Close()
+
+	store, err = sqlite.NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	service, err = NewService(store)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	got, err := service.GetEnvironment("development")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Variables) != 1 || got.Variables[0].Value != "http://localhost:4000" {
+		t.Errorf("variables = %+v, want the edit", got.Variables)
+	}
+	if got.Proxy == nil || got.Proxy.URL != "http://proxy.test:3128" || got.Proxy.Password != "p" {
+		t.Errorf("proxy = %+v, want the edit", got.Proxy)
+	}
+	if got.Hosts["api.test"] != "127.0.0.1" || got.DNSServer != "1.1.1.1:53" {
+		t.Errorf("hosts = %v, dns server = %q, want the edit", got.Hosts, got.DNSServer)
+	}
+
+	// Environments that were never edited are still there
+	if _, err := service.GetEnvironment("production"); err != nil {
+		t.Errorf("production environment: %v", err)
+	}
+	if n := len(service.ListEnvironments()); n != 3 {
+		t.Errorf("%d environments, want 3", n)
+	}
+}
@@ -0,0 +1,260 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"postgirl/internal/http"
+	"postgirl/internal/models"
+)
+
+// webSockets tracks the open WebSocket sessions by ID
+type webSockets struct {
+	mu       sync.Mutex
+	sessions map[string]*webSocketSession
+}
+
+// webSocketSession is an open session with its connection. Watchers wait on
+// changed, which is closed and replaced whenever the session changes.
+type webSocketSession struct {
+	mu      sync.Mutex
+	session *models.WebSocketSession
+	conn    *http.WebSocketConn
+	envID   string
+	changed chan struct{}
+	// saveErr is why the closed session could not be saved; such a session
+	// stays registered so that it can still be read
+	saveErr error
+}
+
+// newWebSockets creates an empty session registry
+func newWebSockets() *webSockets {
+	return &webSockets{sessions: make(map[string]*webSocketSession)}
+}
+
+// get returns an open session by ID
+func (w *webSockets) get(id string) (*webSocketSession, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	session, exists := w.sessions[id]
+	return session, exists
+}
+
+// add registers an open session
+func (w *webSockets) add(session *webSocketSession) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sessions[session.session.ID] = session
+}
+
+// remove unregisters a session once it has closed
+func (w *webSockets) remove(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.sessions, id)
+}
+
+// record appends a message to the log and wakes watchers
+func (s *webSocketSession) record(message models.WebSocketMessage) {
+	s.mu.Lock()
+	s.session.Messages = append(s.session.Messages, message)
+	if message.Type == "pong" && message.RTT > 0 {
+		s.session.LastRTT = message.RTT
+	}
+	s.notify()
+	s.mu.Unlock()
+}
+
+// notify wakes watchers; s.mu must be held
+func (s *webSocketSession) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// snapshot returns a copy of the session
+func (s *webSocketSession) snapshot() *models.WebSocketSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session := *s.session
+	session.Messages = append([]models.WebSocketMessage{}, s.session.Messages...)
+	return &session
+}
+
+// OpenWebSocket opens a WebSocket session for a request, substituting its
+// environment's variables into the URL, headers and subprotocols. Messages
+// sent and received are logged on the session; use WatchWebSocket to follow
+// them. ctx bounds the handshake only; the session stays open until
+// CloseWebSocket or until the server closes it, and is then saved to storage.
+func (s *Service) OpenWebSocket(ctx context.Context, req *models.Request) (*models.WebSocketSession, error) {
+	requestCopy, environment, err := s.prepareRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &webSocketSession{
+		session: &models.WebSocketSession{
+			ID:        generateID(),
+			RequestID: req.ID,
+			URL:       requestCopy.URL,
+			Messages:  []models.WebSocketMessage{},
+			CreatedAt: time.Now(),
+		},
+		envID:   req.EnvironmentID,
+		changed: make(chan struct{}),
+	}
+
+	// Hold the session until it is set up so that early messages are logged
+	// after the handshake details
+	entry.mu.Lock()
+	conn, err := s.httpClient.DialWebSocket(ctx, requestCopy, environment, entry.record)
+	if err != nil {
+		entry.mu.Unlock()
+		return nil, err
+	}
+	entry.conn = conn
+	entry.session.Subprotocol = conn.Subprotocol()
+	entry.session.StatusCode = conn.StatusCode()
+	entry.session.Headers = conn.Headers()
+	entry.session.Open = true
+	entry.mu.Unlock()
+
+	s.webSockets.add(entry)
+	go s.watchWebSocket(entry)
+
+	return entry.snapshot(), nil
+}
+
+// watchWebSocket saves a session to storage once its connection closes
+func (s *Service) watchWebSocket(entry *webSocketSession) {
+	<-entry.conn.Done()
+
+	entry.mu.Lock()
+	entry.session.Open = false
+	entry.session.ClosedAt = time.Now()
+	if err := entry.conn.Err(); err != nil {
+		entry.session.Error = err.Error()
+	}
+	entry.mu.Unlock()
+
+	if err := s.storage.SaveWebSocketSession(entry.snapshot()); err != nil {
+		entry.mu.Lock()
+		entry.saveErr = fmt.Errorf("failed to save WebSocket session: %w", err)
+		if entry.session.Error != "" {
+			entry.session.Error += "; "
+		}
+		entry.session.Error += entry.saveErr.Error()
+		entry.notify()
+		entry.mu.Unlock()
+		return
+	}
+	s.webSockets.remove(entry.session.ID)
+
+	entry.mu.Lock()
+	entry.notify()
+	entry.mu.Unlock()
+}
+
+// openWebSocket returns an open session by ID
+func (s *Service) openWebSocket(id string) (*webSocketSession, error) {
+	entry, exists := s.webSockets.get(id)
+	if !exists {
+		return nil, fmt.Errorf("websocket session not open: %s", id)
+	}
+	return entry, nil
+}
+
+// SendWebSocketMessage sends a text message on an open session, substituting
+// the {{variables}} of the session's environment
+func (s *Service) SendWebSocketMessage(id, text string) error {
+	entry, err := s.openWebSocket(id)
+	if err != nil {
+		return err
+	}
+	text, err = s.environmentService.SubstituteVariables(text, entry.envID)
+	if err != nil {
+		return fmt.Errorf("failed to substitute message variables: %w", err)
+	}
+	if err := entry.conn.SendText(text); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+// PingWebSocket sends a ping on an open session; the pong is logged with its
+// round trip time
+func (s *Service) PingWebSocket(id string) error {
+	entry, err := s.openWebSocket(id)
+	if err != nil {
+		return err
+	}
+	if err := entry.conn.Ping(); err != nil {
+		return fmt.Errorf("failed to send ping: %w", err)
+	}
+	return nil
+}
+
+// CloseWebSocket closes an open session and waits for it to be saved,
+// returning the error if saving failed
+func (s *Service) CloseWebSocket(id string) error {
+	entry, err := s.openWebSocket(id)
+	if err != nil {
+		return err
+	}
+	entry.conn.Close()
+	for {
+		entry.mu.Lock()
+		changed, saveErr := entry.changed, entry.saveErr
+		entry.mu.Unlock()
+		if saveErr != nil {
+			return saveErr
+		}
+		if _, open := s.webSockets.get(id); !open {
+			return nil
+		}
+		<-changed
+	}
+}
+
+// GetWebSocketSession returns a session by ID, open or saved
+func (s *Service) GetWebSocketSession(id string) (*models.WebSocketSession, error) {
+	if entry, open := s.webSockets.get(id); open {
+		return entry.snapshot(), nil
+	}
+	return s.storage.GetWebSocketSession(id)
+}
+
+// WatchWebSocket returns the messages of a session after the first n, whether
+// it is still open, and a channel closed on its next change. A closed session
+// has a nil channel.
+func (s *Service) WatchWebSocket(id string, n int) ([]models.WebSocketMessage, bool, <-chan struct{}, error) {
+	if entry, open := s.webSockets.get(id); open {
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+		messages := entry.session.Messages
+		if n > len(messages) {
+			n = len(messages)
+		}
+		changed := entry.changed
+		if entry.saveErr != nil {
+			// Closed, and will not change again
+			changed = nil
+		}
+		return append([]models.WebSocketMessage(nil), messages[n:]...), entry.session.Open, changed, nil
+	}
+
+	session, err := s.storage.GetWebSocketSession(id)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if n > len(session.Messages) {
+		n = len(session.Messages)
+	}
+	return session.Messages[n:], false, nil, nil
+}
+
+// ListWebSocketSessions returns the saved sessions of a request
+func (s *Service) ListWebSocketSessions(requestID string) ([]*models.WebSocketSession, error) {
+	return s.storage.GetWebSocketSessionsForRequest(requestID)
+}
@@ -0,0 +1,345 @@
+package graphql
+
+import (
+	"strings"
+
+	"postgirl/internal/models"
+)
+
+// Suggestion is a completion offered at the cursor
+type Suggestion struct {
+	Label       string `json:"label"`
+	Kind        string `json:"kind"`             // field, argument, value, type, fragment, directive, keyword
+	Detail      string `json:"detail,omitempty"` // the type of a field or argument
+	Description string `json:"description,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+}
+
+// Completion is what may be typed at the cursor. Prefix is the part of the
+// name being typed, which a chosen suggestion replaces.
+type Completion struct {
+	Prefix      string       `json:"prefix"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// completer follows a query up to the cursor to find out what may come next
+type completer struct {
+	schema *models.GraphQLSchema
+	stack  []*models.GraphQLType // the types of the open selection sets; nil if unknown
+
+	previous  token  // the last token before the cursor
+	operation string // the kind of operation being defined at the top level
+	typeName  string // a type condition waiting for its selection set
+	field     string // the last field name in the current selection set
+	condition bool   // "... on" was read, so the next name is a type
+
+	// Arguments of a field or directive
+	inArguments bool
+	arguments   []models.GraphQLInputValue
+	given       map[string]bool
+	argument    string // the argument whose value is being written
+	depth       int    // nesting of lists and objects within the arguments
+
+	skipping int // nesting of the variable definitions of an operation
+}
+
+// Complete suggests what may be typed at a byte offset of a query: fields of
+// the type being selected, arguments and enum values, type conditions,
+// fragment names, directives and keywords
+func Complete(schema *models.GraphQLSchema, query string, offset int) Completion {
+	if offset < 0 || offset > len(query) {
+		offset = len(query)
+	}
+
+	var tokens []token
+	l := newLexer(query[:offset])
+	for {
+		t, err := l.next()
+		if err != nil || t.kind == tokenEOF {
+			break
+		}
+		tokens = append(tokens, t)
+	}
+
+	completion := Completion{Suggestions: []Suggestion{}}
+	if n := len(tokens); n > 0 && tokens[n-1].kind == tokenName && tokens[n-1].end == offset {
+		completion.Prefix = tokens[n-1].value
+		tokens = tokens[:n-1]
+	}
+
+	c := &completer{schema: schema}
+	for _, t := range tokens {
+		c.step(t)
+		c.previous = t
+	}
+
+	for _, s := range c.suggest(query) {
+		if strings.HasPrefix(strings.ToLower(s.Label), strings.ToLower(completion.Prefix)) && s.Label != completion.Prefix {
+			completion.Suggestions = append(completion.Suggestions, s)
+		}
+	}
+	return completion
+}
+
+// step follows one token
+func (c *completer) step(t token) {
+	punctuator := ""
+	if t.kind == tokenPunctuator {
+		punctuator = t.value
+	}
+
+	if c.skipping > 0 {
+		switch punctuator {
+		case "(":
+			c.skipping++
+		case ")":
+			c.skipping--
+		}
+		return
+	}
+
+	if c.inArguments {
+		switch {
+		case punctuator == "(" || punctuator == "[" || punctuator == "{":
+			c.depth++
+		case (punctuator == "]" || punctuator == "}") && c.depth > 0:
+			c.depth--
+		case punctuator == ")" && c.depth == 0:
+			c.inArguments = false
+		case punctuator == ")":
+			c.depth--
+		case t.kind == tokenName && c.depth == 0 && !c.isPrevious(":") && !c.isPrevious("$"):
+			c.argument = t.value
+			c.given[t.value] = true
+		}
+		return
+	}
+
+	if len(c.stack) == 0 {
+		switch {
+		case t.kind == tokenName && (t.value == "query" || t.value == "mutation" || t.value == "subscription"):
+			c.operation = t.value
+		case t.kind == tokenName && c.isPrevious("on"):
+			c.typeName = t.value
+		case punctuator == "(":
+			c.skipping = 1
+		case punctuator == "{":
+			if c.typeName != "" {
+				c.push(c.typeName)
+			} else {
+				c.push(c.rootType())
+			}
+			c.operation, c.typeName, c.field = "", "", ""
+		}
+		return
+	}
+
+	switch {
+	case t.kind == tokenName && c.isPrevious("..."):
+		c.condition = t.value == "on"
+	case t.kind == tokenName && c.condition:
+		c.typeName = t.value
+		c.condition = false
+	case t.kind == tokenName && c.isPrevious("@"):
+		c.field = ""
+		c.arguments = nil
+		for _, d := range c.schema.Directives {
+			if d.Name == t.value {
+				c.arguments = d.Args
+			}
+		}
+	case t.kind == tokenName:
+		// After an alias, the name that follows it is the field
+		c.field = t.value
+		if f, ok := c.lookupField(c.field); ok {
+			c.arguments = f.Args
+		} else {
+			c.arguments = nil
+		}
+	case punctuator == "(":
+		c.inArguments = true
+		c.given = make(map[string]bool)
+		c.argument = ""
+		c.depth = 0
+	case punctuator == "{":
+		if c.typeName != "" {
+			c.push(c.typeName)
+		} else if f, ok := c.lookupField(c.field); ok {
+			c.push(f.Type.NamedType())
+		} else {
+			c.stack = append(c.stack, nil)
+		}
+		c.typeName, c.field = "", ""
+	case punctuator == "}":
+		c.stack = c.stack[:len(c.stack)-1]
+		c.field = ""
+	}
+}
+
+// isPrevious reports whether the last token was the given punctuator or name
+func (c *completer) isPrevious(value string) bool {
+	return (c.previous.kind == tokenPunctuator || c.previous.kind == tokenName) && c.previous.value == value
+}
+
+// push opens a selection set on a type
+func (c *completer) push(name string) {
+	t, _ := c.schema.Type(name)
+	c.stack = append(c.stack, t)
+}
+
+// current returns the type of the innermost selection set, or nil
+func (c *completer) current() *models.GraphQLType {
+	if len(c.stack) == 0 {
+		return nil
+	}
+	return c.stack[len(c.stack)-1]
+}
+
+// rootType returns the root type of the operation being defined
+func (c *completer) rootType() string {
+	switch c.operation {
+	case "mutation":
+		return c.schema.MutationType
+	case "subscription":
+		return c.schema.SubscriptionType
+	}
+	return c.schema.QueryType
+}
+
+// lookupField finds a field of the current type, including the meta fields
+func (c *completer) lookupField(name string) (*models.GraphQLField, bool) {
+	parent := c.current()
+	if parent == nil || name == "" {
+		return nil, false
+	}
+	v := &validator{schema: c.schema}
+	return v.lookupField(parent, name)
+}
+
+// suggest lists what may come next, before filtering by prefix
+func (c *completer) suggest(query string) []Suggestion {
+	if c.skipping > 0 {
+		return nil
+	}
+
+	if c.inArguments {
+		if c.depth > 0 {
+			return nil
+		}
+		if c.isPrevious(":") {
+			return c.values()
+		}
+		var suggestions []Suggestion
+		for _, arg := range c.arguments {
+			if !c.given[arg.Name] {
+				suggestions = append(suggestions, Suggestion{Label: arg.Name, Kind: "argument", Detail: arg.Type.String(), Description: arg.Description})
+			}
+		}
+		return suggestions
+	}
+
+	if c.condition || (len(c.stack) == 0 && c.isPrevious("on")) {
+		var suggestions []Suggestion
+		for _, t := range c.schema.Types {
+			if (t.Kind == "OBJECT" || t.Kind == "INTERFACE" || t.Kind == "UNION") && !strings.HasPrefix(t.Name, "__") {
+				suggestions = append(suggestions, Suggestion{Label: t.Name, Kind: "type", Detail: t.Kind, Description: t.Description})
+			}
+		}
+		return suggestions
+	}
+
+	if len(c.stack) == 0 {
+		if c.previous.kind == tokenName {
+			// An operation or fragment name comes next
+			return nil
+		}
+		keywords := []string{"query", "mutation", "subscription", "fragment"}
+		suggestions := make([]Suggestion, 0, len(keywords))
+		for _, keyword := range keywords {
+			suggestions = append(suggestions, Suggestion{Label: keyword, Kind: "keyword"})
+		}
+		return suggestions
+	}
+
+	if c.isPrevious("@") {
+		var suggestions []Suggestion
+		for _, d := range c.schema.Directives {
+			suggestions = append(suggestions, Suggestion{Label: d.Name, Kind: "directive", Description: d.Description})
+		}
+		return suggestions
+	}
+
+	if c.isPrevious("...") {
+		suggestions := []Suggestion{{Label: "on", Kind: "keyword"}}
+		for _, name := range fragmentNames(query) {
+			suggestions = append(suggestions, Suggestion{Label: name, Kind: "fragment"})
+		}
+		return suggestions
+	}
+
+	parent := c.current()
+	if parent == nil {
+		return nil
+	}
+	var suggestions []Suggestion
+	if parent.Name == c.schema.QueryType && len(c.stack) == 1 {
+		suggestions = append(suggestions,
+			Suggestion{Label: schemaField.Name, Kind: "field", Detail: schemaField.Type.String()},
+			Suggestion{Label: typeField.Name, Kind: "field", Detail: typeField.Type.String()},
+		)
+	}
+	for _, f := range parent.Fields {
+		suggestions = append(suggestions, Suggestion{
+			Label:       f.Name,
+			Kind:        "field",
+			Detail:      f.Type.String(),
+			Description: f.Description,
+			Deprecated:  f.IsDeprecated,
+		})
+	}
+	return append(suggestions, Suggestion{Label: typenameField.Name, Kind: "field", Detail: typenameField.Type.String()})
+}
+
+// values suggests the values of an enum or Boolean argument
+func (c *completer) values() []Suggestion {
+	definition := findInputValue(c.arguments, c.argument)
+	if definition == nil {
+		return nil
+	}
+	name := definition.Type.NamedType()
+	if name == "Boolean" {
+		return []Suggestion{{Label: "true", Kind: "value", Detail: name}, {Label: "false", Kind: "value", Detail: name}}
+	}
+	t, ok := c.schema.Type(name)
+	if !ok || t.Kind != "ENUM" {
+		return nil
+	}
+	var suggestions []Suggestion
+	for _, value := range t.EnumValues {
+		suggestions = append(suggestions, Suggestion{
+			Label:       value.Name,
+			Kind:        "value",
+			Detail:      name,
+			Description: value.Description,
+			Deprecated:  value.IsDeprecated,
+		})
+	}
+	return suggestions
+}
+
+// fragmentNames returns the names of the fragments a query defines
+func fragmentNames(query string) []string {
+	var names []string
+	l := newLexer(query)
+	var previous token
+	for {
+		t, err := l.next()
+		if err != nil || t.kind == tokenEOF {
+			return names
+		}
+		if t.kind == tokenName && previous.kind == tokenName && previous.value == "fragment" {
+			names = append(names, t.value)
+		}
+		previous = t
+	}
+}
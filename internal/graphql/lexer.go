@@ -0,0 +1,254 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// token is a lexical token of a GraphQL document
+type token struct {
+	kind  tokenKind
+	value string // the punctuator, name or number, or the string's value
+	pos   Position
+	start int // byte offsets in the document
+	end   int
+}
+
+// Position is a line and column in a document, both starting at 1
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// escapes maps the characters after a backslash in a string to what they stand for
+var escapes = map[byte]string{'"': `"`, '\\': `\`, '/': "/", 'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t"}
+
+// lexer splits a GraphQL document into tokens. Commas, whitespace and
+// comments are skipped.
+type lexer struct {
+	source    string
+	offset    int
+	line      int
+	lineStart int
+}
+
+// newLexer creates a lexer for a document
+func newLexer(source string) *lexer {
+	return &lexer{source: source, line: 1}
+}
+
+// position returns the position of a byte offset on the current line
+func (l *lexer) position(offset int) Position {
+	return Position{Line: l.line, Column: utf8.RuneCountInString(l.source[l.lineStart:offset]) + 1}
+}
+
+// newline records that a line ends before offset
+func (l *lexer) newline(offset int) {
+	l.line++
+	l.lineStart = offset
+}
+
+// next returns the next token
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	start := l.offset
+	pos := l.position(start)
+	if start >= len(l.source) {
+		return token{kind: tokenEOF, pos: pos, start: start, end: start}, nil
+	}
+
+	c := l.source[start]
+	switch {
+	case strings.IndexByte("!$&()=:@[]{}|", c) >= 0:
+		l.offset++
+		return token{kind: tokenPunctuator, value: string(c), pos: pos, start: start, end: l.offset}, nil
+	case c == '.':
+		if strings.HasPrefix(l.source[start:], "...") {
+			l.offset += 3
+			return token{kind: tokenPunctuator, value: "...", pos: pos, start: start, end: l.offset}, nil
+		}
+		return token{}, &Error{Message: "unexpected \".\"", Position: pos}
+	case isNameStart(c):
+		for l.offset < len(l.source) && isNameContinue(l.source[l.offset]) {
+			l.offset++
+		}
+		return token{kind: tokenName, value: l.source[start:l.offset], pos: pos, start: start, end: l.offset}, nil
+	case c == '-' || isDigit(c):
+		return l.number(start, pos)
+	case c == '"':
+		if strings.HasPrefix(l.source[start:], `"""`) {
+			return l.blockString(start, pos)
+		}
+		return l.string(start, pos)
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.source[start:])
+	return token{}, &Error{Message: fmt.Sprintf("unexpected character %q", r), Position: pos}
+}
+
+// skipIgnored skips whitespace, commas, comments and byte order marks
+func (l *lexer) skipIgnored() {
+	for l.offset < len(l.source) {
+		switch c := l.source[l.offset]; {
+		case c == ' ' || c == '\t' || c == ',':
+			l.offset++
+		case c == '\n':
+			l.offset++
+			l.newline(l.offset)
+		case c == '\r':
+			l.offset++
+			if l.offset < len(l.source) && l.source[l.offset] == '\n' {
+				l.offset++
+			}
+			l.newline(l.offset)
+		case c == '#':
+			for l.offset < len(l.source) && l.source[l.offset] != '\n' && l.source[l.offset] != '\r' {
+				l.offset++
+			}
+		case strings.HasPrefix(l.source[l.offset:], "\uFEFF"):
+			l.offset += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+// number lexes an int or float value
+func (l *lexer) number(start int, pos Position) (token, error) {
+	if l.source[l.offset] == '-' {
+		l.offset++
+	}
+	digits := func() bool {
+		from := l.offset
+		for l.offset < len(l.source) && isDigit(l.source[l.offset]) {
+			l.offset++
+		}
+		return l.offset > from
+	}
+	if !digits() {
+		return token{}, &Error{Message: "invalid number", Position: pos}
+	}
+	kind := tokenInt
+	if l.offset < len(l.source) && l.source[l.offset] == '.' {
+		l.offset++
+		kind = tokenFloat
+		if !digits() {
+			return token{}, &Error{Message: "invalid number", Position: pos}
+		}
+	}
+	if l.offset < len(l.source) && (l.source[l.offset] == 'e' || l.source[l.offset] == 'E') {
+		l.offset++
+		kind = tokenFloat
+		if l.offset < len(l.source) && (l.source[l.offset] == '+' || l.source[l.offset] == '-') {
+			l.offset++
+		}
+		if !digits() {
+			return token{}, &Error{Message: "invalid number", Position: pos}
+		}
+	}
+	if l.offset < len(l.source) && (isNameStart(l.source[l.offset]) || l.source[l.offset] == '.') {
+		return token{}, &Error{Message: "invalid number", Position: pos}
+	}
+	return token{kind: kind, value: l.source[start:l.offset], pos: pos, start: start, end: l.offset}, nil
+}
+
+// string lexes a quoted string, decoding its escapes
+func (l *lexer) string(start int, pos Position) (token, error) {
+	l.offset++
+	var value strings.Builder
+	for l.offset < len(l.source) {
+		c := l.source[l.offset]
+		switch {
+		case c == '"':
+			l.offset++
+			return token{kind: tokenString, value: value.String(), pos: pos, start: start, end: l.offset}, nil
+		case c == '\n' || c == '\r':
+			return token{}, &Error{Message: "unterminated string", Position: pos}
+		case c == '\\':
+			if l.offset+1 >= len(l.source) {
+				return token{}, &Error{Message: "unterminated string", Position: pos}
+			}
+			escape := l.source[l.offset+1]
+			if escape == 'u' {
+				if l.offset+6 > len(l.source) {
+					return token{}, &Error{Message: "invalid unicode escape", Position: l.position(l.offset)}
+				}
+				r, err := strconv.ParseUint(l.source[l.offset+2:l.offset+6], 16, 32)
+				if err != nil {
+					return token{}, &Error{Message: "invalid unicode escape", Position: l.position(l.offset)}
+				}
+				value.WriteRune(rune(r))
+				l.offset += 6
+				continue
+			}
+			replacement, ok := escapes[escape]
+			if !ok {
+				return token{}, &Error{Message: fmt.Sprintf("invalid escape \\%c", escape), Position: l.position(l.offset)}
+			}
+			value.WriteString(replacement)
+			l.offset += 2
+		default:
+			value.WriteByte(c)
+			l.offset++
+		}
+	}
+	return token{}, &Error{Message: "unterminated string", Position: pos}
+}
+
+// blockString lexes a """block string""". Its value is kept as written,
+// without the common indentation removed.
+func (l *lexer) blockString(start int, pos Position) (token, error) {
+	l.offset += 3
+	from := l.offset
+	for l.offset < len(l.source) {
+		switch {
+		case strings.HasPrefix(l.source[l.offset:], `\"""`):
+			l.offset += 4
+		case strings.HasPrefix(l.source[l.offset:], `"""`):
+			value := l.source[from:l.offset]
+			l.offset += 3
+			return token{kind: tokenString, value: value, pos: pos, start: start, end: l.offset}, nil
+		case l.source[l.offset] == '\n':
+			l.offset++
+			l.newline(l.offset)
+		case l.source[l.offset] == '\r':
+			l.offset++
+			if l.offset < len(l.source) && l.source[l.offset] == '\n' {
+				l.offset++
+			}
+			l.newline(l.offset)
+		default:
+			l.offset++
+		}
+	}
+	return token{}, &Error{Message: "unterminated string", Position: pos}
+}
+
+// isNameStart reports whether a name may start with c
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isNameContinue reports whether c may appear in a name after its first character
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+// isDigit reports whether c is a decimal digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,583 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+// document is a parsed executable GraphQL document
+type document struct {
+	operations []*operation
+	fragments  []*fragment
+}
+
+// operation is a query, mutation or subscription
+type operation struct {
+	kind         string // query, mutation, subscription
+	name         string
+	variables    []*variableDefinition
+	directives   []*directive
+	selectionSet []selection
+	pos          Position
+}
+
+// variableDefinition declares a variable of an operation
+type variableDefinition struct {
+	name       string
+	typ        *typeRef
+	hasDefault bool
+	pos        Position
+}
+
+// typeRef is a type as written in a variable definition
+type typeRef struct {
+	name    string   // for a named type
+	list    *typeRef // for a list type
+	nonNull bool
+}
+
+// String renders the type as written
+func (t *typeRef) String() string {
+	s := t.name
+	if t.list != nil {
+		s = "[" + t.list.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// fragment is a named fragment definition
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selectionSet  []selection
+	pos           Position
+}
+
+// selection is a field, fragment spread or inline fragment
+type selection interface {
+	position() Position
+}
+
+// field selects a field, optionally under an alias
+type field struct {
+	alias        string
+	name         string
+	arguments    []*argument
+	directives   []*directive
+	selectionSet []selection
+	pos          Position
+}
+
+// fragmentSpread includes a named fragment
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	pos        Position
+}
+
+// inlineFragment includes selections, optionally for a given type only
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selectionSet  []selection
+	pos           Position
+}
+
+func (f *field) position() Position          { return f.pos }
+func (f *fragmentSpread) position() Position { return f.pos }
+func (f *inlineFragment) position() Position { return f.pos }
+
+// argument is a named argument of a field or directive
+type argument struct {
+	name  string
+	value *value
+	pos   Position
+}
+
+// directive is a directive such as @include(if: $flag)
+type directive struct {
+	name      string
+	arguments []*argument
+	pos       Position
+}
+
+// valueKind is the kind of an input value
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+// value is an input value of an argument
+type value struct {
+	kind   valueKind
+	raw    string // the variable name, literal or enum value
+	list   []*value
+	fields []*objectField
+	pos    Position
+}
+
+// objectField is a field of an input object value
+type objectField struct {
+	name  string
+	value *value
+	pos   Position
+}
+
+// parser parses an executable GraphQL document
+type parser struct {
+	lexer *lexer
+	token token
+}
+
+// parse parses a document containing operations and fragments
+func parse(source string) (*document, error) {
+	p := &parser{lexer: newLexer(source)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{}
+	if p.token.kind == tokenEOF {
+		return nil, &Error{Message: "the query has no operations", Position: p.token.pos}
+	}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.token.kind == tokenName && (p.token.value == "query" || p.token.value == "mutation" || p.token.value == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.token.kind == tokenName && p.token.value == "fragment":
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments = append(doc.fragments, frag)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	return doc, nil
+}
+
+// advance moves to the next token
+func (p *parser) advance() error {
+	token, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = token
+	return nil
+}
+
+// peek reports whether the current token is the given punctuator
+func (p *parser) peek(punctuator string) bool {
+	return p.token.kind == tokenPunctuator && p.token.value == punctuator
+}
+
+// skip consumes the current token if it is the given punctuator
+func (p *parser) skip(punctuator string) (bool, error) {
+	if !p.peek(punctuator) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+// expect consumes the given punctuator
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return &Error{Message: fmt.Sprintf("expected %q, found %s", punctuator, p.describe()), Position: p.token.pos}
+	}
+	return p.advance()
+}
+
+// name consumes a name
+func (p *parser) name() (string, Position, error) {
+	if p.token.kind != tokenName {
+		return "", p.token.pos, &Error{Message: fmt.Sprintf("expected a name, found %s", p.describe()), Position: p.token.pos}
+	}
+	name, pos := p.token.value, p.token.pos
+	return name, pos, p.advance()
+}
+
+// keyword consumes the given name
+func (p *parser) keyword(keyword string) error {
+	if p.token.kind != tokenName || p.token.value != keyword {
+		return &Error{Message: fmt.Sprintf("expected %q, found %s", keyword, p.describe()), Position: p.token.pos}
+	}
+	return p.advance()
+}
+
+// unexpected reports the current token as unexpected
+func (p *parser) unexpected() error {
+	return &Error{Message: fmt.Sprintf("unexpected %s", p.describe()), Position: p.token.pos}
+}
+
+// describe names the current token for an error message
+func (p *parser) describe() string {
+	switch p.token.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return "string"
+	}
+	return fmt.Sprintf("%q", p.token.value)
+}
+
+// operation parses an operation definition
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: "query", pos: p.token.pos}
+	if p.peek("{") {
+		// Query shorthand
+		selections, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		op.selectionSet = selections
+		return op, nil
+	}
+
+	op.kind = p.token.value
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenName {
+		op.name = p.token.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		variables, err := p.variableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		op.variables = variables
+	}
+	directives, err := p.directives()
+	if err != nil {
+		return nil, err
+	}
+	op.directives = directives
+	if op.selectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// variableDefinitions parses the variables of an operation
+func (p *parser) variableDefinitions() ([]*variableDefinition, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var definitions []*variableDefinition
+	for !p.peek(")") {
+		pos := p.token.pos
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		definition := &variableDefinition{name: name, typ: typ, pos: pos}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if _, err := p.value(true); err != nil {
+				return nil, err
+			}
+			definition.hasDefault = true
+		}
+		if _, err := p.directives(); err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, p.advance()
+}
+
+// typeRef parses a type such as [ID!]!
+func (p *parser) typeRef() (*typeRef, error) {
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		inner, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		t.list = inner
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else {
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		t.name = name
+	}
+	nonNull, err := p.skip("!")
+	t.nonNull = nonNull
+	return t, err
+}
+
+// fragment parses a fragment definition
+func (p *parser) fragment() (*fragment, error) {
+	frag := &fragment{pos: p.token.pos}
+	if err := p.keyword("fragment"); err != nil {
+		return nil, err
+	}
+	name, _, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, &Error{Message: `a fragment cannot be named "on"`, Position: frag.pos}
+	}
+	frag.name = name
+	if err := p.keyword("on"); err != nil {
+		return nil, err
+	}
+	if frag.typeCondition, _, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if frag.selectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+// selectionSet parses { ... }
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.peek("}") {
+		if p.token.kind == tokenEOF {
+			return nil, &Error{Message: `expected "}", found end of query`, Position: p.token.pos}
+		}
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, &Error{Message: "a selection set cannot be empty", Position: p.token.pos}
+	}
+	return selections, p.advance()
+}
+
+// selection parses a field, fragment spread or inline fragment
+func (p *parser) selection() (selection, error) {
+	pos := p.token.pos
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.token.kind == tokenName && p.token.value != "on" {
+			name, _, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			directives, err := p.directives()
+			if err != nil {
+				return nil, err
+			}
+			return &fragmentSpread{name: name, directives: directives, pos: pos}, nil
+		}
+
+		inline := &inlineFragment{pos: pos}
+		if p.token.kind == tokenName {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if inline.typeCondition, _, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if inline.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		if inline.selectionSet, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+		return inline, nil
+	}
+
+	f := &field{pos: pos}
+	name, _, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f.name = name
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = name
+		if f.name, _, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if f.arguments, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.selectionSet, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// arguments parses (name: value, ...)
+func (p *parser) arguments(constant bool) ([]*argument, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var arguments []*argument
+	for !p.peek(")") {
+		name, pos, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, &argument{name: name, value: v, pos: pos})
+	}
+	if len(arguments) == 0 {
+		return nil, &Error{Message: "an argument list cannot be empty", Position: p.token.pos}
+	}
+	return arguments, p.advance()
+}
+
+// directives parses any @name(arguments)
+func (p *parser) directives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek("@") {
+		pos := p.token.pos
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		d := &directive{name: name, pos: pos}
+		if p.peek("(") {
+			if d.arguments, err = p.arguments(false); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// value parses an input value. Constant values, such as variable defaults,
+// cannot refer to variables.
+func (p *parser) value(constant bool) (*value, error) {
+	t := p.token
+	v := &value{raw: t.value, pos: t.pos}
+	switch {
+	case t.kind == tokenPunctuator && t.value == "$" && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, _, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		v.kind, v.raw = valueVariable, name
+		return v, nil
+	case t.kind == tokenPunctuator && t.value == "[":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.kind = valueList
+		for !p.peek("]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+		}
+		return v, p.advance()
+	case t.kind == tokenPunctuator && t.value == "{":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.kind = valueObject
+		for !p.peek("}") {
+			name, pos, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			fieldValue, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.fields = append(v.fields, &objectField{name: name, value: fieldValue, pos: pos})
+		}
+		return v, p.advance()
+	case t.kind == tokenInt:
+		v.kind = valueInt
+	case t.kind == tokenFloat:
+		v.kind = valueFloat
+	case t.kind == tokenString:
+		v.kind = valueString
+	case t.kind == tokenName && (t.value == "true" || t.value == "false"):
+		v.kind = valueBoolean
+	case t.kind == tokenName && t.value == "null":
+		v.kind = valueNull
+	case t.kind == tokenName:
+		v.kind = valueEnum
+	default:
+		return nil, p.unexpected()
+	}
+	return v, p.advance()
+}
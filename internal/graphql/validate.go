@@ -0,0 +1,651 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"postgirl/internal/models"
+)
+
+// Error is a problem found in a query, at a position when it has one
+type Error struct {
+	Message  string   `json:"message"`
+	Position Position `json:"position"`
+}
+
+// Error returns the message, prefixed by the position
+func (e *Error) Error() string {
+	if e.Position.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Position.Line, e.Position.Column, e.Message)
+}
+
+// Errors is every problem found in a query
+type Errors []*Error
+
+// Error joins the messages of all the problems
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Meta fields every schema has without listing them
+var (
+	typenameField = models.GraphQLField{
+		Name: "__typename",
+		Type: nonNull("SCALAR", "String"),
+	}
+	schemaField = models.GraphQLField{
+		Name: "__schema",
+		Type: nonNull("OBJECT", "__Schema"),
+	}
+	typeField = models.GraphQLField{
+		Name: "__type",
+		Args: []models.GraphQLInputValue{{Name: "name", Type: nonNull("SCALAR", "String")}},
+		Type: models.GraphQLTypeRef{Kind: "OBJECT", Name: "__Type"},
+	}
+)
+
+// nonNull returns a reference to a non-null named type
+func nonNull(kind, name string) models.GraphQLTypeRef {
+	return models.GraphQLTypeRef{Kind: "NON_NULL", OfType: &models.GraphQLTypeRef{Kind: kind, Name: name}}
+}
+
+// validator collects the problems of one document
+type validator struct {
+	schema    *models.GraphQLSchema
+	doc       *document
+	fragments map[string]*fragment
+	errors    Errors
+}
+
+// Validate checks a GraphQL body's query against a schema: its syntax, that
+// every field, argument, type, fragment and directive it uses exists, that
+// fields are selected down to scalars, that variables are defined and used,
+// that literal arguments fit their types, and that the operation to run and
+// its required variables are given. It returns Errors, or nil for a valid query.
+func Validate(schema *models.GraphQLSchema, body *models.RequestBody) error {
+	doc, err := parse(body.Query)
+	if err != nil {
+		var syntaxErr *Error
+		if errors.As(err, &syntaxErr) {
+			return Errors{syntaxErr}
+		}
+		return err
+	}
+
+	v := &validator{schema: schema, doc: doc, fragments: make(map[string]*fragment)}
+	v.definitions()
+	op := v.selectOperation(body.OperationName)
+
+	for _, frag := range doc.fragments {
+		if v.fragments[frag.name] != frag {
+			continue
+		}
+		t, ok := v.typeCondition(frag.typeCondition, frag.pos)
+		if ok {
+			v.selections(t, frag.selectionSet)
+		}
+		v.directives(frag.directives, "FRAGMENT_DEFINITION")
+	}
+	v.fragmentUsage()
+
+	for _, o := range doc.operations {
+		v.operation(o)
+	}
+	if op != nil {
+		v.variableValues(op, body.Variables)
+	}
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errors, func(i, j int) bool {
+		a, b := v.errors[i].Position, v.errors[j].Position
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return v.errors
+}
+
+// errorf records a problem
+func (v *validator) errorf(pos Position, format string, args ...interface{}) {
+	v.errors = append(v.errors, &Error{Message: fmt.Sprintf(format, args...), Position: pos})
+}
+
+// definitions indexes the fragments and checks that operation and fragment
+// names are unique
+func (v *validator) definitions() {
+	operations := make(map[string]bool)
+	for _, op := range v.doc.operations {
+		if op.name == "" {
+			if len(v.doc.operations) > 1 {
+				v.errorf(op.pos, "an anonymous operation must be the only operation in the query")
+			}
+			continue
+		}
+		if operations[op.name] {
+			v.errorf(op.pos, "there can be only one operation named %q", op.name)
+		}
+		operations[op.name] = true
+	}
+	for _, frag := range v.doc.fragments {
+		if _, exists := v.fragments[frag.name]; exists {
+			v.errorf(frag.pos, "there can be only one fragment named %q", frag.name)
+			continue
+		}
+		v.fragments[frag.name] = frag
+	}
+}
+
+// selectOperation returns the operation that will run
+func (v *validator) selectOperation(name string) *operation {
+	if name != "" {
+		for _, op := range v.doc.operations {
+			if op.name == name {
+				return op
+			}
+		}
+		v.errorf(Position{}, "the query has no operation named %q", name)
+		return nil
+	}
+	if len(v.doc.operations) > 1 {
+		v.errorf(Position{}, "the query has several operations; set the operation name to choose one")
+		return nil
+	}
+	if len(v.doc.operations) == 0 {
+		v.errorf(Position{}, "the query has no operations")
+		return nil
+	}
+	return v.doc.operations[0]
+}
+
+// operation checks an operation and its variables
+func (v *validator) operation(op *operation) {
+	rootName := map[string]string{
+		"query":        v.schema.QueryType,
+		"mutation":     v.schema.MutationType,
+		"subscription": v.schema.SubscriptionType,
+	}[op.kind]
+	if rootName == "" {
+		v.errorf(op.pos, "the schema does not support %ss", op.kind)
+		return
+	}
+	root, ok := v.schema.Type(rootName)
+	if !ok {
+		v.errorf(op.pos, "the schema has no %s type %q", op.kind, rootName)
+		return
+	}
+
+	defined := make(map[string]*variableDefinition)
+	for _, variable := range op.variables {
+		if _, exists := defined[variable.name]; exists {
+			v.errorf(variable.pos, "there can be only one variable named \"$%s\"", variable.name)
+		}
+		defined[variable.name] = variable
+		named := variable.typ
+		for named.list != nil {
+			named = named.list
+		}
+		if t, ok := v.schema.Type(named.name); !ok {
+			v.errorf(variable.pos, "variable \"$%s\" has unknown type %q", variable.name, named.name)
+		} else if t.Kind != "SCALAR" && t.Kind != "ENUM" && t.Kind != "INPUT_OBJECT" {
+			v.errorf(variable.pos, "variable \"$%s\" cannot be of non-input type %q", variable.name, variable.typ)
+		}
+	}
+
+	location := strings.ToUpper(op.kind)
+	v.directives(op.directives, location)
+	v.selections(root, op.selectionSet)
+
+	// Variables used by the operation, including through its fragments
+	used := make(map[string]Position)
+	v.collectVariables(op.selectionSet, used, make(map[string]bool))
+	for _, d := range op.directives {
+		for _, arg := range d.arguments {
+			collectValueVariables(arg.value, used)
+		}
+	}
+
+	label := "the anonymous operation"
+	if op.name != "" {
+		label = fmt.Sprintf("operation %q", op.name)
+	}
+	for name, pos := range used {
+		if _, ok := defined[name]; !ok {
+			v.errorf(pos, "variable \"$%s\" is not defined by %s", name, label)
+		}
+	}
+	for _, variable := range op.variables {
+		if _, ok := used[variable.name]; !ok {
+			v.errorf(variable.pos, "variable \"$%s\" is never used in %s", variable.name, label)
+		}
+	}
+}
+
+// selections checks a selection set on a type
+func (v *validator) selections(parent *models.GraphQLType, selections []selection) {
+	for _, s := range selections {
+		switch s := s.(type) {
+		case *field:
+			v.field(parent, s)
+		case *fragmentSpread:
+			v.directives(s.directives, "FRAGMENT_SPREAD")
+			frag, ok := v.fragments[s.name]
+			if !ok {
+				v.errorf(s.pos, "unknown fragment %q", s.name)
+				continue
+			}
+			if t, ok := v.schema.Type(frag.typeCondition); ok && !v.overlaps(parent, t) {
+				v.errorf(s.pos, "fragment %q cannot be spread here as objects of type %q can never be of type %q", s.name, parent.Name, t.Name)
+			}
+		case *inlineFragment:
+			v.directives(s.directives, "INLINE_FRAGMENT")
+			t := parent
+			if s.typeCondition != "" {
+				var ok bool
+				if t, ok = v.typeCondition(s.typeCondition, s.pos); !ok {
+					continue
+				}
+				if !v.overlaps(parent, t) {
+					v.errorf(s.pos, "fragment cannot be spread here as objects of type %q can never be of type %q", parent.Name, t.Name)
+				}
+			}
+			v.selections(t, s.selectionSet)
+		}
+	}
+}
+
+// field checks a field selection and its arguments and subfields
+func (v *validator) field(parent *models.GraphQLType, f *field) {
+	definition, ok := v.lookupField(parent, f.name)
+	if !ok {
+		v.errorf(f.pos, "cannot query field %q on type %q", f.name, parent.Name)
+		return
+	}
+	v.directives(f.directives, "FIELD")
+	v.arguments(definition.Args, f.arguments, fmt.Sprintf("field \"%s.%s\"", parent.Name, f.name), f.pos)
+
+	namedType := definition.Type.NamedType()
+	t, ok := v.schema.Type(namedType)
+	if !ok {
+		// The schema refers to a type it does not list; nothing more to check
+		return
+	}
+	switch t.Kind {
+	case "SCALAR", "ENUM":
+		if f.selectionSet != nil {
+			v.errorf(f.pos, "field %q must not have a selection since type %q has no subfields", f.name, definition.Type)
+		}
+	default:
+		if f.selectionSet == nil {
+			v.errorf(f.pos, "field %q of type %q must have a selection of subfields", f.name, definition.Type)
+			return
+		}
+		v.selections(t, f.selectionSet)
+	}
+}
+
+// lookupField finds a field of a type, including the meta fields
+func (v *validator) lookupField(parent *models.GraphQLType, name string) (*models.GraphQLField, bool) {
+	switch {
+	case name == typenameField.Name:
+		return &typenameField, true
+	case parent.Name == v.schema.QueryType && name == schemaField.Name:
+		return &schemaField, true
+	case parent.Name == v.schema.QueryType && name == typeField.Name:
+		return &typeField, true
+	case parent.Kind == "OBJECT" || parent.Kind == "INTERFACE":
+		return parent.Field(name)
+	}
+	return nil, false
+}
+
+// arguments checks the arguments given to a field or directive
+func (v *validator) arguments(definitions []models.GraphQLInputValue, arguments []*argument, owner string, pos Position) {
+	given := make(map[string]bool)
+	for _, arg := range arguments {
+		if given[arg.name] {
+			v.errorf(arg.pos, "there can be only one argument named %q", arg.name)
+			continue
+		}
+		given[arg.name] = true
+
+		definition := findInputValue(definitions, arg.name)
+		if definition == nil {
+			v.errorf(arg.pos, "unknown argument %q on %s", arg.name, owner)
+			continue
+		}
+		v.value(definition.Type, arg.value, fmt.Sprintf("argument %q", arg.name))
+	}
+	for _, definition := range definitions {
+		if definition.Type.Kind == "NON_NULL" && definition.DefaultValue == nil && !given[definition.Name] {
+			v.errorf(pos, "%s argument %q of type %q is required, but it was not provided", owner, definition.Name, definition.Type)
+		}
+	}
+}
+
+// directives checks that directives exist and their arguments
+func (v *validator) directives(directives []*directive, location string) {
+	for _, d := range directives {
+		var definition *models.GraphQLDirective
+		for i := range v.schema.Directives {
+			if v.schema.Directives[i].Name == d.name {
+				definition = &v.schema.Directives[i]
+			}
+		}
+		if definition == nil {
+			if len(v.schema.Directives) > 0 {
+				v.errorf(d.pos, "unknown directive \"@%s\"", d.name)
+			}
+			continue
+		}
+		if len(definition.Locations) > 0 && !contains(definition.Locations, location) {
+			v.errorf(d.pos, "directive \"@%s\" may not be used on %s", d.name, location)
+		}
+		v.arguments(definition.Args, d.arguments, fmt.Sprintf("directive \"@%s\"", d.name), d.pos)
+	}
+}
+
+// value checks that a literal value fits an input type. Variables are
+// checked against their definitions, not here.
+func (v *validator) value(ref models.GraphQLTypeRef, val *value, context string) {
+	if val.kind == valueVariable {
+		return
+	}
+	if ref.Kind == "NON_NULL" {
+		if val.kind == valueNull {
+			v.errorf(val.pos, "%s of type %q cannot be null", context, ref)
+			return
+		}
+		v.value(*ref.OfType, val, context)
+		return
+	}
+	if val.kind == valueNull {
+		return
+	}
+	if ref.Kind == "LIST" {
+		if val.kind != valueList {
+			// A single value is accepted as a list of one
+			v.value(*ref.OfType, val, context)
+			return
+		}
+		for _, item := range val.list {
+			v.value(*ref.OfType, item, context)
+		}
+		return
+	}
+
+	t, ok := v.schema.Type(ref.Name)
+	if !ok {
+		return
+	}
+	switch t.Kind {
+	case "SCALAR":
+		expected := map[string][]valueKind{
+			"Int":     {valueInt},
+			"Float":   {valueInt, valueFloat},
+			"String":  {valueString},
+			"Boolean": {valueBoolean},
+			"ID":      {valueString, valueInt},
+		}[t.Name]
+		if expected != nil && !containsKind(expected, val.kind) {
+			v.errorf(val.pos, "%s expects type %q, found %s", context, t.Name, describeValue(val))
+		}
+	case "ENUM":
+		if val.kind != valueEnum {
+			v.errorf(val.pos, "%s expects enum %q, found %s", context, t.Name, describeValue(val))
+			return
+		}
+		for _, enumValue := range t.EnumValues {
+			if enumValue.Name == val.raw {
+				return
+			}
+		}
+		v.errorf(val.pos, "value %q does not exist in enum %q", val.raw, t.Name)
+	case "INPUT_OBJECT":
+		if val.kind != valueObject {
+			v.errorf(val.pos, "%s expects input object %q, found %s", context, t.Name, describeValue(val))
+			return
+		}
+		given := make(map[string]bool)
+		for _, f := range val.fields {
+			given[f.name] = true
+			definition := findInputValue(t.InputFields, f.name)
+			if definition == nil {
+				v.errorf(f.pos, "field %q is not defined by type %q", f.name, t.Name)
+				continue
+			}
+			v.value(definition.Type, f.value, fmt.Sprintf("field \"%s.%s\"", t.Name, f.name))
+		}
+		for _, definition := range t.InputFields {
+			if definition.Type.Kind == "NON_NULL" && definition.DefaultValue == nil && !given[definition.Name] {
+				v.errorf(val.pos, "field \"%s.%s\" of required type %q was not provided", t.Name, definition.Name, definition.Type)
+			}
+		}
+	}
+}
+
+// typeCondition returns the composite type a fragment applies to
+func (v *validator) typeCondition(name string, pos Position) (*models.GraphQLType, bool) {
+	t, ok := v.schema.Type(name)
+	if !ok {
+		v.errorf(pos, "unknown type %q", name)
+		return nil, false
+	}
+	if t.Kind != "OBJECT" && t.Kind != "INTERFACE" && t.Kind != "UNION" {
+		v.errorf(pos, "fragment cannot condition on non composite type %q", name)
+		return nil, false
+	}
+	return t, true
+}
+
+// overlaps reports whether an object could be of both types
+func (v *validator) overlaps(a, b *models.GraphQLType) bool {
+	possible := func(t *models.GraphQLType) []string {
+		if t.Kind == "OBJECT" {
+			return []string{t.Name}
+		}
+		return t.PossibleTypes
+	}
+	for _, name := range possible(a) {
+		if contains(possible(b), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// fragmentUsage checks that every fragment is used and none spreads itself
+func (v *validator) fragmentUsage() {
+	used := make(map[string]bool)
+	for _, op := range v.doc.operations {
+		v.collectSpreads(op.selectionSet, used)
+	}
+	for name, frag := range v.fragments {
+		if !used[name] {
+			v.errorf(frag.pos, "fragment %q is never used", name)
+		}
+		if v.spreadsItself(name, frag.selectionSet, make(map[string]bool)) {
+			v.errorf(frag.pos, "cannot spread fragment %q within itself", name)
+		}
+	}
+}
+
+// collectSpreads records the fragments a selection set spreads, directly or
+// through other fragments
+func (v *validator) collectSpreads(selections []selection, used map[string]bool) {
+	for _, s := range selections {
+		switch s := s.(type) {
+		case *field:
+			v.collectSpreads(s.selectionSet, used)
+		case *inlineFragment:
+			v.collectSpreads(s.selectionSet, used)
+		case *fragmentSpread:
+			if used[s.name] {
+				continue
+			}
+			used[s.name] = true
+			if frag, ok := v.fragments[s.name]; ok {
+				v.collectSpreads(frag.selectionSet, used)
+			}
+		}
+	}
+}
+
+// spreadsItself reports whether a selection set leads back to fragment name
+func (v *validator) spreadsItself(name string, selections []selection, visited map[string]bool) bool {
+	for _, s := range selections {
+		switch s := s.(type) {
+		case *field:
+			if v.spreadsItself(name, s.selectionSet, visited) {
+				return true
+			}
+		case *inlineFragment:
+			if v.spreadsItself(name, s.selectionSet, visited) {
+				return true
+			}
+		case *fragmentSpread:
+			if s.name == name {
+				return true
+			}
+			if visited[s.name] {
+				continue
+			}
+			visited[s.name] = true
+			if frag, ok := v.fragments[s.name]; ok && v.spreadsItself(name, frag.selectionSet, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// collectVariables records the variables a selection set uses, directly or
+// through fragments
+func (v *validator) collectVariables(selections []selection, used map[string]Position, visited map[string]bool) {
+	for _, s := range selections {
+		switch s := s.(type) {
+		case *field:
+			for _, arg := range s.arguments {
+				collectValueVariables(arg.value, used)
+			}
+			collectDirectiveVariables(s.directives, used)
+			v.collectVariables(s.selectionSet, used, visited)
+		case *inlineFragment:
+			collectDirectiveVariables(s.directives, used)
+			v.collectVariables(s.selectionSet, used, visited)
+		case *fragmentSpread:
+			collectDirectiveVariables(s.directives, used)
+			if visited[s.name] {
+				continue
+			}
+			visited[s.name] = true
+			if frag, ok := v.fragments[s.name]; ok {
+				collectDirectiveVariables(frag.directives, used)
+				v.collectVariables(frag.selectionSet, used, visited)
+			}
+		}
+	}
+}
+
+// collectDirectiveVariables records the variables directives use
+func collectDirectiveVariables(directives []*directive, used map[string]Position) {
+	for _, d := range directives {
+		for _, arg := range d.arguments {
+			collectValueVariables(arg.value, used)
+		}
+	}
+}
+
+// collectValueVariables records the variables a value uses
+func collectValueVariables(val *value, used map[string]Position) {
+	switch val.kind {
+	case valueVariable:
+		if _, exists := used[val.raw]; !exists {
+			used[val.raw] = val.pos
+		}
+	case valueList:
+		for _, item := range val.list {
+			collectValueVariables(item, used)
+		}
+	case valueObject:
+		for _, f := range val.fields {
+			collectValueVariables(f.value, used)
+		}
+	}
+}
+
+// variableValues checks that the variables JSON gives every required variable
+// of the operation that will run
+func (v *validator) variableValues(op *operation, variables string) {
+	values := make(map[string]json.RawMessage)
+	if strings.TrimSpace(variables) != "" {
+		if err := json.Unmarshal([]byte(variables), &values); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				v.errorf(Position{}, "variables are not valid JSON: %v", err)
+			} else {
+				v.errorf(Position{}, "variables must be a JSON object")
+			}
+			return
+		}
+	}
+	for _, variable := range op.variables {
+		if !variable.typ.nonNull || variable.hasDefault {
+			continue
+		}
+		if raw, ok := values[variable.name]; !ok || string(raw) == "null" {
+			v.errorf(variable.pos, "variable \"$%s\" of required type %q was not provided", variable.name, variable.typ)
+		}
+	}
+}
+
+// findInputValue finds an argument or input field by name
+func findInputValue(values []models.GraphQLInputValue, name string) *models.GraphQLInputValue {
+	for i := range values {
+		if values[i].Name == name {
+			return &values[i]
+		}
+	}
+	return nil
+}
+
+// describeValue names the kind of a literal for an error message
+func describeValue(val *value) string {
+	switch val.kind {
+	case valueString:
+		return fmt.Sprintf("%q", val.raw)
+	case valueList:
+		return "a list"
+	case valueObject:
+		return "an object"
+	}
+	return val.raw
+}
+
+// contains reports whether a list contains a string
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// containsKind reports whether a list contains a value kind
+func containsKind(kinds []valueKind, kind valueKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
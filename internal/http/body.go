@@ -0,0 +1,245 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+	"postgirl/internal/models"
+)
+
+// quoteEscaper escapes quotes and backslashes in Content-Disposition parameters
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// multipartBody encodes parts as multipart/form-data and returns the body with
+// its content type. The body is built up front so retries can resend it.
+func multipartBody(parts []models.BodyPart) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	for _, part := range parts {
+		filename, contentType := part.Filename, part.ContentType
+		var content []byte
+
+		switch part.Type {
+		case "", "text":
+			content = []byte(part.Value)
+
+		case "file":
+			if part.FilePath == "" {
+				return nil, "", fmt.Errorf("file path required for multipart part %q", part.Name)
+			}
+			data, err := os.ReadFile(part.FilePath)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to read multipart file %q: %w", part.Name, err)
+			}
+			if filename == "" {
+				filename = filepath.Base(part.FilePath)
+			}
+			if contentType == "" {
+				contentType = mime.TypeByExtension(filepath.Ext(filename))
+			}
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			content = data
+
+		default:
+			return nil, "", fmt.Errorf("unsupported multipart part type: %s", part.Type)
+		}
+
+		disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(part.Name))
+		if filename != "" {
+			disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(filename))
+		}
+		header := textproto.MIMEHeader{"Content-Disposition": {disposition}}
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to write multipart part %q: %w", part.Name, err)
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, "", fmt.Errorf("failed to write multipart part %q: %w", part.Name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to finish multipart body: %w", err)
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
+// urlencodedBody encodes the enabled fields as application/x-www-form-urlencoded,
+// keeping their order and any repeated keys
+func urlencodedBody(fields models.KeyValues) string {
+	pairs := make([]string, 0, len(fields))
+	for _, field := range fields.Enabled() {
+		pairs = append(pairs, url.QueryEscape(field.Key)+"="+url.QueryEscape(field.Value))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// graphqlRequest is the JSON body of a GraphQL request
+type graphqlRequest struct {
+	Query         string          `json:"query"`
+	Variables     json.RawMessage `json:"variables,omitempty"`
+	OperationName string          `json:"operationName,omitempty"`
+}
+
+// newGraphQLRequest checks that a GraphQL body's variables are a JSON object
+// and builds the request to send
+func newGraphQLRequest(body *models.RequestBody) (*graphqlRequest, error) {
+	request := &graphqlRequest{Query: body.Query, OperationName: body.OperationName}
+	if variables := strings.TrimSpace(body.Variables); variables != "" {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(variables), &object); err != nil {
+			return nil, fmt.Errorf("GraphQL variables must be a JSON object: %w", err)
+		}
+		request.Variables = json.RawMessage(variables)
+	}
+	return request, nil
+}
+
+// graphqlBody encodes a GraphQL body as JSON for a POST request
+func graphqlBody(body *models.RequestBody) ([]byte, error) {
+	request, err := newGraphQLRequest(body)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(request)
+}
+
+// graphqlParams encodes a GraphQL body as query parameters for a GET request
+func graphqlParams(body *models.RequestBody) (models.KeyValues, error) {
+	request, err := newGraphQLRequest(body)
+	if err != nil {
+		return nil, err
+	}
+	params := models.KeyValues{}
+	params.Add("query", request.Query)
+	if request.Variables != nil {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, request.Variables); err != nil {
+			return nil, err
+		}
+		params.Add("variables", compact.String())
+	}
+	if request.OperationName != "" {
+		params.Add("operationName", request.OperationName)
+	}
+	return params, nil
+}
+
+// bodyFileContextKey carries a binary request body file on the request context
+type bodyFileContextKey struct{}
+
+// bodyFile is a file sent as a binary request body. It is kept out of resty,
+// which would read it into memory, and attached to each attempt by
+// attachBodyFile instead.
+type bodyFile struct {
+	file *os.File
+	size int64
+}
+
+// openBodyFile opens a file to send as a request body
+func openBodyFile(path string) (*bodyFile, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file path required for binary body")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open body file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open body file: %w", err)
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, fmt.Errorf("body file %s is a directory", path)
+	}
+	return &bodyFile{file: file, size: info.Size()}, nil
+}
+
+// withBodyFile sends a file as the body of a request
+func withBodyFile(ctx context.Context, body *bodyFile) context.Context {
+	return context.WithValue(ctx, bodyFileContextKey{}, body)
+}
+
+// attachBodyFile streams the body file on a request's context, if any. Each
+// attempt gets a fresh reader with a known length, and GetBody lets digest
+// auth replay the body and request signers hash it.
+func attachBodyFile(_ *resty.Client, req *http.Request) error {
+	body, ok := req.Context().Value(bodyFileContextKey{}).(*bodyFile)
+	if !ok {
+		return nil
+	}
+
+	req.ContentLength = body.size
+	req.GetBody = func() (io.ReadCloser, error) {
+		if body.size == 0 {
+			return http.NoBody, nil
+		}
+		return io.NopCloser(io.NewSectionReader(body.file, 0, body.size)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// responseContentType returns the content type a response declares, or one
+// sniffed from its body when it declares none
+func responseContentType(header http.Header, body []byte) string {
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		return contentType
+	}
+	if len(body) == 0 {
+		return ""
+	}
+	return http.DetectContentType(body)
+}
+
+// isBinaryBody reports whether a body should be treated as bytes rather than
+// text: its content type is not textual, or it is not valid UTF-8
+func isBinaryBody(contentType string, body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	if !utf8.Valid(body) {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	switch {
+	case mediaType == "",
+		strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return false
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/ecmascript",
+		"application/x-www-form-urlencoded", "application/yaml", "application/x-yaml",
+		"application/graphql", "application/x-ndjson", "application/ld+json":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,498 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"postgirl/internal/models"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ErrCertificateNotFound is returned when updating a certificate or CA bundle
+// by an ID that does not exist
+var ErrCertificateNotFound = errors.New("certificate not found")
+
+// CertificateStore persists client certificates and CA bundles
+type CertificateStore interface {
+	SaveCertificate(cert *models.Certificate) error
+	GetAllCertificates() ([]*models.Certificate, error)
+	DeleteCertificate(id string) error
+	SaveCACertificate(ca *models.CACertificate) error
+	GetAllCACertificates() ([]*models.CACertificate, error)
+	DeleteCACertificate(id string) error
+}
+
+// tlsVersions maps the accepted MinTLSVersion values to crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// CertificateManager holds client certificates, extra CA bundles and per-host
+// TLS settings, and writes every change through to a CertificateStore
+type CertificateManager struct {
+	mu             sync.RWMutex
+	store          CertificateStore
+	certificates   map[string]*models.Certificate
+	clientCerts    map[string]*tls.Certificate
+	caCertificates map[string]*models.CACertificate
+	roots          *x509.CertPool
+
+	// generation changes whenever the settings do, so cached transports can be rebuilt
+	generation uint64
+}
+
+// NewCertificateManager creates a certificate manager loaded from the store.
+// Entries that fail to load are reported but don't stop the others.
+func NewCertificateManager(store CertificateStore) (*CertificateManager, error) {
+	m := &CertificateManager{
+		store:          store,
+		certificates:   make(map[string]*models.Certificate),
+		clientCerts:    make(map[string]*tls.Certificate),
+		caCertificates: make(map[string]*models.CACertificate),
+	}
+
+	if store == nil {
+		return m, nil
+	}
+
+	var errs []string
+
+	certs, err := store.GetAllCertificates()
+	if err != nil {
+		return m, fmt.Errorf("failed to load certificates: %w", err)
+	}
+	for _, cert := range certs {
+		clientCert, err := parseClientCertificate(cert)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", cert.Host, err))
+			continue
+		}
+		m.certificates[cert.ID] = cert
+		if clientCert != nil {
+			m.clientCerts[cert.ID] = clientCert
+		}
+	}
+
+	cas, err := store.GetAllCACertificates()
+	if err != nil {
+		return m, fmt.Errorf("failed to load CA certificates: %w", err)
+	}
+	for _, ca := range cas {
+		m.caCertificates[ca.ID] = ca
+	}
+	if err := m.rebuildRoots(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return m, fmt.Errorf("failed to load certificates: %s", strings.Join(errs, "; "))
+	}
+	return m, nil
+}
+
+// Certificates returns the client certificates and host settings sorted by host
+func (m *CertificateManager) Certificates() []models.Certificate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	certs := make([]models.Certificate, 0, len(m.certificates))
+	for _, cert := range m.certificates {
+		certs = append(certs, *cert)
+	}
+	sort.Slice(certs, func(a, b int) bool {
+		return certs[a].Host < certs[b].Host
+	})
+	return certs
+}
+
+// SaveCertificate validates and adds or replaces a client certificate and its
+// host settings. An empty ID creates a new entry; any other ID must exist.
+// Secrets an update leaves empty keep their stored values; see keepSecrets.
+func (m *CertificateManager) SaveCertificate(cert *models.Certificate) error {
+	cert.Host = strings.ToLower(strings.TrimSpace(cert.Host))
+	if cert.Host == "" {
+		return fmt.Errorf("certificate host is required")
+	}
+	if _, ok := tlsVersions[cert.MinTLSVersion]; !ok && cert.MinTLSVersion != "" {
+		return fmt.Errorf("unsupported minimum TLS version: %s", cert.MinTLSVersion)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.certificates[cert.ID]
+	if cert.ID != "" && !ok {
+		return ErrCertificateNotFound
+	}
+	if ok {
+		keepSecrets(cert, existing)
+	}
+	clientCert, err := parseClientCertificate(cert)
+	if err != nil {
+		return err
+	}
+
+	if cert.ID == "" {
+		cert.ID = generateID()
+		cert.CreatedAt = time.Now()
+	} else if cert.CreatedAt.IsZero() {
+		cert.CreatedAt = existing.CreatedAt
+	}
+	cert.UpdatedAt = time.Now()
+
+	if m.store != nil {
+		if err := m.store.SaveCertificate(cert); err != nil {
+			return err
+		}
+	}
+
+	saved := *cert
+	m.certificates[cert.ID] = &saved
+	if clientCert != nil {
+		m.clientCerts[cert.ID] = clientCert
+	} else {
+		delete(m.clientCerts, cert.ID)
+	}
+	m.generation++
+	return nil
+}
+
+// DeleteCertificate removes a client certificate and its host settings
+func (m *CertificateManager) DeleteCertificate(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.store != nil {
+		if err := m.store.DeleteCertificate(id); err != nil {
+			return err
+		}
+	}
+
+	delete(m.certificates, id)
+	delete(m.clientCerts, id)
+	m.generation++
+	return nil
+}
+
+// CACertificates returns the extra CA bundles
+func (m *CertificateManager) CACertificates() []models.CACertificate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cas := make([]models.CACertificate, 0, len(m.caCertificates))
+	for _, ca := range m.caCertificates {
+		cas = append(cas, *ca)
+	}
+	sort.Slice(cas, func(a, b int) bool {
+		return cas[a].CreatedAt.Before(cas[b].CreatedAt)
+	})
+	return cas
+}
+
+// SaveCACertificate validates and adds or replaces a CA bundle. An empty ID
+// creates a new entry; any other ID must exist.
+func (m *CertificateManager) SaveCACertificate(ca *models.CACertificate) error {
+	if !x509.NewCertPool().AppendCertsFromPEM([]byte(ca.PEM)) {
+		return fmt.Errorf("CA bundle contains no PEM certificates")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ca.ID == "" {
+		ca.ID = generateID()
+		ca.CreatedAt = time.Now()
+	} else if existing, ok := m.caCertificates[ca.ID]; !ok {
+		return ErrCertificateNotFound
+	} else if ca.CreatedAt.IsZero() {
+		ca.CreatedAt = existing.CreatedAt
+	}
+
+	if m.store != nil {
+		if err := m.store.SaveCACertificate(ca); err != nil {
+			return err
+		}
+	}
+
+	saved := *ca
+	m.caCertificates[ca.ID] = &saved
+	m.generation++
+	return m.rebuildRoots()
+}
+
+// DeleteCACertificate removes a CA bundle
+func (m *CertificateManager) DeleteCACertificate(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.store != nil {
+		if err := m.store.DeleteCACertificate(id); err != nil {
+			return err
+		}
+	}
+
+	delete(m.caCertificates, id)
+	m.generation++
+	return m.rebuildRoots()
+}
+
+// rebuildRoots recomputes the trusted roots: the system pool plus every CA
+// bundle, or nil to use the system pool as is. The caller must hold the lock.
+func (m *CertificateManager) rebuildRoots() error {
+	if len(m.caCertificates) == 0 {
+		m.roots = nil
+		return nil
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+
+	var invalid []string
+	for _, ca := range m.caCertificates {
+		if !roots.AppendCertsFromPEM([]byte(ca.PEM)) {
+			invalid = append(invalid, ca.Name)
+		}
+	}
+	m.roots = roots
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("CA bundles contain no PEM certificates: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration for a request to host ("host" or
+// "host:port"), a key identifying it for transport reuse and the generation
+// of the settings it came from
+func (m *CertificateManager) tlsConfig(host, defaultPort string) (string, *tls.Config, uint64) {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname, port = host, defaultPort
+	}
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var match *models.Certificate
+	bestScore := 0
+	for _, cert := range m.certificates {
+		if score := hostPatternScore(cert.Host, hostname, port); score > bestScore {
+			match, bestScore = cert, score
+		}
+	}
+
+	if match == nil {
+		if m.roots == nil {
+			return "", nil, m.generation
+		}
+		return "ca", &tls.Config{RootCAs: m.roots}, m.generation
+	}
+
+	config := &tls.Config{
+		RootCAs:            m.roots,
+		InsecureSkipVerify: match.InsecureSkipVerify,
+		MinVersion:         tlsVersions[match.MinTLSVersion],
+	}
+	if clientCert := m.clientCerts[match.ID]; clientCert != nil {
+		// Always offer the configured certificate; servers behind private CAs
+		// often don't advertise acceptable issuers that would match it
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return clientCert, nil
+		}
+	}
+	return "cert:" + match.ID, config, m.generation
+}
+
+// hostPatternScore reports how specifically a host pattern matches a host and
+// port, or 0 when it doesn't. Exact hosts beat wildcards, which beat "*", and
+// a pattern with a port beats the same pattern without one.
+func hostPatternScore(pattern, hostname, port string) int {
+	pattern = strings.TrimPrefix(strings.TrimPrefix(pattern, "https://"), "http://")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	if pattern == "*" {
+		return 1
+	}
+
+	patternHost, patternPort, err := net.SplitHostPort(pattern)
+	if err != nil {
+		patternHost, patternPort = pattern, ""
+	}
+	if patternPort != "" && patternPort != port {
+		return 0
+	}
+
+	score := 0
+	switch {
+	case patternHost == hostname:
+		score = 3000
+	case strings.HasPrefix(patternHost, "*.") && strings.HasSuffix(hostname, patternHost[1:]):
+		// Longer wildcard suffixes are more specific
+		score = 2000 + len(patternHost)
+	default:
+		return 0
+	}
+	if patternPort != "" {
+		score += 500
+	}
+	return score
+}
+
+// keepSecrets fills in the private key, PKCS#12 bundle and passphrase an
+// update left empty from the stored entry, since clients are never sent them.
+// Each is kept only while what it belongs to is unchanged: the key while the
+// certificate is, the bundle while no PEM pair replaces it, and the
+// passphrase while the key or bundle is. A bundle is removed by deleting the
+// entry.
+func keepSecrets(cert, stored *models.Certificate) {
+	if cert.PFX == "" && cert.KeyPEM == "" {
+		if cert.CertPEM == "" {
+			cert.PFX = stored.PFX
+		}
+		if cert.CertPEM == stored.CertPEM {
+			cert.KeyPEM = stored.KeyPEM
+		}
+	}
+	if cert.Passphrase == "" && cert.PFX == stored.PFX && cert.KeyPEM == stored.KeyPEM {
+		cert.Passphrase = stored.Passphrase
+	}
+}
+
+// parseClientCertificate loads the client certificate of a Certificate from
+// PKCS#12 or PEM. It returns nil when none is configured.
+func parseClientCertificate(cert *models.Certificate) (*tls.Certificate, error) {
+	if cert.PFX != "" {
+		data, err := base64.StdEncoding.DecodeString(cert.PFX)
+		if err != nil {
+			return nil, fmt.Errorf("PKCS#12 bundle must be base64 encoded: %w", err)
+		}
+		key, leaf, chain, err := pkcs12.DecodeChain(data, cert.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode PKCS#12 bundle: %w", err)
+		}
+
+		clientCert := &tls.Certificate{
+			Certificate: [][]byte{leaf.Raw},
+			PrivateKey:  key,
+			Leaf:        leaf,
+		}
+		for _, ca := range chain {
+			clientCert.Certificate = append(clientCert.Certificate, ca.Raw)
+		}
+		return clientCert, nil
+	}
+
+	if cert.CertPEM == "" && cert.KeyPEM == "" {
+		return nil, nil
+	}
+	if cert.CertPEM == "" || cert.KeyPEM == "" {
+		return nil, fmt.Errorf("both a PEM certificate and key are required")
+	}
+
+	keyPEM := []byte(cert.KeyPEM)
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("private key must be PEM encoded")
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, fmt.Errorf("encrypted PKCS#8 keys are not supported; use a PKCS#12 bundle instead")
+	}
+	// Legacy "Proc-Type: 4,ENCRYPTED" keys are still common for client certificates
+	if x509.IsEncryptedPEMBlock(block) {
+		der, err := x509.DecryptPEMBlock(block, []byte(cert.Passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+
+	clientCert, err := tls.X509KeyPair([]byte(cert.CertPEM), keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	return &clientCert, nil
+}
+
+// tlsTransport sends each HTTPS request through an http.Transport configured
+// with the TLS settings for its host
+type tlsTransport struct {
+	base  *http.Transport
+	certs *CertificateManager
+
+	mu         sync.Mutex
+	generation uint64
+	transports map[string]*http.Transport
+}
+
+// newTLSTransport wraps base so requests pick up the manager's TLS settings
+func newTLSTransport(base *http.Transport, certs *CertificateManager) *tlsTransport {
+	return &tlsTransport{
+		base:       base,
+		certs:      certs,
+		transports: make(map[string]*http.Transport),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.certs == nil || req.URL.Scheme != "https" {
+		return t.base.RoundTrip(req)
+	}
+
+	key, config, generation := t.certs.tlsConfig(req.URL.Host, "443")
+	if config == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	return t.transport(key, config, generation).RoundTrip(req)
+}
+
+// transport returns the cached transport for a TLS configuration, dropping
+// every cached transport once the settings have changed
+func (t *tlsTransport) transport(key string, config *tls.Config, generation uint64) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if generation != t.generation {
+		for _, transport := range t.transports {
+			transport.CloseIdleConnections()
+		}
+		t.transports = make(map[string]*http.Transport)
+		t.generation = generation
+	}
+
+	transport, ok := t.transports[key]
+	if !ok {
+		transport = t.base.Clone()
+		transport.TLSClientConfig = config
+		t.transports[key] = transport
+	}
+	return transport
+}
+
+// CloseIdleConnections closes idle connections on every transport
+func (t *tlsTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, transport := range t.transports {
+		transport.CloseIdleConnections()
+	}
+}
@@ -1,7 +1,16 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -12,60 +21,149 @@ import (
 type Client struct {
 	restyClient *resty.Client
 	config      *Config
+	oauth2      *oauth2Manager
+	mu          sync.RWMutex
 }
 
 // Config represents HTTP client configuration
 type Config struct {
+	// Timeout bounds each attempt of a request
 	Timeout     time.Duration
 	RetryCount  int
 	RetryDelay  time.Duration
+	// RetryOnStatus lists response status codes that are retried like failed attempts
+	RetryOnStatus []int
 	UserAgent   string
 	FollowRedirects bool
+	MaxRedirects int
+	// DisableKeepAlives closes the connection after each request instead of reusing it
+	DisableKeepAlives bool
+	CookieJar   *CookieJar
+	Certificates *CertificateManager
+	// Proxy holds the global proxy settings; nil uses HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	Proxy       *models.ProxyConfig
+	// ExpiryWarningDays warns about peer certificates expiring within this many days
+	ExpiryWarningDays int
+}
+
+// DefaultConfig returns the default HTTP client configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Timeout:           30 * time.Second,
+		RetryCount:        3,
+		RetryDelay:        1 * time.Second,
+		UserAgent:         "Litepost/1.0",
+		FollowRedirects:   true,
+		MaxRedirects:      10,
+		ExpiryWarningDays: 30,
+	}
 }
 
 // NewClient creates a new HTTP client
 func NewClient(config *Config) *Client {
 	if config == nil {
-		config = &Config{
-			Timeout:         30 * time.Second,
-			RetryCount:      3,
-			RetryDelay:      1 * time.Second,
-			UserAgent:       "Litepost/1.0",
-			FollowRedirects: true,
-		}
+		config = DefaultConfig()
 	}
 
+	// Timeouts, retries, redirects and keep-alive are applied per request
+	// from its settings, so resty's client-wide equivalents are left unset
 	client := resty.New()
-	client.SetTimeout(config.Timeout)
-	client.SetRetryCount(config.RetryCount)
-	client.SetRetryWaitTime(config.RetryDelay)
 	client.SetHeader("User-Agent", config.UserAgent)
-	client.SetRedirectPolicy(resty.FlexibleRedirectPolicy(10))
+	if config.CookieJar != nil {
+		client.SetCookieJar(config.CookieJar)
+	}
 
-	return &Client{
+	c := &Client{
 		restyClient: client,
 		config:      config,
 	}
+	client.SetRedirectPolicy(resty.RedirectPolicyFunc(c.checkRedirect))
+	client.SetPreRequestHook(c.prepareRequest)
+
+	base := client.GetClient().Transport.(*http.Transport)
+	base.Proxy = c.proxy
+
+	// Token requests use a client of their own, without the cookie jar, the
+	// pre-request hook or the transports that record the request's hops
+	tokenTransport := base.Clone()
+	tokenClient := resty.NewWithClient(&http.Client{Transport: newTLSTransport(tokenTransport, config.Certificates)})
+	tokenClient.SetHeader("User-Agent", config.UserAgent)
+	c.oauth2 = newOAuth2Manager(tokenClient)
+
+	// Responses are decoded by decodingTransport, which reports their size on the wire
+	base.DisableCompression = true
+	client.SetTransport(&redirectRecorder{transport: newDigestTransport(&signingTransport{transport: &decodingTransport{transport: newDialTransport(base, config.Certificates)}})})
+
+	return c
 }
 
-// Execute executes an HTTP request
-func (c *Client) Execute(req *models.Request) (*models.Response, error) {
-	start := time.Now()
+// Execute executes an HTTP request, giving up when ctx is done. Settings on
+// env, such as its proxy, host overrides and DNS server, override the
+// client's for this request; env may be nil. A unix:// URL sends the request
+// over a Unix socket.
+// The request's own settings override the client's timeout, retries,
+// redirects and keep-alive for this call only. Event streams are read as
+// they arrive; see WithEventHandler.
+func (c *Client) Execute(ctx context.Context, req *models.Request, env *models.Environment) (*models.Response, error) {
+	settings := c.settingsFor(req.Settings)
+	if err := validateCompression(settings.compression); err != nil {
+		return nil, err
+	}
+	ctx = withRequestSettings(ctx, settings)
+	if streams(req, settings) {
+		return c.stream(ctx, req, env, settings)
+	}
+
+	for attempt := 0; ; attempt++ {
+		tracer := newTimingTracer()
+		chain := &redirectChain{}
+		attemptCtx := withRedirectChain(httptrace.WithClientTrace(ctx, tracer.clientTrace()), chain)
+		r, cleanup, err := c.newRequest(attemptCtx, req, env)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.send(r, settings)
+		cleanup()
+		if attempt < settings.retryCount && settings.shouldRetry(ctx, resp, err) {
+			select {
+			case <-time.After(settings.retryDelay):
+				continue
+			case <-ctx.Done():
+			}
+		}
+		if errors.Is(err, errRedirectLimit) && resp != nil && resp.RawResponse != nil {
+			// Report a redirect loop as the last redirect with the chain that led to it
+			response := c.newResponse(req, resp, tracer, chain)
+			response.Error = err.Error()
+			return response, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		return c.newResponse(req, resp, tracer, chain), nil
+	}
+}
+
+// newRequest builds the resty request for one attempt. The returned cleanup
+// function releases resources, such as an open body file, once it has been sent.
+func (c *Client) newRequest(ctx context.Context, req *models.Request, env *models.Environment) (*resty.Request, func(), error) {
+	cleanup := func() {}
 
 	// Create resty request
 	r := c.restyClient.R()
-	
-	// Set headers
-	if req.Headers != nil {
-		r.SetHeaders(req.Headers)
+	r.SetContext(ctx)
+	if env != nil && env.Proxy != nil {
+		r.SetContext(withProxyConfig(r.Context(), env.Proxy))
 	}
-
-	// Set query parameters
-	if req.QueryParams != nil {
-		r.SetQueryParams(req.QueryParams)
+	
+	// Set headers, keeping repeated names
+	for _, header := range req.Headers.Enabled() {
+		r.Header.Add(header.Key, header.Value)
 	}
 
 	// Set request body
+	params := req.QueryParams.Enabled()
 	if req.Body != nil {
 		switch req.Body.Type {
 		case "json":
@@ -79,46 +177,165 @@ func (c *Client) Execute(req *models.Request) (*models.Response, error) {
 			r.SetBody(req.Body.Content)
 		case "raw":
 			r.SetBody(req.Body.Content)
+		case "multipart":
+			body, contentType, err := multipartBody(req.Body.Parts)
+			if err != nil {
+				return nil, nil, err
+			}
+			r.SetHeader("Content-Type", contentType)
+			r.SetBody(body)
+		case "urlencoded":
+			r.SetHeader("Content-Type", "application/x-www-form-urlencoded")
+			r.SetBody(urlencodedBody(req.Body.Fields))
+		case "binary":
+			body, err := openBodyFile(req.Body.FilePath)
+			if err != nil {
+				return nil, nil, err
+			}
+			cleanup = func() { body.file.Close() }
+			if r.Header.Get("Content-Type") == "" {
+				contentType := mime.TypeByExtension(filepath.Ext(req.Body.FilePath))
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				r.SetHeader("Content-Type", contentType)
+			}
+			r.SetContext(withBodyFile(r.Context(), body))
+		case "graphql":
+			// GraphQL over GET sends the operation in the query string
+			if req.Method == http.MethodGet {
+				extra, err := graphqlParams(req.Body)
+				if err != nil {
+					return nil, nil, err
+				}
+				params = append(params, extra...)
+			} else {
+				body, err := graphqlBody(req.Body)
+				if err != nil {
+					return nil, nil, err
+				}
+				r.SetHeader("Content-Type", "application/json")
+				r.SetBody(body)
+			}
+			if r.Header.Get("Accept") == "" {
+				r.SetHeader("Accept", "application/graphql-response+json, application/json")
+			}
 		}
 	}
 
 	// Apply authentication
 	if req.Auth != nil {
 		if err := c.applyAuth(r, req.Auth); err != nil {
-			return nil, fmt.Errorf("failed to apply authentication: %w", err)
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to apply authentication: %w", err)
 		}
 	}
 
-	// Set method and URL
+	// Set method and URL, with the query parameters appended in order. A
+	// unix:// URL is requested over HTTP through the socket it names; other
+	// URLs resolve with the environment's host overrides and DNS server.
+	target := dialTargetFor(env)
+	address := req.URL
+	if isUnixSocketURL(address) {
+		socket, httpURL, err := unixSocketURL(address)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		target = dialTarget{socket: socket}
+		address = httpURL
+	}
+	r.SetContext(withDialTarget(r.Context(), target))
 	r.Method = req.Method
-	r.URL = req.URL
+	r.URL = appendQuery(address, params)
+
+	return r, cleanup, nil
+}
 
-	// Execute request
-	resp, err := r.Send()
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+// send sends one attempt of a request within the attempt timeout
+func (c *Client) send(r *resty.Request, settings requestSettings) (*resty.Response, error) {
+	if settings.timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), settings.timeout)
+		defer cancel()
+		r.SetContext(ctx)
 	}
+	return r.Send()
+}
 
-	duration := time.Since(start)
+// newResponse converts a resty response into a response model
+func (c *Client) newResponse(req *models.Request, resp *resty.Response, tracer *timingTracer, chain *redirectChain) *models.Response {
+	timing := tracer.timing(time.Now())
+	remoteAddr, reused := tracer.connection()
 
-	// Convert headers to map
-	headers := make(map[string]string)
-	for key, values := range resp.Header() {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
+	raw := resp.Body()
+	contentType := responseContentType(resp.Header(), raw)
+	binary := isBinaryBody(contentType, raw)
+	body := string(raw)
+	if binary {
+		body = ""
+	}
+
+	// The body may have been decoded from the encoding it was received in
+	header := receivedHeader(resp.RawResponse)
+	wireSize := int64(len(raw))
+	var encoding string
+	var wire []byte
+	if received, ok := resp.RawResponse.Body.(*wireBody); ok {
+		wireSize = received.size
+		encoding = received.encoding()
+		wire = received.received()
 	}
 
 	return &models.Response{
-		ID:         generateID(),
-		RequestID:  req.ID,
-		StatusCode: resp.StatusCode(),
-		Headers:    headers,
-		Body:       string(resp.Body()),
-		Size:       int64(len(resp.Body())),
-		Duration:   duration,
-		CreatedAt:  time.Now(),
-	}, nil
+		ID:              generateID(),
+		RequestID:       req.ID,
+		StatusCode:      resp.StatusCode(),
+		Headers:         headerList(header),
+		Body:            body,
+		Size:            int64(len(raw)),
+		WireSize:        wireSize,
+		HeaderSize:      headerSize(resp.RawResponse, header),
+		ContentEncoding: encoding,
+		WireBody:        wire,
+		Duration:        timing.Total,
+		Timing:          timing,
+		Cookies:         responseCookies(resp.Cookies()),
+		Connection:      c.proxyInfo(connectionInfo(remoteAddr, reused, resp.RawResponse, c.config.ExpiryWarningDays, time.Now()), resp.RawResponse.Request),
+		CreatedAt:       time.Now(),
+		RawBody:         raw,
+		ContentType:     contentType,
+		Binary:          binary,
+		Redirects:       chain.redirects(),
+	}
+}
+
+// Proxy returns the global proxy settings
+func (c *Client) Proxy() *models.ProxyConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Proxy
+}
+
+// SetProxy replaces the global proxy settings
+func (c *Client) SetProxy(proxy *models.ProxyConfig) error {
+	if err := ValidateProxyConfig(proxy); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Proxy = proxy
+	return nil
+}
+
+// Certificates returns the certificate manager, or nil if none is configured
+func (c *Client) Certificates() *CertificateManager {
+	return c.config.Certificates
+}
+
+// CookieJar returns the persistent cookie jar, or nil if none is configured
+func (c *Client) CookieJar() *CookieJar {
+	return c.config.CookieJar
 }
 
 // applyAuth applies authentication to the request
@@ -154,20 +371,17 @@ func (c *Client) applyAuth(r *resty.Request, auth *models.AuthConfig) error {
 		r.SetHeader(header, value)
 
 	case "oauth2":
-		// OAuth2 implementation
-		accessToken, ok := auth.Config["access_token"]
-		if !ok {
-			return fmt.Errorf("access_token required for OAuth2 auth")
-		}
-		r.SetAuthToken(accessToken)
-		
-		// Add additional OAuth2 headers if needed
-		if tokenType, ok := auth.Config["token_type"]; ok && tokenType != "" {
-			r.SetHeader("Authorization", fmt.Sprintf("%s %s", tokenType, accessToken))
+		// OAuth2 token, either supplied directly or obtained through a grant and cached
+		ctx, cancel := tokenContext(r.Context())
+		token, err := c.oauth2.token(ctx, auth.Config)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to obtain OAuth2 token: %w", err)
 		}
+		r.SetHeader("Authorization", token.authorizationHeader())
 
 	case "digest":
-		// Digest authentication
+		// Digest authentication, answered by digestTransport on the server's challenge
 		username, ok := auth.Config["username"]
 		if !ok {
 			return fmt.Errorf("username required for digest auth")
@@ -176,21 +390,43 @@ func (c *Client) applyAuth(r *resty.Request, auth *models.AuthConfig) error {
 		if !ok {
 			return fmt.Errorf("password required for digest auth")
 		}
-		// Note: resty doesn't have built-in digest auth, would need custom implementation
-		r.SetBasicAuth(username, password) // Fallback to basic auth
+		r.SetContext(withDigestCredentials(r.Context(), username, password))
 
 	case "hawk":
-		// Hawk authentication
-		id, ok := auth.Config["id"]
-		if !ok {
-			return fmt.Errorf("id required for hawk auth")
+		// Hawk authentication, signed by signingTransport once the final URL and body are known
+		signer, err := newHawkSigner(auth.Config)
+		if err != nil {
+			return err
 		}
-		key, ok := auth.Config["key"]
-		if !ok {
-			return fmt.Errorf("key required for hawk auth")
+		r.SetContext(withRequestSigner(r.Context(), signer))
+
+	case "aws_sigv4":
+		// AWS Signature Version 4, as headers or a presigned URL
+		signer, err := newSigV4Signer(auth.Config)
+		if err != nil {
+			return err
+		}
+		r.SetContext(withRequestSigner(r.Context(), signer))
+
+	case "jwt":
+		// JWT signed from the claims template at send time
+		token, err := buildJWT(auth.Config, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to sign JWT: %w", err)
+		}
+		if auth.Config["add_to"] == "query" {
+			param := auth.Config["query_param"]
+			if param == "" {
+				param = "token"
+			}
+			r.SetQueryParam(param, token)
+		} else {
+			prefix, ok := auth.Config["header_prefix"]
+			if !ok {
+				prefix = "Bearer"
+			}
+			r.SetHeader("Authorization", strings.TrimSpace(prefix+" "+token))
 		}
-		// Note: resty doesn't have built-in hawk auth, would need custom implementation
-		r.SetHeader("Authorization", fmt.Sprintf("Hawk id=\"%s\", key=\"%s\"", id, key))
 
 	default:
 		return fmt.Errorf("unsupported authentication type: %s", auth.Type)
@@ -199,6 +435,47 @@ func (c *Client) applyAuth(r *resty.Request, auth *models.AuthConfig) error {
 	return nil
 }
 
+// headerList converts headers to an ordered list, keeping every value.
+// net/http does not keep the order headers arrived in, so names are sorted
+// and repeated values stay in the order they were received.
+func headerList(header http.Header) models.KeyValues {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headers models.KeyValues
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers.Add(name, value)
+		}
+	}
+	return headers
+}
+
+// appendQuery adds query parameters to a URL in order, after any query it
+// already has and before its fragment
+func appendQuery(rawURL string, params models.KeyValues) string {
+	if len(params) == 0 {
+		return rawURL
+	}
+
+	base, fragment, hasFragment := strings.Cut(rawURL, "#")
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+		if strings.HasSuffix(base, "?") || strings.HasSuffix(base, "&") {
+			separator = ""
+		}
+	}
+	result := base + separator + urlencodedBody(params)
+	if hasFragment {
+		result += "#" + fragment
+	}
+	return result
+}
+
 // generateID generates a unique ID
 func generateID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
@@ -0,0 +1,338 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultAcceptEncoding is offered for responses when neither the request's
+// headers nor its settings choose the encodings
+const defaultAcceptEncoding = "gzip, deflate, br, zstd"
+
+// maxWireCopy bounds how much of an encoded body is kept as received for
+// viewing; larger bodies are still counted in full
+const maxWireCopy = 16 << 20
+
+// compressionWriters create the encoders for request bodies
+var compressionWriters = map[string]func(io.Writer) (io.WriteCloser, error){
+	"gzip": func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+	"deflate": func(w io.Writer) (io.WriteCloser, error) {
+		// HTTP's deflate is the zlib format
+		return zlib.NewWriter(w), nil
+	},
+	"br": func(w io.Writer) (io.WriteCloser, error) {
+		return brotli.NewWriter(w), nil
+	},
+	"zstd": func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	},
+}
+
+// compressionReaders create the decoders for response bodies
+var compressionReaders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"x-gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": newDeflateReader,
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	},
+}
+
+// validateCompression checks a request body compression setting
+func validateCompression(encoding string) error {
+	if _, ok := compressionWriters[encoding]; encoding != "" && !ok {
+		return fmt.Errorf("unsupported compression %q, use gzip, deflate, br or zstd", encoding)
+	}
+	return nil
+}
+
+// compressRequestBody encodes the body of an outgoing request and declares
+// it with Content-Encoding. Requests that already declare an encoding are
+// sent as they are. Body files are encoded as they are sent, without a
+// length; other bodies are encoded up front so their length is known.
+func compressRequestBody(req *http.Request, encoding string) error {
+	if encoding == "" || req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	newWriter, ok := compressionWriters[encoding]
+	if !ok {
+		return validateCompression(encoding)
+	}
+
+	if _, ok := req.Context().Value(bodyFileContextKey{}).(*bodyFile); ok && req.GetBody != nil {
+		getBody := req.GetBody
+		req.Body.Close()
+		req.Header.Set("Content-Encoding", encoding)
+		req.ContentLength = -1
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return compressingReader(body, newWriter, encoding), nil
+		}
+		req.Body, _ = req.GetBody()
+		return nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err == nil {
+		_, err = w.Write(body)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compress request body with %s: %w", encoding, err)
+	}
+
+	compressed := buf.Bytes()
+	req.Header.Set("Content-Encoding", encoding)
+	req.ContentLength = int64(len(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// compressingReader reads body encoded with the writer newWriter creates.
+// The body is encoded through a pipe as it is read, and closed once it has
+// been read or the reader is closed.
+func compressingReader(body io.ReadCloser, newWriter func(io.Writer) (io.WriteCloser, error), encoding string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		w, err := newWriter(pw)
+		if err == nil {
+			_, err = io.Copy(w, body)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to compress request body with %s: %w", encoding, err)
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// decodingTransport decodes response bodies by their Content-Encoding,
+// keeping count of the bytes as received so both sizes can be reported.
+// The Transport's own transparent gzip handling is disabled so that it never
+// hides the encoded size.
+type decodingTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip sends the request and wraps an encoded response body in a wireBody
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+
+	encodings := contentEncodings(resp.Header)
+	if len(encodings) == 0 {
+		return resp, nil
+	}
+	for _, encoding := range encodings {
+		if _, ok := compressionReaders[encoding]; !ok {
+			// Pass bodies in encodings we can't decode through untouched
+			return resp, nil
+		}
+	}
+
+	// Like the Transport's transparent gzip, the decoded body has no known
+	// length and no encoding; the headers as received are kept for reporting
+	resp.Body = &wireBody{body: resp.Body, header: resp.Header.Clone(), encodings: encodings}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// contentEncodings lists the encodings of a body in the order they were
+// applied, leaving out identity
+func contentEncodings(header http.Header) []string {
+	var encodings []string
+	for _, value := range header.Values("Content-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding != "" && encoding != "identity" {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+	return encodings
+}
+
+// wireBody decodes an encoded response body while counting, and keeping a
+// copy of, the bytes as they were received
+type wireBody struct {
+	body      io.ReadCloser
+	header    http.Header // the response headers as received
+	encodings []string
+
+	size    int64 // bytes received so far
+	wire    bytes.Buffer
+	decoded io.Reader
+	closers []io.Closer
+	err     error
+}
+
+// Read returns decoded bytes. Decoders are set up on the first read so an
+// empty body, as for HEAD, reads as empty rather than failing.
+func (b *wireBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.decoded == nil {
+		received := bufio.NewReader(io.TeeReader(b.body, wireCounter{b}))
+		if _, err := received.Peek(1); err != nil {
+			b.err = err
+			return 0, err
+		}
+		var r io.Reader = received
+		for i := len(b.encodings) - 1; i >= 0; i-- {
+			decoder, err := compressionReaders[b.encodings[i]](r)
+			if err != nil {
+				b.err = fmt.Errorf("failed to decode %s response body: %w", b.encodings[i], err)
+				return 0, b.err
+			}
+			b.closers = append(b.closers, decoder)
+			r = decoder
+		}
+		b.decoded = r
+	}
+
+	n, err := b.decoded.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("failed to decode %s response body: %w", strings.Join(b.encodings, ", "), err)
+		b.err = err
+	}
+	return n, err
+}
+
+// Close releases the decoders and closes the body as received
+func (b *wireBody) Close() error {
+	for _, closer := range b.closers {
+		closer.Close()
+	}
+	return b.body.Close()
+}
+
+// encoding returns the Content-Encoding the body was received with
+func (b *wireBody) encoding() string {
+	return strings.Join(b.encodings, ", ")
+}
+
+// received returns the bytes as received, or nil if the body was too large
+// to keep
+func (b *wireBody) received() []byte {
+	if b.size > maxWireCopy {
+		return nil
+	}
+	return b.wire.Bytes()
+}
+
+// wireCounter counts the bytes of a wireBody as they are received
+type wireCounter struct {
+	body *wireBody
+}
+
+// Write records received bytes, keeping a copy up to maxWireCopy
+func (c wireCounter) Write(p []byte) (int, error) {
+	c.body.size += int64(len(p))
+	if c.body.size <= maxWireCopy {
+		c.body.wire.Write(p)
+	}
+	return len(p), nil
+}
+
+// newDeflateReader reads a deflate body, which servers send either in the
+// zlib format the standard calls for or as a bare deflate stream
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// receivedHeader returns the headers of a response as they were received,
+// before decoding removed its Content-Encoding
+func receivedHeader(resp *http.Response) http.Header {
+	if body, ok := resp.Body.(*wireBody); ok {
+		return body.header
+	}
+	return resp.Header
+}
+
+// bufferResponseBody reads a response body in full and leaves one in its place
+// that reads the same bytes. A wireBody stays in place, replaying its decoded
+// bytes, so the body as received is still reported.
+func bufferResponseBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if received, ok := resp.Body.(*wireBody); ok {
+		received.decoded = bytes.NewReader(body)
+		received.err = nil
+		return body, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// headerSize is the size of a response's status line and headers in
+// HTTP/1.1 form. HTTP/2 and HTTP/3 compress headers, so for them it is an
+// upper bound.
+func headerSize(resp *http.Response, header http.Header) int64 {
+	size := len(resp.Proto) + len(" ") + len(resp.Status) + len("\r\n")
+	for name, values := range header {
+		for _, value := range values {
+			size += len(name) + len(": ") + len(value) + len("\r\n")
+		}
+	}
+	if len(resp.TransferEncoding) > 0 {
+		// The Transport moves Transfer-Encoding out of the headers
+		size += len("Transfer-Encoding: ") + len(strings.Join(resp.TransferEncoding, ", ")) + len("\r\n")
+	}
+	return int64(size + len("\r\n"))
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"postgirl/internal/models"
+)
+
+func TestCompressedBodyFileIsStreamed(t *testing.T) {
+	content := bytes.Repeat([]byte("postgirl "), 1<<17)
+	path := filepath.Join(t.TempDir(), "body.bin")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received, err := io.ReadAll(reader)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "%s %v %d %t", r.Header.Get("Content-Encoding"), r.TransferEncoding, r.ContentLength, bytes.Equal(received, content))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.RetryCount = 0
+	resp, err := NewClient(config).Execute(context.Background(), &models.Request{
+		ID:       "r",
+		Method:   "POST",
+		URL:      server.URL,
+		Body:     &models.RequestBody{Type: "binary", FilePath: path},
+		Settings: &models.RequestSettings{Compression: "gzip"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	// Encoded as it is sent, the body has no length up front
+	if want := "gzip [chunked] -1 true"; resp.Body != want {
+		t.Errorf("server saw %q, want %q", resp.Body, want)
+	}
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"postgirl/internal/models"
+)
+
+// connectionInfo describes the connection a response arrived on. Peer
+// certificates expiring within warnDays produce a warning.
+func connectionInfo(remote net.Addr, reused bool, resp *http.Response, warnDays int, now time.Time) *models.ConnectionInfo {
+	info := &models.ConnectionInfo{
+		Protocol: resp.Proto,
+		Reused:   reused,
+	}
+
+	if remote != nil {
+		host, port, err := net.SplitHostPort(remote.String())
+		if err == nil {
+			info.RemoteIP = host
+			info.RemotePort, _ = strconv.Atoi(port)
+		} else {
+			info.RemoteIP = remote.String()
+		}
+	}
+
+	if resp.TLS != nil {
+		info.TLS = tlsInfo(resp.TLS, warnDays, now)
+	}
+
+	return info
+}
+
+// tlsInfo summarizes a negotiated TLS session and its peer chain
+func tlsInfo(state *tls.ConnectionState, warnDays int, now time.Time) *models.TLSInfo {
+	info := &models.TLSInfo{
+		Version:          tls.VersionName(state.Version),
+		CipherSuite:      tls.CipherSuiteName(state.CipherSuite),
+		ALPN:             state.NegotiatedProtocol,
+		ServerName:       state.ServerName,
+		Resumed:          state.DidResume,
+		PeerCertificates: make([]models.CertificateInfo, 0, len(state.PeerCertificates)),
+		Warnings:         []string{},
+	}
+
+	if state.Version == tls.VersionTLS10 || state.Version == tls.VersionTLS11 {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("%s is deprecated", info.Version))
+	}
+
+	for _, cert := range state.PeerCertificates {
+		summary := certificateInfo(cert, now)
+		info.PeerCertificates = append(info.PeerCertificates, summary)
+
+		name := cert.Subject.CommonName
+		if name == "" {
+			name = summary.Subject
+		}
+		switch {
+		case now.After(cert.NotAfter):
+			info.Warnings = append(info.Warnings, fmt.Sprintf("certificate %q expired on %s", name, cert.NotAfter.Format("2006-01-02")))
+		case now.Before(cert.NotBefore):
+			info.Warnings = append(info.Warnings, fmt.Sprintf("certificate %q is not valid until %s", name, cert.NotBefore.Format("2006-01-02")))
+		case summary.DaysUntilExpiry <= warnDays:
+			info.Warnings = append(info.Warnings, fmt.Sprintf("certificate %q expires in %d days on %s", name, summary.DaysUntilExpiry, cert.NotAfter.Format("2006-01-02")))
+		}
+	}
+
+	return info
+}
+
+// certificateInfo summarizes a single certificate
+func certificateInfo(cert *x509.Certificate, now time.Time) models.CertificateInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	hexBytes := make([]string, len(fingerprint))
+	for i, b := range fingerprint {
+		hexBytes[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return models.CertificateInfo{
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		SANs:              sans,
+		SerialNumber:      cert.SerialNumber.Text(16),
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+		DaysUntilExpiry:   int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+		FingerprintSHA256: strings.Join(hexBytes, ":"),
+	}
+}
@@ -0,0 +1,354 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+	"postgirl/internal/models"
+)
+
+// CookieStore persists the cookies held by a CookieJar
+type CookieStore interface {
+	SaveCookie(cookie *models.Cookie) error
+	GetAllCookies() ([]*models.Cookie, error)
+	DeleteCookie(domain, path, name string) error
+}
+
+// CookieJar is an http.CookieJar that follows the RFC 6265 domain, path,
+// secure and expiry rules and writes every change through to a CookieStore
+type CookieJar struct {
+	mu       sync.Mutex
+	store    CookieStore
+	cookies  map[string]*models.Cookie
+	storeErr error
+}
+
+// NewCookieJar creates a cookie jar loaded from the store. If loading fails
+// the jar is still usable and starts empty.
+func NewCookieJar(store CookieStore) (*CookieJar, error) {
+	jar := &CookieJar{
+		store:   store,
+		cookies: make(map[string]*models.Cookie),
+	}
+
+	if store == nil {
+		return jar, nil
+	}
+
+	cookies, err := store.GetAllCookies()
+	if err != nil {
+		return jar, fmt.Errorf("failed to load cookies: %w", err)
+	}
+	for _, cookie := range cookies {
+		jar.cookies[cookieKey(cookie.Domain, cookie.Path, cookie.Name)] = cookie
+	}
+
+	return jar, nil
+}
+
+// SetCookies stores the cookies a response from u asked to set
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := canonicalHost(u.Hostname())
+	if host == "" {
+		return
+	}
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		cookie := &models.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+
+		if c.Domain == "" {
+			cookie.Domain = host
+			cookie.HostOnly = true
+		} else {
+			domain := canonicalHost(strings.TrimPrefix(c.Domain, "."))
+			// A response may only set cookies for its own host or a parent domain
+			if !domainMatch(host, domain) || (net.ParseIP(host) != nil && domain != host) {
+				continue
+			}
+			cookie.Domain = domain
+			// and never for a public suffix such as co.uk, unless that is the
+			// host itself, when the cookie is host-only (RFC 6265 5.3 step 5)
+			if isPublicSuffix(domain) {
+				if domain != host {
+					continue
+				}
+				cookie.HostOnly = true
+			}
+		}
+
+		cookie.Path = c.Path
+		if cookie.Path == "" || !strings.HasPrefix(cookie.Path, "/") {
+			cookie.Path = defaultCookiePath(u.Path)
+		}
+
+		key := cookieKey(cookie.Domain, cookie.Path, cookie.Name)
+
+		switch {
+		case c.MaxAge < 0:
+			j.keepStoreError(j.remove(key))
+			continue
+		case c.MaxAge > 0:
+			cookie.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			cookie.Expires = c.Expires
+		}
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			j.keepStoreError(j.remove(key))
+			continue
+		}
+
+		j.cookies[key] = cookie
+		if j.store != nil {
+			j.keepStoreError(j.store.SaveCookie(cookie))
+		}
+	}
+}
+
+// StoreError returns the last error writing cookies that responses set to
+// the store, if there has been one since the previous call. The cookies are
+// still held in memory.
+func (j *CookieJar) StoreError() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err := j.storeErr
+	j.storeErr = nil
+	return err
+}
+
+// keepStoreError remembers a store error for StoreError; the caller must
+// hold the lock
+func (j *CookieJar) keepStoreError(err error) {
+	if err != nil {
+		j.storeErr = err
+	}
+}
+
+// Cookies returns the cookies to send in a request to u
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	host := canonicalHost(u.Hostname())
+	if host == "" {
+		return nil
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var matched []*models.Cookie
+	for key, cookie := range j.cookies {
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			j.remove(key)
+			continue
+		}
+		if cookie.HostOnly {
+			if host != cookie.Domain {
+				continue
+			}
+		} else if !domainMatch(host, cookie.Domain) {
+			continue
+		}
+		if !pathMatch(path, cookie.Path) || (cookie.Secure && !secure) {
+			continue
+		}
+		matched = append(matched, cookie)
+	}
+
+	// More specific paths first, as RFC 6265 recommends
+	sort.Slice(matched, func(a, b int) bool {
+		if len(matched[a].Path) != len(matched[b].Path) {
+			return len(matched[a].Path) > len(matched[b].Path)
+		}
+		return matched[a].Name < matched[b].Name
+	})
+
+	cookies := make([]*http.Cookie, len(matched))
+	for i, cookie := range matched {
+		cookies[i] = &http.Cookie{Name: cookie.Name, Value: cookie.Value}
+	}
+	return cookies
+}
+
+// List returns all unexpired cookies sorted by domain, path and name
+func (j *CookieJar) List() []models.Cookie {
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	cookies := make([]models.Cookie, 0, len(j.cookies))
+	for key, cookie := range j.cookies {
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			j.remove(key)
+			continue
+		}
+		cookies = append(cookies, *cookie)
+	}
+
+	sort.Slice(cookies, func(a, b int) bool {
+		if cookies[a].Domain != cookies[b].Domain {
+			return cookies[a].Domain < cookies[b].Domain
+		}
+		if cookies[a].Path != cookies[b].Path {
+			return cookies[a].Path < cookies[b].Path
+		}
+		return cookies[a].Name < cookies[b].Name
+	})
+	return cookies
+}
+
+// Save adds or replaces a cookie
+func (j *CookieJar) Save(cookie models.Cookie) error {
+	if cookie.Name == "" {
+		return fmt.Errorf("cookie name is required")
+	}
+	cookie.Domain = canonicalHost(strings.TrimPrefix(cookie.Domain, "."))
+	if cookie.Domain == "" {
+		return fmt.Errorf("cookie domain is required")
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.cookies[cookieKey(cookie.Domain, cookie.Path, cookie.Name)] = &cookie
+	if j.store != nil {
+		return j.store.SaveCookie(&cookie)
+	}
+	return nil
+}
+
+// Delete removes a single cookie
+func (j *CookieJar) Delete(domain, path, name string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.remove(cookieKey(canonicalHost(domain), path, name))
+}
+
+// Clear removes all cookies, or only those for a domain when one is given
+func (j *CookieJar) Clear(domain string) error {
+	domain = canonicalHost(domain)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for key, cookie := range j.cookies {
+		if domain != "" && cookie.Domain != domain {
+			continue
+		}
+		if err := j.remove(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove deletes a cookie by key; the caller must hold the lock
+func (j *CookieJar) remove(key string) error {
+	cookie, ok := j.cookies[key]
+	if !ok {
+		return nil
+	}
+	delete(j.cookies, key)
+
+	if j.store != nil {
+		return j.store.DeleteCookie(cookie.Domain, cookie.Path, cookie.Name)
+	}
+	return nil
+}
+
+// cookieKey identifies a cookie by domain, path and name
+func cookieKey(domain, path, name string) string {
+	return domain + ";" + path + ";" + name
+}
+
+// canonicalHost lowercases a host name and strips a trailing dot
+func canonicalHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// domainMatch reports whether host domain-matches domain (RFC 6265 5.1.3)
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	return strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil
+}
+
+// isPublicSuffix reports whether cookies may not be set for domain because
+// anyone can register names under it
+func isPublicSuffix(domain string) bool {
+	if net.ParseIP(domain) != nil {
+		return false
+	}
+	_, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	return err != nil
+}
+
+// pathMatch reports whether requestPath path-matches cookiePath (RFC 6265 5.1.4)
+func pathMatch(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath computes the default cookie path from a request path (RFC 6265 5.1.4)
+func defaultCookiePath(requestPath string) string {
+	if requestPath == "" || requestPath[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(requestPath, "/")
+	if i == 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// responseCookies converts the Set-Cookie headers of a response for display
+func responseCookies(cookies []*http.Cookie) []models.Cookie {
+	result := make([]models.Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		cookie := models.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+		if c.MaxAge > 0 {
+			cookie.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		result = append(result, cookie)
+	}
+	return result
+}
@@ -0,0 +1,304 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"postgirl/internal/models"
+)
+
+// maxDialTransports bounds how many dial targets keep a transport of their
+// own; past it, every cached transport is dropped and rebuilt on demand
+const maxDialTransports = 16
+
+// dialTargetContextKey carries where a request's connections go on its context
+type dialTargetContextKey struct{}
+
+// dialTarget directs the connections of a request: to a Unix socket, or over
+// TCP with host name overrides and a DNS server of its own. The zero value
+// dials as usual. Through a proxy, the overrides and DNS server only decide
+// how the proxy is reached; the proxy resolves the request's host itself, as
+// proxyInfo reports.
+type dialTarget struct {
+	socket    string            // path of a Unix socket
+	hosts     map[string]string // host name to IP address, like /etc/hosts
+	dnsServer string            // host:port of the DNS server for other names
+}
+
+// dialTargetFor returns the host overrides and DNS server of an environment,
+// which may be nil
+func dialTargetFor(env *models.Environment) dialTarget {
+	if env == nil {
+		return dialTarget{}
+	}
+	return dialTarget{hosts: env.Hosts, dnsServer: dnsServerAddress(env.DNSServer)}
+}
+
+// withDialTarget directs the connections of a request
+func withDialTarget(ctx context.Context, target dialTarget) context.Context {
+	if target.isDefault() {
+		return ctx
+	}
+	return context.WithValue(ctx, dialTargetContextKey{}, target)
+}
+
+// isDefault reports whether connections are dialed as usual
+func (t dialTarget) isDefault() bool {
+	return t.socket == "" && len(t.hosts) == 0 && t.dnsServer == ""
+}
+
+// key identifies the target, so requests dialing the same way share connections
+func (t dialTarget) key() string {
+	if t.socket != "" {
+		return "unix:" + t.socket
+	}
+	names := make([]string, 0, len(t.hosts))
+	for name := range t.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("dns:" + t.dnsServer)
+	for _, name := range names {
+		b.WriteString(" " + name + "=" + t.hosts[name])
+	}
+	return b.String()
+}
+
+// lookupHost returns the address a host name is overridden with
+func (t dialTarget) lookupHost(host string) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for name, address := range t.hosts {
+		if strings.TrimSuffix(strings.ToLower(name), ".") == host {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// dialContext returns a dial function connecting the way the target says
+func (t dialTarget) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if t.socket != "" {
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", t.socket)
+		}
+	}
+
+	resolver := net.DefaultResolver
+	if t.dnsServer != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, t.dnsServer)
+			},
+		}
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if ip, ok := t.lookupHost(host); ok {
+			return dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		}
+		if t.dnsServer == "" || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		ips, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s with %s: %w", host, t.dnsServer, err)
+		}
+		// Try each address in turn, as the standard dialer does
+		var errs []error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// newDialer returns a dialer with the standard transport's settings
+func newDialer() *net.Dialer {
+	return &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+}
+
+// dnsServerAddress adds the DNS port to a server given without one
+func dnsServerAddress(server string) string {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return server
+}
+
+// ValidateResolution checks an environment's host overrides and DNS server
+func ValidateResolution(hosts map[string]string, dnsServer string) error {
+	for name, address := range hosts {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("host override for %q needs a host name", address)
+		}
+		if net.ParseIP(address) == nil {
+			return fmt.Errorf("host override for %s must be an IP address, got %q", name, address)
+		}
+	}
+	if server := dnsServerAddress(dnsServer); server != "" {
+		host, port, err := net.SplitHostPort(server)
+		if err != nil || net.ParseIP(host) == nil || port == "" {
+			return fmt.Errorf("DNS server must be an IP address with an optional port, got %q", dnsServer)
+		}
+	}
+	return nil
+}
+
+// unixSocketURL splits a unix:// URL into the socket it names and the HTTP
+// URL to request over it. The socket path ends at ":" when the URL has one,
+// as in unix:///var/run/docker.sock:/v1.43/info; otherwise it is the longest
+// leading part of the path that is a socket, as in
+// unix:///var/run/docker.sock/v1.43/info.
+func unixSocketURL(rawURL string) (string, string, error) {
+	rest, ok := cutScheme(rawURL, "unix")
+	if !ok {
+		return "", "", fmt.Errorf("not a unix:// URL: %s", rawURL)
+	}
+	path, query, hasQuery := strings.Cut(rest, "?")
+	if !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("unix socket URL needs an absolute socket path, as in unix:///var/run/docker.sock")
+	}
+
+	socket, requestPath, found := strings.Cut(path, ":")
+	if !found {
+		socket, requestPath = findSocket(path)
+	}
+	if socket == "" {
+		return "", "", fmt.Errorf("no unix socket found in %s", path)
+	}
+	if unescaped, err := url.PathUnescape(socket); err == nil {
+		socket = unescaped
+	}
+	if !strings.HasPrefix(requestPath, "/") {
+		requestPath = "/" + requestPath
+	}
+
+	// The host only names the server in the Host header; the dialer ignores it
+	target := "http://localhost" + requestPath
+	if hasQuery {
+		target += "?" + query
+	}
+	return socket, target, nil
+}
+
+// cutScheme removes scheme:// from the start of a URL, ignoring case
+func cutScheme(rawURL, scheme string) (string, bool) {
+	prefix := scheme + "://"
+	if len(rawURL) < len(prefix) || !strings.EqualFold(rawURL[:len(prefix)], prefix) {
+		return "", false
+	}
+	return rawURL[len(prefix):], true
+}
+
+// findSocket returns the longest leading part of a path that is a socket,
+// with the rest of the path; the whole path is taken as the socket when no
+// part of it is one, so dialing reports the error
+func findSocket(path string) (string, string) {
+	for end := len(path); end > 0; end = strings.LastIndex(path[:end], "/") {
+		if info, err := os.Stat(path[:end]); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return path[:end], path[end:]
+		}
+	}
+	return path, "/"
+}
+
+// isUnixSocketURL reports whether a URL targets a Unix socket
+func isUnixSocketURL(rawURL string) bool {
+	_, ok := cutScheme(rawURL, "unix")
+	return ok
+}
+
+// dialTransport sends requests that carry a dial target through transports
+// of their own, so connections dialed one way are never reused for another.
+// Other requests go through the default transport.
+type dialTransport struct {
+	base  *http.Transport
+	certs *CertificateManager
+
+	fallback   *tlsTransport
+	mu         sync.Mutex
+	transports map[string]*tlsTransport
+}
+
+// newDialTransport wraps base so requests pick up their dial target and the
+// manager's TLS settings
+func newDialTransport(base *http.Transport, certs *CertificateManager) *dialTransport {
+	return &dialTransport{
+		base:       base,
+		certs:      certs,
+		fallback:   newTLSTransport(base, certs),
+		transports: make(map[string]*tlsTransport),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *dialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, ok := req.Context().Value(dialTargetContextKey{}).(dialTarget)
+	if !ok {
+		return t.fallback.RoundTrip(req)
+	}
+	return t.transport(target).RoundTrip(req)
+}
+
+// transport returns the cached transport for a dial target
+func (t *dialTransport) transport(target dialTarget) *tlsTransport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := target.key()
+	if transport, ok := t.transports[key]; ok {
+		return transport
+	}
+	if len(t.transports) >= maxDialTransports {
+		for _, transport := range t.transports {
+			transport.CloseIdleConnections()
+		}
+		t.transports = make(map[string]*tlsTransport)
+	}
+
+	base := t.base.Clone()
+	base.DialContext = target.dialContext(newDialer())
+	if target.socket != "" {
+		// A socket is reached directly, never through a proxy
+		base.Proxy = nil
+	}
+	transport := newTLSTransport(base, t.certs)
+	t.transports[key] = transport
+	return transport
+}
+
+// CloseIdleConnections closes idle connections on every transport
+func (t *dialTransport) CloseIdleConnections() {
+	t.fallback.CloseIdleConnections()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, transport := range t.transports {
+		transport.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,404 @@
+package http
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestHashes maps RFC 7616 algorithm names to their hash functions
+var digestHashes = map[string]func() hash.Hash{
+	"MD5":         md5.New,
+	"SHA-256":     sha256.New,
+	"SHA-512-256": sha512.New512_256,
+}
+
+// digestContextKey carries Digest credentials on a request context
+type digestContextKey struct{}
+
+// digestCredentials holds the username and password for Digest auth
+type digestCredentials struct {
+	username string
+	password string
+}
+
+// withDigestCredentials marks a request context for Digest authentication
+func withDigestCredentials(ctx context.Context, username, password string) context.Context {
+	return context.WithValue(ctx, digestContextKey{}, digestCredentials{username: username, password: password})
+}
+
+// digestChallenge represents a parsed WWW-Authenticate: Digest challenge
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       []string
+	userhash  bool
+}
+
+// digestSession tracks the nonce count for a server nonce
+type digestSession struct {
+	challenge *digestChallenge
+	cnonce    string
+	nc        uint32
+}
+
+// digestTransport answers Digest challenges (RFC 7616) for requests whose
+// context carries credentials, reusing nonces across requests to the same host
+type digestTransport struct {
+	transport http.RoundTripper
+	mu        sync.Mutex
+	sessions  map[string]*digestSession
+}
+
+// newDigestTransport wraps a transport with Digest authentication support
+func newDigestTransport(transport http.RoundTripper) *digestTransport {
+	return &digestTransport{
+		transport: transport,
+		sessions:  make(map[string]*digestSession),
+	}
+}
+
+// RoundTrip sends the request, answering a Digest challenge if the server issues one
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	creds, ok := req.Context().Value(digestContextKey{}).(digestCredentials)
+	if !ok || !sameHostAsOriginal(req) {
+		// Credentials are only sent to the host they were given for, not to
+		// another host a redirect leads to
+		return t.transport.RoundTrip(req)
+	}
+
+	key := req.URL.Host + "|" + creds.username
+
+	// Authorize up front when a nonce from an earlier exchange is still known
+	first := req
+	if session := t.session(key); session != nil {
+		authorized, err := t.authorize(req, creds, session)
+		if err != nil {
+			return nil, err
+		}
+		first = authorized
+	}
+
+	resp, err := t.transport.RoundTrip(first)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge, err := findDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if err != nil || challenge == nil {
+		// Not a Digest challenge (or one we can't answer); hand back the 401
+		return resp, nil
+	}
+
+	// The body has to be replayed, which is only possible with GetBody
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	cnonce, err := newCNonce()
+	if err != nil {
+		return resp, nil
+	}
+	session := &digestSession{challenge: challenge, cnonce: cnonce}
+	t.mu.Lock()
+	t.sessions[key] = session
+	t.mu.Unlock()
+
+	retry, err := t.authorize(req, creds, session)
+	if err != nil {
+		return resp, nil
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return t.transport.RoundTrip(retry)
+}
+
+// session returns the known session for a host and user, if any
+func (t *digestTransport) session(key string) *digestSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessions[key]
+}
+
+// authorize clones the request with a fresh body and a Digest Authorization header
+func (t *digestTransport) authorize(req *http.Request, creds digestCredentials, session *digestSession) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+
+	t.mu.Lock()
+	session.nc++
+	nc := session.nc
+	t.mu.Unlock()
+
+	header, err := session.authorization(creds, req, nc)
+	if err != nil {
+		return nil, err
+	}
+	clone.Header.Set("Authorization", header)
+
+	return clone, nil
+}
+
+// authorization computes the Authorization header value for a request
+func (s *digestSession) authorization(creds digestCredentials, req *http.Request, nc uint32) (string, error) {
+	c := s.challenge
+
+	algorithm, sess := splitDigestAlgorithm(c.algorithm)
+	newHash, ok := digestHashes[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported digest algorithm: %s", c.algorithm)
+	}
+	h := func(data string) string {
+		hasher := newHash()
+		io.WriteString(hasher, data)
+		return hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	uri := req.URL.RequestURI()
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(creds.username + ":" + c.realm + ":" + creds.password)
+	if sess {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + s.cnonce)
+	}
+
+	qop := selectQop(c.qop)
+	ha2 := h(req.Method + ":" + uri)
+	if qop == "auth-int" {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return "", err
+		}
+		ha2 = h(req.Method + ":" + uri + ":" + h(string(body)))
+	}
+
+	var response string
+	if qop == "" {
+		// RFC 2069 compatibility when the server offers no qop
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ncValue + ":" + s.cnonce + ":" + qop + ":" + ha2)
+	}
+
+	username := creds.username
+	if c.userhash {
+		username = h(creds.username + ":" + c.realm)
+	}
+
+	params := []string{
+		fmt.Sprintf("username=%s", quoteAuthParam(username)),
+		fmt.Sprintf("realm=%s", quoteAuthParam(c.realm)),
+		fmt.Sprintf("nonce=%s", quoteAuthParam(c.nonce)),
+		fmt.Sprintf("uri=%s", quoteAuthParam(uri)),
+		fmt.Sprintf("algorithm=%s", c.algorithm),
+		fmt.Sprintf("response=%s", quoteAuthParam(response)),
+	}
+	if qop != "" {
+		params = append(params,
+			fmt.Sprintf("qop=%s", qop),
+			fmt.Sprintf("nc=%s", ncValue),
+			fmt.Sprintf("cnonce=%s", quoteAuthParam(s.cnonce)))
+	}
+	if c.opaque != "" {
+		params = append(params, fmt.Sprintf("opaque=%s", quoteAuthParam(c.opaque)))
+	}
+	if c.userhash {
+		params = append(params, "userhash=true")
+	}
+
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+// findDigestChallenge returns the first Digest challenge with a supported
+// algorithm. Headers that fail to parse are skipped; their error is returned
+// only when no other header has a usable challenge.
+func findDigestChallenge(headers []string) (*digestChallenge, error) {
+	var parseErr error
+	for _, header := range headers {
+		challenges, err := parseAuthChallenges(header)
+		if err != nil {
+			if parseErr == nil {
+				parseErr = err
+			}
+			continue
+		}
+		for _, ch := range challenges {
+			if !strings.EqualFold(ch.scheme, "Digest") {
+				continue
+			}
+
+			c := &digestChallenge{
+				realm:     ch.params["realm"],
+				nonce:     ch.params["nonce"],
+				opaque:    ch.params["opaque"],
+				algorithm: ch.params["algorithm"],
+				userhash:  strings.EqualFold(ch.params["userhash"], "true"),
+			}
+			if c.algorithm == "" {
+				c.algorithm = "MD5"
+			}
+			if qop, ok := ch.params["qop"]; ok {
+				for _, option := range strings.Split(qop, ",") {
+					c.qop = append(c.qop, strings.TrimSpace(option))
+				}
+			}
+
+			if algorithm, _ := splitDigestAlgorithm(c.algorithm); digestHashes[algorithm] == nil || c.nonce == "" {
+				continue
+			}
+			return c, nil
+		}
+	}
+	return nil, parseErr
+}
+
+// splitDigestAlgorithm normalizes an algorithm name and reports whether it is a -sess variant
+func splitDigestAlgorithm(name string) (string, bool) {
+	name = strings.ToUpper(name)
+	if strings.HasSuffix(name, "-SESS") {
+		return strings.TrimSuffix(name, "-SESS"), true
+	}
+	return name, false
+}
+
+// selectQop prefers "auth" over "auth-int"; an empty result means no qop
+func selectQop(options []string) string {
+	for _, option := range options {
+		if option == "auth" {
+			return option
+		}
+	}
+	for _, option := range options {
+		if option == "auth-int" {
+			return option
+		}
+	}
+	return ""
+}
+
+// readRequestBody returns a copy of the request body without consuming it
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body cannot be replayed")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// newCNonce generates a random client nonce
+func newCNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// authChallenge represents one challenge from a WWW-Authenticate header
+type authChallenge struct {
+	scheme string
+	params map[string]string
+}
+
+// parseAuthChallenges parses a WWW-Authenticate header value, which may hold
+// several comma-separated challenges, each with its own auth-params
+func parseAuthChallenges(header string) ([]authChallenge, error) {
+	var challenges []authChallenge
+	s := header
+
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return challenges, nil
+		}
+
+		token, rest := readAuthToken(s)
+		if token == "" {
+			return nil, fmt.Errorf("malformed authentication header: %q", header)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		if !strings.HasPrefix(rest, "=") || len(challenges) == 0 {
+			// A bare token starts a new challenge
+			challenges = append(challenges, authChallenge{scheme: token, params: make(map[string]string)})
+			s = rest
+			continue
+		}
+
+		// token=value or token="quoted value" belongs to the current challenge
+		rest = strings.TrimLeft(rest[1:], " \t")
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var ok bool
+			value, rest, ok = readQuotedString(rest)
+			if !ok {
+				return nil, fmt.Errorf("unterminated quoted string in authentication header: %q", header)
+			}
+		} else {
+			value, rest = readAuthToken(rest)
+		}
+		challenges[len(challenges)-1].params[strings.ToLower(token)] = value
+		s = rest
+	}
+}
+
+// readAuthToken reads a token up to whitespace, '=' or ','
+func readAuthToken(s string) (string, string) {
+	i := strings.IndexAny(s, " \t=,")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// readQuotedString reads a quoted-string, unescaping backslash escapes
+func readQuotedString(s string) (string, string, bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", false
+}
+
+// quoteAuthParam renders a value as a quoted-string
+func quoteAuthParam(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"postgirl/internal/models"
+)
+
+// introspectionQuery asks a GraphQL endpoint for its schema. Type references
+// are unwrapped seven levels deep, enough for types such as [[String!]!]!.
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) {
+    name description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+
+fragment InputValue on __InputValue {
+  name description
+  type { ...TypeRef }
+  defaultValue
+}
+
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name
+    ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+// introspectionResponse is the result of the introspection query
+type introspectionResponse struct {
+	Data *struct {
+		Schema *struct {
+			QueryType        *introspectionTypeRef `json:"queryType"`
+			MutationType     *introspectionTypeRef `json:"mutationType"`
+			SubscriptionType *introspectionTypeRef `json:"subscriptionType"`
+			Types            []introspectionType   `json:"types"`
+			Directives       []struct {
+				Name        string                    `json:"name"`
+				Description string                    `json:"description"`
+				Locations   []string                  `json:"locations"`
+				Args        []introspectionInputValue `json:"args"`
+			} `json:"directives"`
+		} `json:"__schema"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// introspectionType is a type in the introspection result
+type introspectionType struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Fields      []struct {
+		Name              string                    `json:"name"`
+		Description       string                    `json:"description"`
+		Args              []introspectionInputValue `json:"args"`
+		Type              introspectionTypeRef      `json:"type"`
+		IsDeprecated      bool                      `json:"isDeprecated"`
+		DeprecationReason string                    `json:"deprecationReason"`
+	} `json:"fields"`
+	InputFields   []introspectionInputValue `json:"inputFields"`
+	Interfaces    []introspectionTypeRef    `json:"interfaces"`
+	EnumValues    []models.GraphQLEnumValue `json:"enumValues"`
+	PossibleTypes []introspectionTypeRef    `json:"possibleTypes"`
+}
+
+// introspectionInputValue is an argument or input field in the introspection result
+type introspectionInputValue struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"`
+}
+
+// introspectionTypeRef is a type reference in the introspection result
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+// Introspect fetches the schema of the GraphQL endpoint a request is sent to,
+// using the request's method, headers, query parameters and authentication
+func (c *Client) Introspect(ctx context.Context, req *models.Request, env *models.Environment) (*models.GraphQLSchema, error) {
+	introspection := req.Clone()
+	if introspection.Method != http.MethodGet {
+		introspection.Method = http.MethodPost
+	}
+	introspection.Body = &models.RequestBody{
+		Type:          "graphql",
+		Query:         introspectionQuery,
+		OperationName: "IntrospectionQuery",
+	}
+	if introspection.Settings != nil {
+		introspection.Settings.Stream = false
+	}
+
+	resp, err := c.Execute(ctx, introspection, env)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("introspection failed: %s", resp.Error)
+	}
+
+	var result introspectionResponse
+	if err := json.Unmarshal(resp.RawBody, &result); err != nil {
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("introspection failed with status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("invalid introspection response: %w", err)
+	}
+	if result.Data == nil || result.Data.Schema == nil {
+		if len(result.Errors) > 0 {
+			messages := make([]string, len(result.Errors))
+			for i, e := range result.Errors {
+				messages[i] = e.Message
+			}
+			return nil, fmt.Errorf("introspection failed: %s", strings.Join(messages, "; "))
+		}
+		return nil, fmt.Errorf("introspection failed with status %d", resp.StatusCode)
+	}
+
+	raw := result.Data.Schema
+	schema := &models.GraphQLSchema{
+		Endpoint:  GraphQLEndpoint(req.URL),
+		Types:     make([]models.GraphQLType, 0, len(raw.Types)),
+		FetchedAt: time.Now(),
+	}
+	if raw.QueryType != nil {
+		schema.QueryType = raw.QueryType.Name
+	}
+	if raw.MutationType != nil {
+		schema.MutationType = raw.MutationType.Name
+	}
+	if raw.SubscriptionType != nil {
+		schema.SubscriptionType = raw.SubscriptionType.Name
+	}
+	for _, t := range raw.Types {
+		schema.Types = append(schema.Types, t.model())
+	}
+	for _, d := range raw.Directives {
+		schema.Directives = append(schema.Directives, models.GraphQLDirective{
+			Name:        d.Name,
+			Description: d.Description,
+			Locations:   d.Locations,
+			Args:        inputValues(d.Args),
+		})
+	}
+	return schema, nil
+}
+
+// GraphQLEndpoint returns the key a GraphQL endpoint's schema is cached
+// under: its URL without query string or fragment
+func GraphQLEndpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.Host = strings.ToLower(u.Host)
+	return u.String()
+}
+
+// model converts a type from the introspection result
+func (t introspectionType) model() models.GraphQLType {
+	result := models.GraphQLType{
+		Kind:        t.Kind,
+		Name:        t.Name,
+		Description: t.Description,
+		InputFields: inputValues(t.InputFields),
+		EnumValues:  t.EnumValues,
+	}
+	for _, f := range t.Fields {
+		result.Fields = append(result.Fields, models.GraphQLField{
+			Name:              f.Name,
+			Description:       f.Description,
+			Args:              inputValues(f.Args),
+			Type:              f.Type.model(),
+			IsDeprecated:      f.IsDeprecated,
+			DeprecationReason: f.DeprecationReason,
+		})
+	}
+	for _, i := range t.Interfaces {
+		result.Interfaces = append(result.Interfaces, i.Name)
+	}
+	for _, p := range t.PossibleTypes {
+		result.PossibleTypes = append(result.PossibleTypes, p.Name)
+	}
+	return result
+}
+
+// model converts a type reference from the introspection result
+func (t introspectionTypeRef) model() models.GraphQLTypeRef {
+	ref := models.GraphQLTypeRef{Kind: t.Kind, Name: t.Name}
+	if t.OfType != nil {
+		ofType := t.OfType.model()
+		ref.OfType = &ofType
+	}
+	return ref
+}
+
+// inputValues converts arguments or input fields from the introspection result
+func inputValues(values []introspectionInputValue) []models.GraphQLInputValue {
+	var result []models.GraphQLInputValue
+	for _, v := range values {
+		result = append(result, models.GraphQLInputValue{
+			Name:         v.Name,
+			Description:  v.Description,
+			Type:         v.Type.model(),
+			DefaultValue: v.DefaultValue,
+		})
+	}
+	return result
+}
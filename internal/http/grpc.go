@@ -0,0 +1,522 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"postgirl/internal/models"
+)
+
+// grpcStatusNames are the canonical names of the gRPC status codes
+var grpcStatusNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND",
+	"ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION",
+	"ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// grpcHTTPStatus maps gRPC status codes to the HTTP status a response
+// reports, following the usual gRPC-HTTP gateway mapping
+var grpcHTTPStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           statusClientClosedRequest,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// statusClientClosedRequest is reported for calls cancelled by the client
+const statusClientClosedRequest = 499
+
+// grpcTarget is the server and method a gRPC request's URL names
+type grpcTarget struct {
+	address string // host:port
+	tls     bool
+	service string
+	method  string
+}
+
+// parseGRPCURL parses grpc://host:port/package.Service/Method, or grpcs://
+// for TLS. The method may be left out where only the server is needed.
+func parseGRPCURL(rawURL string) (grpcTarget, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return grpcTarget{}, fmt.Errorf("invalid gRPC URL: %w", err)
+	}
+
+	var target grpcTarget
+	switch strings.ToLower(u.Scheme) {
+	case "grpc":
+	case "grpcs":
+		target.tls = true
+	default:
+		return grpcTarget{}, fmt.Errorf("gRPC URL must start with grpc:// or grpcs://")
+	}
+	if u.Host == "" {
+		return grpcTarget{}, fmt.Errorf("gRPC URL has no host")
+	}
+	target.address = u.Host
+	if u.Port() == "" {
+		port := "80"
+		if target.tls {
+			port = "443"
+		}
+		target.address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	path := strings.Trim(u.Path, "/")
+	if path != "" {
+		service, method, ok := strings.Cut(path, "/")
+		if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+			return grpcTarget{}, fmt.Errorf("gRPC URL path must be /package.Service/Method")
+		}
+		target.service, target.method = service, method
+	}
+	return target, nil
+}
+
+// dialGRPC creates a connection to the server a gRPC request names, resolved
+// with the environment's host overrides and DNS server. Calls on it use the
+// client's certificates for grpcs:// URLs.
+func (c *Client) dialGRPC(target grpcTarget, env *models.Environment) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if target.tls {
+		var config *tls.Config
+		if c.config.Certificates != nil {
+			_, config, _ = c.config.Certificates.tlsConfig(target.address, "443")
+		}
+		if config == nil {
+			config = &tls.Config{}
+		}
+		creds = credentials.NewTLS(config)
+	}
+
+	options := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUserAgent(c.config.UserAgent),
+	}
+	address := target.address
+	if dial := dialTargetFor(env); !dial.isDefault() {
+		dialContext := dial.dialContext(newDialer())
+		options = append(options, grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return dialContext(ctx, "tcp", address)
+		}))
+		// The default dns resolver would look the host up before the dialer
+		// sees it, so pass the host name through for the dialer to resolve
+		address = "passthrough:///" + address
+	}
+	conn, err := grpc.NewClient(address, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return conn, nil
+}
+
+// DescribeGRPC lists the services of the server a gRPC request is sent to,
+// from the request's proto files or by server reflection
+func (c *Client) DescribeGRPC(ctx context.Context, req *models.Request, env *models.Environment) ([]models.GRPCService, error) {
+	target, err := parseGRPCURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	settings := c.settingsFor(req.Settings)
+	if settings.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.timeout)
+		defer cancel()
+	}
+
+	var conn *grpc.ClientConn
+	if req.GRPC == nil || len(req.GRPC.ProtoFiles) == 0 {
+		conn, err = c.dialGRPC(target, env)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		ctx, err = c.outgoingMetadata(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	descriptors, err := loadDescriptors(ctx, conn, req.GRPC, "")
+	if err != nil {
+		return nil, err
+	}
+	return descriptors.describe()
+}
+
+// InvokeGRPC calls the gRPC method a request names, sending its body as the
+// request message, or each element of a JSON array as one message of a
+// client stream. The call is bounded by the request's timeout; it isn't retried.
+//
+// Whatever status the server ends the call with is reported in the
+// response's GRPC result rather than as an error. Messages received on a
+// server stream are also passed to the context's event handler as they
+// arrive; see WithEventHandler.
+func (c *Client) InvokeGRPC(ctx context.Context, req *models.Request, env *models.Environment) (*models.Response, error) {
+	target, err := parseGRPCURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	if target.method == "" {
+		return nil, fmt.Errorf("gRPC URL path must be /package.Service/Method")
+	}
+	settings := c.settingsFor(req.Settings)
+	if settings.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.timeout)
+		defer cancel()
+	}
+
+	conn, err := c.dialGRPC(target, env)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx, err = c.outgoingMetadata(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	descriptors, err := loadDescriptors(ctx, conn, req.GRPC, target.service)
+	if err != nil {
+		return nil, err
+	}
+	method, err := descriptors.findMethod(target.service, target.method)
+	if err != nil {
+		return nil, err
+	}
+	types := dynamicpb.NewTypes(descriptors.files)
+	messages, err := requestMessages(req.Body, method, types)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, _ := ctx.Value(eventHandlerContextKey{}).(EventHandler)
+	call := &grpcCall{
+		method:  method,
+		types:   types,
+		handler: handler,
+		result: &models.GRPCResult{
+			Method:   fmt.Sprintf("%s/%s", target.service, target.method),
+			Trailers: models.KeyValues{},
+			Messages: []models.GRPCMessage{},
+		},
+	}
+	started := time.Now()
+	header, trailer, received, err := call.run(ctx, conn, messages)
+	duration := time.Since(started)
+	call.setStatus(err)
+
+	body := received
+	if method.IsStreamingServer() {
+		body = "[" + strings.Join(call.bodies, ",") + "]"
+	}
+	body = indentJSON([]byte(body))
+	call.result.Trailers = metadataList(trailer)
+
+	return &models.Response{
+		ID:          generateID(),
+		RequestID:   req.ID,
+		StatusCode:  grpcHTTPStatus[codes.Code(call.result.Code)],
+		Headers:     metadataList(header),
+		Body:        body,
+		Size:        int64(len(body)),
+		Duration:    duration,
+		Timing:      models.ResponseTiming{Total: duration},
+		CreatedAt:   time.Now(),
+		RawBody:     []byte(body),
+		ContentType: "application/json",
+		GRPC:        call.result,
+	}, nil
+}
+
+// grpcCall is one call of a gRPC method
+type grpcCall struct {
+	method  protoreflect.MethodDescriptor
+	types   *dynamicpb.Types
+	handler EventHandler
+	mu      sync.Mutex
+	result  *models.GRPCResult
+	bodies  []string // the received messages as JSON
+}
+
+// run sends the messages and receives the replies until the server ends the
+// call. It returns the header and trailer metadata, the last message received
+// as JSON, and the error ending the call, nil for an OK status.
+func (g *grpcCall) run(ctx context.Context, conn *grpc.ClientConn, messages []*dynamicpb.Message) (metadata.MD, metadata.MD, string, error) {
+	desc := &grpc.StreamDesc{
+		ClientStreams: g.method.IsStreamingClient(),
+		ServerStreams: g.method.IsStreamingServer(),
+	}
+	fullMethod := fmt.Sprintf("/%s/%s", g.method.Parent().FullName(), g.method.Name())
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, desc, fullMethod)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// Send while receiving, so bidirectional servers may answer each message
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for _, message := range messages {
+			if err := stream.SendMsg(message); err != nil {
+				// The server ended the call; RecvMsg reports its status
+				return
+			}
+			g.record("sent", message)
+		}
+		stream.CloseSend()
+	}()
+
+	var last string
+	for {
+		reply := dynamicpb.NewMessage(g.method.Output())
+		err = stream.RecvMsg(reply)
+		if err != nil {
+			break
+		}
+		last = g.record("received", reply)
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	// Stop a sender still blocked on a server that didn't read
+	cancel()
+	<-sent
+
+	header, _ := stream.Header()
+	return header, stream.Trailer(), last, err
+}
+
+// record logs a message sent or received, returning it as JSON
+func (g *grpcCall) record(direction string, message *dynamicpb.Message) string {
+	data, err := protojson.MarshalOptions{Resolver: g.types}.Marshal(message)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%q", err.Error()))
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, data) == nil {
+		data = compact.Bytes()
+	}
+	now := time.Now()
+
+	g.mu.Lock()
+	g.result.Messages = append(g.result.Messages, models.GRPCMessage{Direction: direction, Data: string(data), Time: now})
+	if direction == "received" {
+		g.bodies = append(g.bodies, string(data))
+	}
+	g.mu.Unlock()
+
+	if direction == "received" && g.handler != nil && g.method.IsStreamingServer() {
+		g.handler(models.ServerSentEvent{Event: "message", Data: string(data), ReceivedAt: now})
+	}
+	return string(data)
+}
+
+// setStatus records the status the call ended with
+func (g *grpcCall) setStatus(err error) {
+	st := status.Convert(err)
+	g.result.Code = int(st.Code())
+	g.result.Status = st.Code().String()
+	if int(st.Code()) < len(grpcStatusNames) {
+		g.result.Status = grpcStatusNames[st.Code()]
+	}
+	g.result.Message = st.Message()
+
+	resolver := resolverChain{g.types, protoregistry.GlobalTypes}
+	for _, detail := range st.Proto().GetDetails() {
+		data, err := protojson.MarshalOptions{Resolver: resolver}.Marshal(detail)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"@type": detail.GetTypeUrl()})
+		}
+		g.result.Details = append(g.result.Details, indentJSON(data))
+	}
+}
+
+// requestMessages decodes the request message, or a JSON array of messages
+// for client-streaming methods. An empty body sends one empty message.
+func requestMessages(body *models.RequestBody, method protoreflect.MethodDescriptor, types *dynamicpb.Types) ([]*dynamicpb.Message, error) {
+	content := ""
+	if body != nil {
+		content = strings.TrimSpace(body.Content)
+	}
+	if content == "" {
+		content = "{}"
+	}
+
+	var raw []json.RawMessage
+	if strings.HasPrefix(content, "[") {
+		if !method.IsStreamingClient() {
+			return nil, fmt.Errorf("method %s takes a single request message, not an array", method.Name())
+		}
+		if err := json.Unmarshal([]byte(content), &raw); err != nil {
+			return nil, fmt.Errorf("invalid request messages: %w", err)
+		}
+	} else {
+		raw = []json.RawMessage{json.RawMessage(content)}
+	}
+
+	messages := make([]*dynamicpb.Message, 0, len(raw))
+	for i, data := range raw {
+		message := dynamicpb.NewMessage(method.Input())
+		if err := (protojson.UnmarshalOptions{Resolver: types}).Unmarshal(data, message); err != nil {
+			if len(raw) > 1 {
+				return nil, fmt.Errorf("invalid request message %d: %w", i+1, err)
+			}
+			return nil, fmt.Errorf("invalid request message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// outgoingMetadata attaches a request's enabled headers and authentication
+// to ctx as metadata. Values of binary -bin keys are given in base64.
+func (c *Client) outgoingMetadata(ctx context.Context, req *models.Request) (context.Context, error) {
+	header := http.Header{}
+	for _, h := range req.Headers.Enabled() {
+		header.Add(h.Key, h.Value)
+	}
+	if req.Auth != nil {
+		if err := staticAuth(header, req.Auth, "gRPC"); err != nil {
+			return nil, fmt.Errorf("failed to apply authentication: %w", err)
+		}
+	}
+
+	md := metadata.MD{}
+	for key, values := range header {
+		key = strings.ToLower(key)
+		for _, value := range values {
+			if strings.HasSuffix(key, "-bin") {
+				decoded, err := decodeBase64(value)
+				if err != nil {
+					return nil, fmt.Errorf("metadata %s must be base64: %w", key, err)
+				}
+				value = string(decoded)
+			}
+			md.Append(key, value)
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// decodeBase64 decodes standard base64, padded or not
+func decodeBase64(value string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// metadataList converts metadata to a sorted list, with binary values in base64
+func metadataList(md metadata.MD) models.KeyValues {
+	keys := make([]string, 0, len(md))
+	for key := range md {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := models.KeyValues{}
+	for _, key := range keys {
+		for _, value := range md[key] {
+			if strings.HasSuffix(key, "-bin") {
+				value = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+			list.Add(key, value)
+		}
+	}
+	return list
+}
+
+// indentJSON formats JSON for display, leaving anything else as it is
+func indentJSON(data []byte) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return out.String()
+}
+
+// resolverChain looks up message types in each resolver in turn, for Any
+// values holding types the server's descriptors or this program define
+type resolverChain []interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+}
+
+// FindMessageByName implements protoregistry.MessageTypeResolver
+func (r resolverChain) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	for _, resolver := range r {
+		if t, err := resolver.FindMessageByName(name); err == nil {
+			return t, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+// FindMessageByURL implements protoregistry.MessageTypeResolver
+func (r resolverChain) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	for _, resolver := range r {
+		if t, err := resolver.FindMessageByURL(url); err == nil {
+			return t, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+// FindExtensionByName implements protoregistry.ExtensionTypeResolver
+func (r resolverChain) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	for _, resolver := range r {
+		if t, err := resolver.FindExtensionByName(field); err == nil {
+			return t, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+// FindExtensionByNumber implements protoregistry.ExtensionTypeResolver
+func (r resolverChain) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	for _, resolver := range r {
+		if t, err := resolver.FindExtensionByNumber(message, field); err == nil {
+			return t, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
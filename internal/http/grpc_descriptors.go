@@ -0,0 +1,328 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"postgirl/internal/models"
+)
+
+// reflectionMethods are the server reflection services, newest first. Their
+// messages are the same on the wire, so the v1 types serve both.
+var reflectionMethods = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// grpcDescriptors holds the files describing a server's services
+type grpcDescriptors struct {
+	files    *protoregistry.Files
+	services []protoreflect.FullName // the services the server or proto files define
+}
+
+// loadDescriptors describes the services of a gRPC request, from its proto
+// files if it has any or else by server reflection over conn. With
+// reflection, only the file defining symbol is fetched, unless symbol is empty.
+func loadDescriptors(ctx context.Context, conn *grpc.ClientConn, config *models.GRPCConfig, symbol string) (*grpcDescriptors, error) {
+	if config != nil && len(config.ProtoFiles) > 0 {
+		return compileProtoFiles(ctx, config)
+	}
+	return reflectDescriptors(ctx, conn, symbol)
+}
+
+// compileProtoFiles parses and links a request's proto files. Well-known
+// types such as google/protobuf/timestamp.proto are always available.
+func compileProtoFiles(ctx context.Context, config *models.GRPCConfig) (*grpcDescriptors, error) {
+	importPaths := config.ImportPaths
+	names := make([]string, len(config.ProtoFiles))
+	for i, path := range config.ProtoFiles {
+		names[i] = filepath.ToSlash(path)
+		if len(config.ImportPaths) == 0 {
+			dir := filepath.Dir(path)
+			if !contains(importPaths, dir) {
+				importPaths = append(importPaths, dir)
+			}
+			names[i] = filepath.Base(path)
+			continue
+		}
+		for _, importPath := range config.ImportPaths {
+			if rel, err := filepath.Rel(importPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+				names[i] = filepath.ToSlash(rel)
+				break
+			}
+		}
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	compiled, err := compiler.Compile(ctx, names...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile proto files: %w", err)
+	}
+
+	descriptors := &grpcDescriptors{files: new(protoregistry.Files)}
+	for _, file := range compiled {
+		if err := registerFile(descriptors.files, file); err != nil {
+			return nil, err
+		}
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			descriptors.services = append(descriptors.services, services.Get(i).FullName())
+		}
+	}
+	return descriptors, nil
+}
+
+// registerFile adds a file and the files it imports to a registry
+func registerFile(files *protoregistry.Files, file protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(file.Path()); err == nil {
+		return nil
+	}
+	imports := file.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFile(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	if err := files.RegisterFile(file); err != nil {
+		return fmt.Errorf("failed to register %s: %w", file.Path(), err)
+	}
+	return nil
+}
+
+// reflectionClient fetches file descriptors from a server's reflection service
+type reflectionClient struct {
+	stream grpc.ClientStream
+	protos map[string]*descriptorpb.FileDescriptorProto
+}
+
+// reflectDescriptors describes a server's services by server reflection
+func reflectDescriptors(ctx context.Context, conn *grpc.ClientConn, symbol string) (*grpcDescriptors, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		client   *reflectionClient
+		services []string
+		err      error
+	)
+	for _, method := range reflectionMethods {
+		var stream grpc.ClientStream
+		stream, err = conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, method)
+		if err != nil {
+			break
+		}
+		client = &reflectionClient{stream: stream, protos: make(map[string]*descriptorpb.FileDescriptorProto)}
+		services, err = client.listServices()
+		if status.Code(err) != codes.Unimplemented {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("server reflection failed: %w", err)
+	}
+
+	descriptors := &grpcDescriptors{}
+	for _, service := range services {
+		if strings.HasPrefix(service, "grpc.reflection.") {
+			continue
+		}
+		descriptors.services = append(descriptors.services, protoreflect.FullName(service))
+		if symbol != "" && service != symbol {
+			continue
+		}
+		if err := client.fileContaining(service); err != nil {
+			return nil, fmt.Errorf("server reflection failed for %s: %w", service, err)
+		}
+	}
+	if symbol != "" && !contains(services, symbol) {
+		return nil, fmt.Errorf("service %s not found on the server", symbol)
+	}
+
+	if err := client.resolveDependencies(); err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, file := range client.protos {
+		set.File = append(set.File, file)
+	}
+	descriptors.files, err = protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptors from server reflection: %w", err)
+	}
+	return descriptors, nil
+}
+
+// roundTrip sends a reflection request and returns its response
+func (r *reflectionClient) roundTrip(req *reflectionpb.ServerReflectionRequest) (*reflectionpb.ServerReflectionResponse, error) {
+	if err := r.stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	resp := &reflectionpb.ServerReflectionResponse{}
+	if err := r.stream.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.ErrorCode), e.ErrorMessage)
+	}
+	return resp, nil
+}
+
+// listServices returns the names of the services the server exposes
+func (r *reflectionClient) listServices() ([]string, error) {
+	resp, err := r.roundTrip(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// fileContaining fetches the file defining a symbol
+func (r *reflectionClient) fileContaining(symbol string) error {
+	resp, err := r.roundTrip(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	})
+	if err != nil {
+		return err
+	}
+	return r.addFiles(resp)
+}
+
+// addFiles records the files in a reflection response. Servers may send a
+// file's dependencies along with it.
+func (r *reflectionClient) addFiles(resp *reflectionpb.ServerReflectionResponse) error {
+	for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		file := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(data, file); err != nil {
+			return fmt.Errorf("invalid file descriptor: %w", err)
+		}
+		r.protos[file.GetName()] = file
+	}
+	return nil
+}
+
+// resolveDependencies fetches the files the fetched files import. Files the
+// server doesn't know, such as well-known types, are taken from the ones
+// compiled into this program.
+func (r *reflectionClient) resolveDependencies() error {
+	for {
+		var missing []string
+		for _, file := range r.protos {
+			for _, dependency := range file.GetDependency() {
+				if _, ok := r.protos[dependency]; !ok && !contains(missing, dependency) {
+					missing = append(missing, dependency)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		for _, name := range missing {
+			resp, err := r.roundTrip(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+			})
+			if err == nil {
+				err = r.addFiles(resp)
+			}
+			if _, ok := r.protos[name]; ok {
+				continue
+			}
+			builtin, findErr := protoregistry.GlobalFiles.FindFileByPath(name)
+			if findErr != nil {
+				if err == nil {
+					err = findErr
+				}
+				return fmt.Errorf("failed to resolve %s: %w", name, err)
+			}
+			r.protos[name] = protodesc.ToFileDescriptorProto(builtin)
+		}
+	}
+}
+
+// findMethod looks up package.Service/Method
+func (d *grpcDescriptors) findMethod(service, method string) (protoreflect.MethodDescriptor, error) {
+	descriptor, err := d.files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found", service)
+	}
+	serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	methodDescriptor := serviceDescriptor.Methods().ByName(protoreflect.Name(method))
+	if methodDescriptor == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", method, service)
+	}
+	return methodDescriptor, nil
+}
+
+// describe lists the services with their methods
+func (d *grpcDescriptors) describe() ([]models.GRPCService, error) {
+	services := make([]models.GRPCService, 0, len(d.services))
+	for _, name := range d.services {
+		descriptor, err := d.files.FindDescriptorByName(name)
+		if err != nil {
+			// Reflection fetched only the file of the requested service
+			continue
+		}
+		serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+
+		service := models.GRPCService{Name: string(name), Methods: []models.GRPCMethod{}}
+		methods := serviceDescriptor.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			m := methods.Get(i)
+			template, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(dynamicpb.NewMessage(m.Input()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe %s: %w", m.FullName(), err)
+			}
+			service.Methods = append(service.Methods, models.GRPCMethod{
+				Name:            string(m.Name()),
+				FullName:        fmt.Sprintf("%s/%s", name, m.Name()),
+				ClientStreaming: m.IsStreamingClient(),
+				ServerStreaming: m.IsStreamingServer(),
+				InputType:       string(m.Input().FullName()),
+				OutputType:      string(m.Output().FullName()),
+				InputTemplate:   indentJSON(template),
+			})
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// contains reports whether a list holds s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	testgrpc "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"postgirl/internal/models"
+)
+
+// testService echoes payloads back, streams one response per requested size,
+// and ends calls with the status a request asks for
+type testService struct {
+	testgrpc.UnimplementedTestServiceServer
+}
+
+func (testService) UnaryCall(ctx context.Context, req *testgrpc.SimpleRequest) (*testgrpc.SimpleResponse, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-token")) > 0 {
+		grpc.SetHeader(ctx, metadata.Pairs("x-echo", md.Get("x-token")[0]))
+	}
+	if st := req.GetResponseStatus(); st != nil && st.GetCode() != 0 {
+		return nil, status.Error(codes.Code(st.GetCode()), st.GetMessage())
+	}
+	return &testgrpc.SimpleResponse{Payload: req.GetPayload(), Username: "tester"}, nil
+}
+
+func (testService) StreamingOutputCall(req *testgrpc.StreamingOutputCallRequest, stream testgrpc.TestService_StreamingOutputCallServer) error {
+	for _, params := range req.GetResponseParameters() {
+		payload := &testgrpc.Payload{Body: []byte(strings.Repeat("x", int(params.GetSize())))}
+		if err := stream.Send(&testgrpc.StreamingOutputCallResponse{Payload: payload}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newGRPCServer serves the test service with reflection on a loopback
+// listener and returns its grpc:// URL
+func newGRPCServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	testgrpc.RegisterTestServiceServer(server, testService{})
+	reflection.Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return "grpc://" + listener.Addr().String()
+}
+
+func grpcRequest(url, body string) *models.Request {
+	return &models.Request{
+		ID:   "r",
+		Type: models.RequestTypeGRPC,
+		URL:  url,
+		Body: &models.RequestBody{Type: "json", Content: body},
+	}
+}
+
+func TestGRPCDescribeByReflection(t *testing.T) {
+	server := newGRPCServer(t)
+
+	services, err := NewClient(nil).DescribeGRPC(context.Background(), grpcRequest(server, ""), nil)
+	if err != nil {
+		t.Fatalf("describe: %v", err)
+	}
+
+	var found *models.GRPCService
+	for i := range services {
+		if services[i].Name == "grpc.testing.TestService" {
+			found = &services[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("services = %+v, want grpc.testing.TestService", services)
+	}
+	methods := map[string]models.GRPCMethod{}
+	for _, method := range found.Methods {
+		methods[method.Name] = method
+	}
+	unary := methods["UnaryCall"]
+	if unary.FullName != "grpc.testing.TestService/UnaryCall" || unary.InputType != "grpc.testing.SimpleRequest" || unary.ServerStreaming {
+		t.Errorf("UnaryCall = %+v", unary)
+	}
+	if !strings.Contains(unary.InputTemplate, `"responseSize"`) {
+		t.Errorf("UnaryCall input template = %s, want every field of SimpleRequest", unary.InputTemplate)
+	}
+	if streaming := methods["StreamingOutputCall"]; !streaming.ServerStreaming || streaming.ClientStreaming {
+		t.Errorf("StreamingOutputCall = %+v, want server streaming only", streaming)
+	}
+}
+
+func TestGRPCUnaryCall(t *testing.T) {
+	server := newGRPCServer(t)
+	req := grpcRequest(server+"/grpc.testing.TestService/UnaryCall", `{"payload": {"body": "aGVsbG8="}}`)
+	req.Headers = models.KeyValues{{Key: "x-token", Value: "secret", Enabled: true}}
+
+	resp, err := NewClient(nil).InvokeGRPC(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+
+	var body struct {
+		Payload  struct{ Body string }
+		Username string
+	}
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+		t.Fatalf("body %q: %v", resp.Body, err)
+	}
+	if body.Payload.Body != "aGVsbG8=" || body.Username != "tester" {
+		t.Errorf("body = %s, want the payload echoed back", resp.Body)
+	}
+	if resp.StatusCode != 200 || resp.GRPC.Status != "OK" || resp.GRPC.Method != "grpc.testing.TestService/UnaryCall" {
+		t.Errorf("status = %d, result = %+v", resp.StatusCode, resp.GRPC)
+	}
+	if got := resp.Headers.Get("x-echo"); got != "secret" {
+		t.Errorf("x-echo header = %q, want the request's metadata echoed back", got)
+	}
+	if len(resp.GRPC.Messages) != 2 || resp.GRPC.Messages[0].Direction != "sent" || resp.GRPC.Messages[1].Direction != "received" {
+		t.Errorf("messages = %+v, want one sent and one received", resp.GRPC.Messages)
+	}
+}
+
+func TestGRPCServerStreaming(t *testing.T) {
+	server := newGRPCServer(t)
+	req := grpcRequest(server+"/grpc.testing.TestService/StreamingOutputCall",
+		`{"responseParameters": [{"size": 1}, {"size": 2}, {"size": 3}]}`)
+
+	var mu sync.Mutex
+	var events []models.ServerSentEvent
+	ctx := WithEventHandler(context.Background(), func(event models.ServerSentEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+	resp, err := NewClient(nil).InvokeGRPC(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+
+	var body []json.RawMessage
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil || len(body) != 3 {
+		t.Fatalf("body = %s, want an array of the three streamed messages", resp.Body)
+	}
+	if resp.GRPC.Status != "OK" {
+		t.Errorf("status = %s, want OK", resp.GRPC.Status)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 3 {
+		t.Errorf("events = %+v, want one per streamed message", events)
+	}
+}
+
+func TestGRPCErrorStatus(t *testing.T) {
+	server := newGRPCServer(t)
+	req := grpcRequest(server+"/grpc.testing.TestService/UnaryCall",
+		`{"responseStatus": {"code": 5, "message": "no such thing"}}`)
+
+	resp, err := NewClient(nil).InvokeGRPC(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if resp.StatusCode != 404 || resp.GRPC.Code != 5 || resp.GRPC.Status != "NOT_FOUND" || resp.GRPC.Message != "no such thing" {
+		t.Errorf("status = %d, result = %+v, want NOT_FOUND", resp.StatusCode, resp.GRPC)
+	}
+
+	// A method the server doesn't implement ends the call rather than failing it
+	resp, err = NewClient(nil).InvokeGRPC(context.Background(), grpcRequest(server+"/grpc.testing.TestService/EmptyCall", ""), nil)
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if resp.GRPC.Status != "UNIMPLEMENTED" {
+		t.Errorf("status = %s, want UNIMPLEMENTED", resp.GRPC.Status)
+	}
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"postgirl/internal/models"
+)
+
+// newHawkServer answers Hawk requests with a gzip-encoded body and a
+// Server-Authorization header whose payload hash covers hashed
+func newHawkServer(t *testing.T, body, hashed []byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		challenges, err := parseAuthChallenges(r.Header.Get("Authorization"))
+		if err != nil || len(challenges) == 0 || challenges[0].scheme != "Hawk" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		params := challenges[0].params
+
+		h, err := newHawkSigner(map[string]string{"id": "id", "key": "key"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		signed := r.Clone(r.Context())
+		signed.URL.Scheme, signed.URL.Host = "http", r.Host
+		hash := h.hashPayload("application/json", hashed)
+		mac := h.mac("response", signed, params["ts"], params["nonce"], hash, "")
+
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write(body)
+		gz.Close()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Server-Authorization", fmt.Sprintf(`Hawk mac="%s", hash="%s"`, mac, hash))
+		w.Write(compressed.Bytes())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func hawkRequest(rawURL string) *models.Request {
+	return &models.Request{
+		ID:     "r",
+		Method: "GET",
+		URL:    rawURL,
+		Auth: &models.AuthConfig{Type: "hawk", Config: map[string]string{
+			"id":              "id",
+			"key":             "key",
+			"verify_response": "true",
+		}},
+	}
+}
+
+func TestHawkVerifiesEncodedResponse(t *testing.T) {
+	payload := []byte(`{"ok":true}`)
+	server := newHawkServer(t, payload, payload)
+
+	config := DefaultConfig()
+	config.RetryCount = 0
+	resp, err := NewClient(config).Execute(context.Background(), hawkRequest(server.URL), nil)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if resp.Body != string(payload) {
+		t.Errorf("body = %q, want %q", resp.Body, payload)
+	}
+	if resp.ContentEncoding != "gzip" {
+		t.Errorf("content encoding = %q, want gzip", resp.ContentEncoding)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(resp.WireBody))
+	if err != nil {
+		t.Fatalf("wire body is not the gzip body as received: %v", err)
+	}
+	var decoded bytes.Buffer
+	decoded.ReadFrom(reader)
+	if decoded.String() != string(payload) {
+		t.Errorf("wire body decodes to %q, want %q", decoded.String(), payload)
+	}
+	if resp.WireSize != int64(len(resp.WireBody)) || resp.WireSize == resp.Size {
+		t.Errorf("wire size = %d, size = %d, want the compressed size of %d bytes", resp.WireSize, resp.Size, len(resp.WireBody))
+	}
+}
+
+func TestHawkRejectsPayloadMismatch(t *testing.T) {
+	server := newHawkServer(t, []byte(`{"ok":false}`), []byte(`{"ok":true}`))
+
+	config := DefaultConfig()
+	config.RetryCount = 0
+	if _, err := NewClient(config).Execute(context.Background(), hawkRequest(server.URL), nil); err == nil {
+		t.Error("expected an error for a payload that does not match its hash")
+	}
+}
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"strconv"
+	"time"
+)
+
+// jwtDefaultLifetime is used for exp when the claims and config don't set one
+const jwtDefaultLifetime = 5 * time.Minute
+
+// buildJWT signs a JWT from the auth config's claims template. Variables in the
+// template have already been substituted; iat, exp and jti are filled in when missing.
+func buildJWT(config map[string]string, now time.Time) (string, error) {
+	algorithm := config["algorithm"]
+	if algorithm == "" {
+		algorithm = "HS256"
+	}
+
+	claims := make(map[string]interface{})
+	if template := config["claims"]; template != "" {
+		decoder := json.NewDecoder(bytes.NewReader([]byte(template)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&claims); err != nil {
+			return "", fmt.Errorf("invalid JWT claims: %w", err)
+		}
+	}
+
+	lifetime := jwtDefaultLifetime
+	if expiresIn, ok := config["expires_in"]; ok && expiresIn != "" {
+		seconds, err := strconv.Atoi(expiresIn)
+		if err != nil || seconds <= 0 {
+			return "", fmt.Errorf("expires_in must be a positive number of seconds")
+		}
+		lifetime = time.Duration(seconds) * time.Second
+	}
+
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now.Unix()
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = now.Add(lifetime).Unix()
+	}
+	if _, ok := claims["jti"]; !ok {
+		jti := make([]byte, 16)
+		if _, err := rand.Read(jti); err != nil {
+			return "", err
+		}
+		claims["jti"] = hex.EncodeToString(jti)
+	}
+
+	header := make(map[string]interface{})
+	if extra := config["header"]; extra != "" {
+		if err := json.Unmarshal([]byte(extra), &header); err != nil {
+			return "", fmt.Errorf("invalid JWT header: %w", err)
+		}
+	}
+	header["alg"] = algorithm
+	header["typ"] = "JWT"
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	signature, err := signJWT(algorithm, config, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// signJWT signs the JWT signing input with the configured algorithm and key
+func signJWT(algorithm string, config map[string]string, input []byte) ([]byte, error) {
+	switch algorithm {
+	case "HS256", "HS384", "HS512":
+		secret := []byte(config["secret"])
+		if config["secret_base64"] == "true" {
+			decoded, err := base64.StdEncoding.DecodeString(config["secret"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 secret: %w", err)
+			}
+			secret = decoded
+		}
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("secret required for %s", algorithm)
+		}
+
+		newHash := map[string]func() hash.Hash{
+			"HS256": sha256.New,
+			"HS384": sha512.New384,
+			"HS512": sha512.New,
+		}[algorithm]
+		mac := hmac.New(newHash, secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+
+	case "RS256":
+		key, err := parsePrivateKey(config["private_key"])
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("RS256 requires an RSA private key")
+		}
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+
+	case "ES256":
+		key, err := parsePrivateKey(config["private_key"])
+		if err != nil {
+			return nil, err
+		}
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok || ecKey.Curve.Params().BitSize != 256 {
+			return nil, fmt.Errorf("ES256 requires a P-256 private key")
+		}
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the fixed-width r || s encoding rather than ASN.1
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", algorithm)
+	}
+}
+
+// parsePrivateKey parses a PEM encoded PKCS#1, SEC 1 or PKCS#8 private key
+func parsePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("private_key must be PEM encoded")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type")
+		}
+		return signer, nil
+	}
+}
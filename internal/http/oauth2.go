@@ -0,0 +1,460 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// oauth2ExpirySkew refreshes tokens slightly before they actually expire
+const oauth2ExpirySkew = 30 * time.Second
+
+// oauth2AuthorizeTimeout bounds how long the loopback listener waits for the redirect
+const oauth2AuthorizeTimeout = 5 * time.Minute
+
+// oauth2Token represents a token issued by an authorization server
+type oauth2Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// valid reports whether the token can still be used
+func (t *oauth2Token) valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(oauth2ExpirySkew).Before(t.Expiry)
+}
+
+// authorizationHeader returns the Authorization header value for the token
+func (t *oauth2Token) authorizationHeader() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// oauth2Manager acquires OAuth 2.0 tokens and caches them per auth config
+type oauth2Manager struct {
+	client *resty.Client
+	mu     sync.Mutex
+	tokens map[string]*oauth2Token
+
+	// fetching serializes acquisition per cache key, so concurrent requests
+	// share one grant without holding mu across token endpoint calls
+	fetching map[string]chan struct{}
+
+	// openAuthorizationURL sends the user to the authorization endpoint
+	openAuthorizationURL func(authURL string) error
+}
+
+// newOAuth2Manager creates a token manager that talks to token endpoints through client
+func newOAuth2Manager(client *resty.Client) *oauth2Manager {
+	return &oauth2Manager{
+		client:               client,
+		tokens:               make(map[string]*oauth2Token),
+		fetching:             make(map[string]chan struct{}),
+		openAuthorizationURL: openBrowser,
+	}
+}
+
+// tokenContext returns a context for fetching a token on behalf of the
+// request on ctx. It ends when ctx does and keeps its deadline and settings,
+// but none of its other values, such as its tracer, redirect chain or proxy
+// and dial overrides, so token requests use the global proxy settings.
+func tokenContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	tokenCtx := context.Background()
+	if settings, ok := ctx.Value(settingsContextKey{}).(requestSettings); ok {
+		tokenCtx = withRequestSettings(tokenCtx, settings)
+	}
+
+	var cancel context.CancelFunc
+	if deadline, ok := ctx.Deadline(); ok {
+		tokenCtx, cancel = context.WithDeadline(tokenCtx, deadline)
+	} else {
+		tokenCtx, cancel = context.WithCancel(tokenCtx)
+	}
+	stop := context.AfterFunc(ctx, cancel)
+	return tokenCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// token returns a usable token for the config, acquiring or refreshing it as needed
+func (m *oauth2Manager) token(ctx context.Context, config map[string]string) (*oauth2Token, error) {
+	grantType := config["grant_type"]
+	if grantType == "" {
+		// Manually supplied token
+		accessToken, ok := config["access_token"]
+		if !ok {
+			return nil, fmt.Errorf("access_token or grant_type required for OAuth2 auth")
+		}
+		return &oauth2Token{AccessToken: accessToken, TokenType: config["token_type"]}, nil
+	}
+
+	key := oauth2CacheKey(config)
+
+	if cached := m.cached(key); cached.valid() {
+		return cached, nil
+	}
+
+	// Only one acquisition per config at a time; the others wait for its token
+	unlock, err := m.lockKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cached := m.cached(key)
+	if cached.valid() {
+		return cached, nil
+	}
+
+	// Prefer a refresh over a full grant when the server gave us a refresh token
+	if cached != nil && cached.RefreshToken != "" {
+		if token, err := m.refresh(ctx, config, cached.RefreshToken); err == nil {
+			m.store(key, token)
+			return token, nil
+		}
+	}
+
+	var token *oauth2Token
+	switch grantType {
+	case "client_credentials":
+		token, err = m.requestToken(ctx, config, url.Values{
+			"grant_type": {"client_credentials"},
+		})
+	case "password":
+		token, err = m.requestToken(ctx, config, url.Values{
+			"grant_type": {"password"},
+			"username":   {config["username"]},
+			"password":   {config["password"]},
+		})
+	case "refresh_token":
+		refreshToken, ok := config["refresh_token"]
+		if !ok {
+			return nil, fmt.Errorf("refresh_token required for refresh_token grant")
+		}
+		token, err = m.refresh(ctx, config, refreshToken)
+	case "authorization_code":
+		token, err = m.authorizationCode(ctx, config)
+	default:
+		return nil, fmt.Errorf("unsupported OAuth2 grant type: %s", grantType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.store(key, token)
+	return token, nil
+}
+
+// cached returns the token cached for key, if any
+func (m *oauth2Manager) cached(key string) *oauth2Token {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tokens[key]
+}
+
+// store caches the token for key
+func (m *oauth2Manager) store(key string, token *oauth2Token) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[key] = token
+}
+
+// lockKey waits until no other token acquisition for key is running and
+// returns the function that releases it
+func (m *oauth2Manager) lockKey(ctx context.Context, key string) (func(), error) {
+	m.mu.Lock()
+	lock, ok := m.fetching[key]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		m.fetching[key] = lock
+	}
+	m.mu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh exchanges a refresh token for a new access token
+func (m *oauth2Manager) refresh(ctx context.Context, config map[string]string, refreshToken string) (*oauth2Token, error) {
+	token, err := m.requestToken(ctx, config, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Servers may omit the refresh token when it is not rotated
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// authorizationCode runs the authorization code grant with PKCE, receiving
+// the code on a loopback redirect listener
+func (m *oauth2Manager) authorizationCode(ctx context.Context, config map[string]string) (*oauth2Token, error) {
+	authURL, ok := config["auth_url"]
+	if !ok {
+		return nil, fmt.Errorf("auth_url required for authorization_code grant")
+	}
+
+	redirectURI := config["redirect_uri"]
+	if redirectURI == "" {
+		redirectURI = "http://127.0.0.1:0/callback"
+	}
+	redirect, err := url.Parse(redirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect_uri: %w", err)
+	}
+	if host := redirect.Hostname(); host != "127.0.0.1" && host != "localhost" && host != "::1" {
+		return nil, fmt.Errorf("redirect_uri must be a loopback address, got %s", redirect.Host)
+	}
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start redirect listener: %w", err)
+	}
+	defer listener.Close()
+
+	// An ephemeral port is only known once the listener is up
+	redirect.Host = net.JoinHostPort(redirect.Hostname(), strconv.Itoa(listener.Addr().(*net.TCPAddr).Port))
+	if redirect.Path == "" {
+		redirect.Path = "/"
+	}
+	redirectURI = redirect.String()
+
+	verifier, err := randomURLSafe(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomURLSafe(16)
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authorize, err := url.Parse(authURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth_url: %w", err)
+	}
+	query := authorize.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", config["client_id"])
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if scope := config["scope"]; scope != "" {
+		query.Set("scope", scope)
+	}
+	authorize.RawQuery = query.Encode()
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != redirect.Path {
+			http.NotFound(w, r)
+			return
+		}
+
+		params := r.URL.Query()
+		var result callbackResult
+		switch {
+		case params.Get("error") != "":
+			result.err = fmt.Errorf("authorization failed: %s %s", params.Get("error"), params.Get("error_description"))
+		case params.Get("state") != state:
+			result.err = fmt.Errorf("authorization failed: state mismatch")
+		default:
+			result.code = params.Get("code")
+		}
+
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Authorization complete. You can close this window and return to Postgirl.")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	if err := m.openAuthorizationURL(authorize.String()); err != nil {
+		return nil, fmt.Errorf("failed to open authorization URL %s: %w", authorize.String(), err)
+	}
+
+	var result callbackResult
+	select {
+	case result = <-results:
+	case <-time.After(oauth2AuthorizeTimeout):
+		return nil, fmt.Errorf("timed out waiting for authorization redirect")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	return m.requestToken(ctx, config, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+}
+
+// requestToken posts a grant to the token endpoint and parses the response
+func (m *oauth2Manager) requestToken(ctx context.Context, config map[string]string, form url.Values) (*oauth2Token, error) {
+	tokenURL, ok := config["token_url"]
+	if !ok {
+		return nil, fmt.Errorf("token_url required for OAuth2 %s grant", form.Get("grant_type"))
+	}
+
+	if scope := config["scope"]; scope != "" && form.Get("scope") == "" {
+		form.Set("scope", scope)
+	}
+	if audience := config["audience"]; audience != "" {
+		form.Set("audience", audience)
+	}
+
+	// Token requests get the same time limit as the request they authorize
+	if settings, ok := ctx.Value(settingsContextKey{}).(requestSettings); ok && settings.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.timeout)
+		defer cancel()
+	}
+
+	r := m.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/x-www-form-urlencoded").
+		SetHeader("Accept", "application/json")
+
+	clientID, clientSecret := config["client_id"], config["client_secret"]
+	if config["client_authentication"] == "body" || clientSecret == "" {
+		if clientID != "" {
+			form.Set("client_id", clientID)
+		}
+		if clientSecret != "" {
+			form.Set("client_secret", clientSecret)
+		}
+	} else {
+		// client_secret_basic; set directly since resty warns about Basic auth over plain HTTP
+		credentials := url.QueryEscape(clientID) + ":" + url.QueryEscape(clientSecret)
+		r.SetHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+
+	resp, err := r.SetBody(form.Encode()).Post(tokenURL)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+
+	var body struct {
+		AccessToken      string          `json:"access_token"`
+		TokenType        string          `json:"token_type"`
+		RefreshToken     string          `json:"refresh_token"`
+		ExpiresIn        json.RawMessage `json:"expires_in"`
+		Error            string          `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return nil, fmt.Errorf("invalid token response (status %d): %w", resp.StatusCode(), err)
+	}
+	if body.Error != "" {
+		return nil, fmt.Errorf("token endpoint returned %s: %s", body.Error, body.ErrorDescription)
+	}
+	if resp.IsError() || body.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned status %d without an access token", resp.StatusCode())
+	}
+
+	token := &oauth2Token{
+		AccessToken:  body.AccessToken,
+		TokenType:    body.TokenType,
+		RefreshToken: body.RefreshToken,
+	}
+
+	// expires_in is a number, but some servers send it as a string
+	if expiresIn, err := strconv.ParseInt(strings.Trim(string(body.ExpiresIn), `"`), 10, 64); err == nil && expiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// oauth2CacheKey identifies an auth config, so tokens are shared only between
+// requests that would obtain the same token
+func oauth2CacheKey(config map[string]string) string {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		if key == "access_token" || key == "token_type" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hasher := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hasher, "%s=%s\n", key, config[key])
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// randomURLSafe returns n random bytes encoded as unpadded base64url
+func randomURLSafe(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// openBrowser opens a URL in the system browser
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	case "darwin":
+		cmd = exec.Command("open", target)
+	default: // linux
+		cmd = exec.Command("xdg-open", target)
+	}
+
+	return cmd.Start()
+}
@@ -0,0 +1,298 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"postgirl/internal/models"
+)
+
+// tokenServer is a token endpoint that records the grants it receives
+type tokenServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	grants    []string
+	cookies   []string
+	expiresIn int
+	issued    atomic.Int64
+	release   chan struct{}
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{expiresIn: expiresIn}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		grant := r.PostForm.Get("grant_type")
+		ts.mu.Lock()
+		ts.grants = append(ts.grants, grant)
+		ts.cookies = append(ts.cookies, r.Header.Values("Cookie")...)
+		release := ts.release
+		ts.mu.Unlock()
+		if release != nil && r.PostForm.Get("scope") == "slow" {
+			<-release
+		}
+
+		if grant == "refresh_token" && r.PostForm.Get("refresh_token") != "refresh-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		n := ts.issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "token-" + strconv.FormatInt(n, 10),
+			"token_type":    "bearer",
+			"refresh_token": "refresh-1",
+			"expires_in":    ts.expiresIn,
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *tokenServer) receivedGrants() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]string(nil), ts.grants...)
+}
+
+func (ts *tokenServer) config(grantType string) map[string]string {
+	return map[string]string{
+		"grant_type":    grantType,
+		"token_url":     ts.URL,
+		"client_id":     "client",
+		"client_secret": "secret",
+	}
+}
+
+func equalGrants(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOAuth2ClientCredentialsIsCached(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	m := newOAuth2Manager(resty.New())
+	config := ts.config("client_credentials")
+
+	first, err := m.token(context.Background(), config)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if got := first.authorizationHeader(); got != "Bearer token-1" {
+		t.Fatalf("authorization header = %q, want %q", got, "Bearer token-1")
+	}
+
+	second, err := m.token(context.Background(), config)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if second.AccessToken != first.AccessToken {
+		t.Errorf("second token = %q, want cached %q", second.AccessToken, first.AccessToken)
+	}
+	if grants := ts.receivedGrants(); !equalGrants(grants, "client_credentials") {
+		t.Errorf("grants = %v, want one client_credentials grant", grants)
+	}
+}
+
+func TestOAuth2ClientCredentialsRejected(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	m := newOAuth2Manager(resty.New())
+	config := ts.config("client_credentials")
+	config["client_secret"] = "wrong"
+
+	if _, err := m.token(context.Background(), config); err == nil {
+		t.Fatal("expected an error for a rejected client")
+	}
+}
+
+func TestOAuth2RefreshTokenGrant(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	m := newOAuth2Manager(resty.New())
+	config := ts.config("refresh_token")
+	config["refresh_token"] = "refresh-1"
+
+	token, err := m.token(context.Background(), config)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if token.AccessToken != "token-1" || token.RefreshToken != "refresh-1" {
+		t.Errorf("token = %+v", token)
+	}
+
+	config["refresh_token"] = "revoked"
+	if _, err := m.token(context.Background(), config); err == nil {
+		t.Error("expected an error for a revoked refresh token")
+	}
+}
+
+func TestOAuth2RefreshesExpiredToken(t *testing.T) {
+	// Tokens that expire within the skew are never valid, so every call
+	// after the first has to refresh
+	ts := newTokenServer(t, 1)
+	m := newOAuth2Manager(resty.New())
+	config := ts.config("client_credentials")
+
+	first, err := m.token(context.Background(), config)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	second, err := m.token(context.Background(), config)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if second.AccessToken == first.AccessToken {
+		t.Errorf("expired token %q was reused", first.AccessToken)
+	}
+	if grants := ts.receivedGrants(); !equalGrants(grants, "client_credentials", "refresh_token") {
+		t.Errorf("grants = %v, want client_credentials then refresh_token", grants)
+	}
+}
+
+func TestOAuth2ConcurrentRequestsShareOneGrant(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	ts.release = make(chan struct{})
+	m := newOAuth2Manager(resty.New())
+	slow := ts.config("client_credentials")
+	slow["scope"] = "slow"
+
+	var wg sync.WaitGroup
+	tokens := make([]*oauth2Token, 5)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens[i], _ = m.token(context.Background(), slow)
+		}()
+	}
+
+	// Another config is not held up by the slow grant in flight
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.token(context.Background(), ts.config("client_credentials"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("token: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("token for another config waited on the slow grant")
+	}
+
+	close(ts.release)
+	wg.Wait()
+
+	for i, token := range tokens {
+		if token == nil || token.AccessToken != tokens[0].AccessToken {
+			t.Fatalf("token %d = %+v, want the shared token", i, token)
+		}
+	}
+	if grants := ts.receivedGrants(); len(grants) != 2 {
+		t.Errorf("grants = %v, want one per config", grants)
+	}
+}
+
+func TestOAuth2WaitHonoursContext(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	ts.release = make(chan struct{})
+	defer close(ts.release)
+	m := newOAuth2Manager(resty.New())
+	config := ts.config("client_credentials")
+	config["scope"] = "slow"
+
+	go m.token(context.Background(), config)
+	for len(ts.receivedGrants()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := m.token(ctx, config); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestOAuth2TokenRequestStaysOutOfResponse(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	ts.release = make(chan struct{})
+	tokenDelay := 200 * time.Millisecond
+	time.AfterFunc(tokenDelay, func() { close(ts.release) })
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+
+	// Both servers are on 127.0.0.1, so the jar's cookie matches either
+	jar, err := NewCookieJar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiURL, _ := url.Parse(api.URL)
+	jar.SetCookies(apiURL, []*http.Cookie{{Name: "session", Value: "api"}})
+
+	config := DefaultConfig()
+	config.RetryCount = 0
+	config.CookieJar = jar
+	c := NewClient(config)
+
+	auth := ts.config("client_credentials")
+	auth["scope"] = "slow"
+	resp, err := c.Execute(context.Background(), &models.Request{
+		ID:     "r",
+		Method: "GET",
+		URL:    api.URL,
+		Auth:   &models.AuthConfig{Type: "oauth2", Config: auth},
+	}, nil)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if resp.Body != "Bearer token-1" {
+		t.Errorf("body = %q, want the token in the Authorization header", resp.Body)
+	}
+	if len(resp.Redirects) != 0 {
+		t.Errorf("redirects = %+v, want none for the token request", resp.Redirects)
+	}
+	if resp.Timing.ServerProcessing >= tokenDelay {
+		t.Errorf("server processing = %v, want the API server's rather than the token endpoint's", resp.Timing.ServerProcessing)
+	}
+	if resp.Timing.TCPConnection <= 0 {
+		t.Errorf("tcp connection = %v, want the connection to the API server", resp.Timing.TCPConnection)
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if len(ts.cookies) != 0 {
+		t.Errorf("token endpoint received cookies %v, want none from the jar", ts.cookies)
+	}
+}
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"postgirl/internal/models"
+)
+
+// proxyContextKey carries a per-request proxy override on the request context
+type proxyContextKey struct{}
+
+// withProxyConfig overrides the client's proxy settings for a request
+func withProxyConfig(ctx context.Context, proxy *models.ProxyConfig) context.Context {
+	return context.WithValue(ctx, proxyContextKey{}, proxy)
+}
+
+// ValidateProxyConfig checks that proxy settings are usable
+func ValidateProxyConfig(proxy *models.ProxyConfig) error {
+	if proxy == nil {
+		return nil
+	}
+
+	switch proxy.Mode {
+	case "", "system", "none":
+		return nil
+	case "manual":
+		_, err := proxyURL(proxy)
+		return err
+	default:
+		return fmt.Errorf("unsupported proxy mode: %s", proxy.Mode)
+	}
+}
+
+// proxy picks the proxy for a request: the override on its context, then the
+// client's global settings, then the HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables
+func (c *Client) proxy(req *http.Request) (*url.URL, error) {
+	proxy, _ := req.Context().Value(proxyContextKey{}).(*models.ProxyConfig)
+	if proxy == nil {
+		proxy = c.Proxy()
+	}
+
+	if proxy == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+
+	switch proxy.Mode {
+	case "", "system":
+		return http.ProxyFromEnvironment(req)
+	case "none":
+		return nil, nil
+	case "manual":
+		if proxyBypassed(proxy.Bypass, req.URL) {
+			return nil, nil
+		}
+		return proxyURL(proxy)
+	default:
+		return nil, fmt.Errorf("unsupported proxy mode: %s", proxy.Mode)
+	}
+}
+
+// proxyInfo adds the proxy a request went through to its connection info.
+// The proxy resolves the request's host, so host overrides and a DNS server
+// for it are reported as not applied.
+func (c *Client) proxyInfo(info *models.ConnectionInfo, req *http.Request) *models.ConnectionInfo {
+	if req == nil {
+		return info
+	}
+	// A socket is reached directly, never through a proxy
+	target, _ := req.Context().Value(dialTargetContextKey{}).(dialTarget)
+	if target.socket != "" {
+		return info
+	}
+	proxy, err := c.proxy(req)
+	if err != nil || proxy == nil {
+		return info
+	}
+
+	info.Proxy = proxy.Host
+	host := req.URL.Hostname()
+	if _, ok := target.lookupHost(host); ok {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("host override for %s not applied: the proxy resolves it", host))
+	} else if target.dnsServer != "" && net.ParseIP(host) == nil {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("DNS server %s not used for %s: the proxy resolves it", target.dnsServer, host))
+	}
+	return info
+}
+
+// proxyURL builds the proxy URL for manual settings, with credentials when configured
+func proxyURL(proxy *models.ProxyConfig) (*url.URL, error) {
+	address := strings.TrimSpace(proxy.URL)
+	if address == "" {
+		return nil, fmt.Errorf("proxy URL is required")
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL must include a host")
+	}
+
+	if proxy.Username != "" {
+		u.User = url.UserPassword(proxy.Username, proxy.Password)
+	}
+	return u, nil
+}
+
+// proxyBypassed reports whether a request URL matches the bypass list. Entries
+// may be "*", a host or domain (matching its subdomains too), ".domain" or
+// "*.domain" for subdomains only, an IP or CIDR, any of those with a ":port",
+// or "<local>" for plain host names without a dot.
+func proxyBypassed(bypass []string, target *url.URL) bool {
+	hostname := strings.ToLower(target.Hostname())
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" || target.Scheme == "wss" {
+			port = "443"
+		}
+	}
+	ip := net.ParseIP(hostname)
+
+	for _, entry := range bypass {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case entry == "<local>":
+			if ip == nil && !strings.Contains(hostname, ".") {
+				return true
+			}
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort, err := net.SplitHostPort(entry)
+		if err != nil {
+			entryHost, entryPort = strings.Trim(entry, "[]"), ""
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(entryHost, "*."):
+			if strings.HasSuffix(hostname, entryHost[1:]) {
+				return true
+			}
+		case strings.HasPrefix(entryHost, "."):
+			if strings.HasSuffix(hostname, entryHost) {
+				return true
+			}
+		case hostname == entryHost:
+			return true
+		case ip == nil && strings.HasSuffix(hostname, "."+entryHost):
+			return true
+		}
+	}
+	return false
+}
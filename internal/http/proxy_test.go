@@ -0,0 +1,324 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"postgirl/internal/models"
+)
+
+// testProxy is a forward proxy that also tunnels CONNECT requests. It sends
+// every tunnel to 127.0.0.1, whatever host was asked for, and answers plain
+// requests itself.
+type testProxy struct {
+	*httptest.Server
+
+	username, password string
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newTestProxy(t *testing.T, username, password string) *testProxy {
+	t.Helper()
+	p := &testProxy{username: username, password: password}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *testProxy) serve(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.requests = append(p.requests, r.Method+" "+r.RequestURI)
+	p.mu.Unlock()
+
+	if p.username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.username + ":" + p.password))
+		if r.Header.Get("Proxy-Authorization") != "Basic "+credentials {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+	}
+
+	if r.Method != http.MethodConnect {
+		fmt.Fprintf(w, "proxied %s", r.URL)
+		return
+	}
+
+	_, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	upstream, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	conn, buffered, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	fmt.Fprint(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+	go func() {
+		io.Copy(upstream, buffered)
+		upstream.Close()
+	}()
+	io.Copy(conn, upstream)
+	conn.Close()
+}
+
+func (p *testProxy) received() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.requests...)
+}
+
+// newProxyTestClient creates a client with the given proxy settings that
+// trusts the certificate of server, if one is given
+func newProxyTestClient(t *testing.T, proxy *models.ProxyConfig, server *httptest.Server) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.RetryCount = 0
+	config.Proxy = proxy
+
+	if server != nil {
+		certs, err := NewCertificateManager(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ca := &models.CACertificate{
+			Name: "test server",
+			PEM:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+		}
+		if err := certs.SaveCACertificate(ca); err != nil {
+			t.Fatal(err)
+		}
+		config.Certificates = certs
+	}
+	return NewClient(config)
+}
+
+func execute(t *testing.T, c *Client, rawURL string, env *models.Environment) (*models.Response, error) {
+	t.Helper()
+	return c.Execute(context.Background(), &models.Request{ID: "r", Method: "GET", URL: rawURL}, env)
+}
+
+func TestProxyForwardsWithCredentials(t *testing.T) {
+	proxy := newTestProxy(t, "user", "p@ss:word")
+	c := newProxyTestClient(t, &models.ProxyConfig{
+		Mode:     "manual",
+		URL:      proxy.URL,
+		Username: "user",
+		Password: "p@ss:word",
+	}, nil)
+
+	resp, err := execute(t, c, "http://api.example.test/items?page=2", nil)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Body != "proxied http://api.example.test/items?page=2" {
+		t.Errorf("response = %d %q, want it to come from the proxy", resp.StatusCode, resp.Body)
+	}
+}
+
+func TestProxyRejectsWrongCredentials(t *testing.T) {
+	proxy := newTestProxy(t, "user", "secret")
+	c := newProxyTestClient(t, &models.ProxyConfig{
+		Mode:     "manual",
+		URL:      proxy.URL,
+		Username: "user",
+		Password: "wrong",
+	}, nil)
+
+	resp, err := execute(t, c, "http://api.example.test/", nil)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if resp.StatusCode != http.StatusProxyAuthRequired {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusProxyAuthRequired)
+	}
+}
+
+func TestProxyTunnelsHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "secure %s", r.URL.Path)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	proxy := newTestProxy(t, "user", "secret")
+	c := newProxyTestClient(t, &models.ProxyConfig{
+		Mode:     "manual",
+		URL:      proxy.URL,
+		Username: "user",
+		Password: "secret",
+	}, server)
+
+	// The test server's certificate is for example.com
+	resp, err := execute(t, c, "https://example.com:"+port+"/tunnel", nil)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if resp.Body != "secure /tunnel" {
+		t.Errorf("body = %q, want the server's response through the tunnel", resp.Body)
+	}
+	if got := proxy.received(); len(got) != 1 || got[0] != "CONNECT example.com:"+port {
+		t.Errorf("proxy received %v, want one CONNECT", got)
+	}
+
+	// Without credentials the tunnel is refused
+	c = newProxyTestClient(t, &models.ProxyConfig{Mode: "manual", URL: proxy.URL}, server)
+	if _, err := execute(t, c, "https://example.com:"+port+"/tunnel", nil); err == nil {
+		t.Error("expected an error when the proxy refuses the tunnel")
+	}
+}
+
+func TestProxyBypass(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	proxy := newTestProxy(t, "", "")
+	c := newProxyTestClient(t, &models.ProxyConfig{
+		Mode:   "manual",
+		URL:    proxy.URL,
+		Bypass: []string{".internal.test", "10.0.0.0/8"},
+	}, nil)
+	// Host overrides send the bypassed names to the test server
+	env := &models.Environment{Hosts: map[string]string{
+		"db.internal.test":  "127.0.0.1",
+		"api.external.test": "127.0.0.1",
+	}}
+
+	resp, err := execute(t, c, "http://db.internal.test:"+port+"/", env)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if resp.Body != "direct" || resp.Connection.Proxy != "" || len(resp.Connection.Warnings) != 0 {
+		t.Errorf("bypassed host body = %q, connection %+v, want a direct connection with its override applied", resp.Body, resp.Connection)
+	}
+
+	resp, err = execute(t, c, "http://api.external.test:"+port+"/", env)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.HasPrefix(resp.Body, "proxied ") {
+		t.Errorf("other host body = %q, want it to go through the proxy", resp.Body)
+	}
+
+	if got := proxy.received(); len(got) != 1 {
+		t.Errorf("proxy received %v, want only the request that was not bypassed", got)
+	}
+}
+
+func TestProxyEnvironmentOverride(t *testing.T) {
+	global := newTestProxy(t, "", "")
+	override := newTestProxy(t, "", "")
+	c := newProxyTestClient(t, &models.ProxyConfig{Mode: "manual", URL: global.URL}, nil)
+
+	env := &models.Environment{Proxy: &models.ProxyConfig{Mode: "manual", URL: override.URL}}
+	if _, err := execute(t, c, "http://api.example.test/", env); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(global.received()) != 0 || len(override.received()) != 1 {
+		t.Errorf("global proxy received %v and override %v, want only the override used", global.received(), override.received())
+	}
+}
+
+func TestProxyReportsUnappliedOverrides(t *testing.T) {
+	proxy := newTestProxy(t, "", "")
+	c := newProxyTestClient(t, &models.ProxyConfig{Mode: "manual", URL: proxy.URL}, nil)
+	env := &models.Environment{
+		Hosts:     map[string]string{"api.example.test": "127.0.0.1"},
+		DNSServer: "127.0.0.1:5353",
+	}
+	proxyHost := strings.TrimPrefix(proxy.URL, "http://")
+
+	for _, tc := range []struct {
+		url     string
+		warning string
+	}{
+		{"http://api.example.test/", "host override for api.example.test not applied: the proxy resolves it"},
+		{"http://other.example.test/", "DNS server 127.0.0.1:5353 not used for other.example.test: the proxy resolves it"},
+	} {
+		resp, err := execute(t, c, tc.url, env)
+		if err != nil {
+			t.Fatalf("execute %s: %v", tc.url, err)
+		}
+		if resp.Connection.Proxy != proxyHost {
+			t.Errorf("%s: connection proxy = %q, want %q", tc.url, resp.Connection.Proxy, proxyHost)
+		}
+		if len(resp.Connection.Warnings) != 1 || resp.Connection.Warnings[0] != tc.warning {
+			t.Errorf("%s: connection warnings = %q, want %q", tc.url, resp.Connection.Warnings, tc.warning)
+		}
+	}
+}
+
+// TestProxyFromEnvironmentVariables runs itself in a child process, since
+// net/http reads HTTP_PROXY only once per process
+func TestProxyFromEnvironmentVariables(t *testing.T) {
+	if target := os.Getenv("POSTGIRL_PROXY_TEST_URL"); target != "" {
+		for _, proxy := range []*models.ProxyConfig{nil, {Mode: "system"}} {
+			c := newProxyTestClient(t, proxy, nil)
+			resp, err := execute(t, c, target, nil)
+			if err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			if resp.Body != "proxied "+target {
+				t.Errorf("body = %q with settings %+v, want it to come from HTTP_PROXY", resp.Body, proxy)
+			}
+		}
+
+		// NO_PROXY still applies
+		c := newProxyTestClient(t, nil, nil)
+		if _, err := execute(t, c, "http://skip.example.test/", nil); err == nil {
+			t.Error("expected skip.example.test, which does not resolve, to be dialed directly")
+		}
+		return
+	}
+
+	proxy := newTestProxy(t, "", "")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestProxyFromEnvironmentVariables$")
+	cmd.Env = append(filteredProxyEnv(),
+		"POSTGIRL_PROXY_TEST_URL=http://api.example.test/env",
+		"HTTP_PROXY="+proxy.URL,
+		"NO_PROXY=skip.example.test",
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child test failed: %v\n%s", err, output)
+	}
+	if got := proxy.received(); len(got) != 2 {
+		t.Errorf("proxy received %v, want the two requests without proxy settings", got)
+	}
+}
+
+// filteredProxyEnv returns the environment without any proxy variables
+func filteredProxyEnv() []string {
+	var env []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		switch strings.ToUpper(name) {
+		case "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "REQUEST_METHOD":
+			continue
+		}
+		env = append(env, variable)
+	}
+	return env
+}
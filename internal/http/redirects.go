@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"postgirl/internal/models"
+)
+
+// redirectChainContextKey carries the redirect chain being recorded for a request
+type redirectChainContextKey struct{}
+
+// redirectChain collects every round trip made while sending one attempt of a
+// request; all but the last are the redirects that were followed
+type redirectChain struct {
+	mu   sync.Mutex
+	hops []models.RedirectHop
+}
+
+// withRedirectChain records the round trips of a request into chain
+func withRedirectChain(ctx context.Context, chain *redirectChain) context.Context {
+	return context.WithValue(ctx, redirectChainContextKey{}, chain)
+}
+
+// redirects returns the hops that led to the final response
+func (c *redirectChain) redirects() []models.RedirectHop {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.hops) < 2 {
+		return nil
+	}
+	return append([]models.RedirectHop(nil), c.hops[:len(c.hops)-1]...)
+}
+
+// redirectRecorder records each round trip of a request, with its own timing,
+// into the redirect chain on the request context. It sits outside the digest
+// transport so a challenge and its answer count as one hop.
+type redirectRecorder struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip sends the request and records the response it got
+func (t *redirectRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	chain, ok := req.Context().Value(redirectChainContextKey{}).(*redirectChain)
+	if !ok {
+		return t.transport.RoundTrip(req)
+	}
+
+	tracer := newTimingTracer()
+	traced := req.WithContext(httptrace.WithClientTrace(req.Context(), tracer.clientTrace()))
+	resp, err := t.transport.RoundTrip(traced)
+	if err != nil {
+		return nil, err
+	}
+
+	hop := models.RedirectHop{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Location:   resp.Header.Get("Location"),
+		Headers:    headerList(receivedHeader(resp)),
+		Timing:     tracer.timing(time.Now()),
+	}
+	chain.mu.Lock()
+	chain.hops = append(chain.hops, hop)
+	chain.mu.Unlock()
+
+	return resp, nil
+}
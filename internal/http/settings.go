@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"postgirl/internal/models"
+)
+
+// errRedirectLimit ends a request that was redirected more times than allowed
+var errRedirectLimit = errors.New("too many redirects")
+
+// settingsContextKey carries the settings in effect for a request on its context
+type settingsContextKey struct{}
+
+// requestSettings are the client settings in effect for a single request: the
+// client's configuration with any per-request overrides applied
+type requestSettings struct {
+	timeout         time.Duration
+	retryCount      int
+	retryDelay      time.Duration
+	retryOnStatus   []int
+	followRedirects bool
+	maxRedirects    int
+	keepAlive       bool
+	stream          bool
+	reconnect       bool
+	compression     string
+	acceptEncoding  string
+}
+
+// settingsFor resolves the settings for a request from the client's
+// configuration and the request's overrides, which may be nil
+func (c *Client) settingsFor(override *models.RequestSettings) requestSettings {
+	settings := requestSettings{
+		timeout:         c.config.Timeout,
+		retryCount:      c.config.RetryCount,
+		retryDelay:      c.config.RetryDelay,
+		retryOnStatus:   c.config.RetryOnStatus,
+		followRedirects: c.config.FollowRedirects,
+		maxRedirects:    c.config.MaxRedirects,
+		keepAlive:       !c.config.DisableKeepAlives,
+		acceptEncoding:  defaultAcceptEncoding,
+	}
+	if override == nil {
+		return settings
+	}
+
+	if override.Timeout > 0 {
+		settings.timeout = time.Duration(override.Timeout) * time.Millisecond
+	}
+	if override.RetryCount != nil {
+		settings.retryCount = *override.RetryCount
+	}
+	if override.RetryOnStatus != nil {
+		settings.retryOnStatus = override.RetryOnStatus
+	}
+	if override.FollowRedirects != nil {
+		settings.followRedirects = *override.FollowRedirects
+	}
+	if override.MaxRedirects > 0 {
+		settings.maxRedirects = override.MaxRedirects
+	}
+	if override.KeepAlive != nil {
+		settings.keepAlive = *override.KeepAlive
+	}
+	settings.stream = override.Stream
+	settings.reconnect = override.Reconnect
+	settings.compression = override.Compression
+	if override.AcceptEncoding != "" {
+		settings.acceptEncoding = override.AcceptEncoding
+	}
+	return settings
+}
+
+// withRequestSettings attaches the settings in effect for a request
+func withRequestSettings(ctx context.Context, settings requestSettings) context.Context {
+	return context.WithValue(ctx, settingsContextKey{}, settings)
+}
+
+// settingsFromContext returns the settings attached to a request, falling
+// back to the client's configuration
+func (c *Client) settingsFromContext(ctx context.Context) requestSettings {
+	if settings, ok := ctx.Value(settingsContextKey{}).(requestSettings); ok {
+		return settings
+	}
+	return c.settingsFor(nil)
+}
+
+// shouldRetry reports whether an attempt that ended with resp or err should be
+// retried: it failed without being cancelled or hitting the redirect limit, or
+// it returned one of the retry status codes
+func (s requestSettings) shouldRetry(ctx context.Context, resp *resty.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, errRedirectLimit)
+	}
+	return slices.Contains(s.retryOnStatus, resp.StatusCode())
+}
+
+// checkRedirect applies the redirect settings of the request being redirected
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	settings := c.settingsFromContext(req.Context())
+	if !settings.followRedirects {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= settings.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects: %w", settings.maxRedirects, errRedirectLimit)
+	}
+	// Redirected requests are new requests, so carry keep-alive over
+	req.Close = !settings.keepAlive
+	return nil
+}
+
+// prepareRequest adjusts each outgoing attempt for the settings and body
+// carried on its context
+func (c *Client) prepareRequest(client *resty.Client, req *http.Request) error {
+	settings := c.settingsFromContext(req.Context())
+	if !settings.keepAlive {
+		req.Close = true
+	}
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", settings.acceptEncoding)
+	}
+	if err := attachBodyFile(client, req); err != nil {
+		return err
+	}
+	return compressRequestBody(req, settings.compression)
+}
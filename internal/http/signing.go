@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// requestSigner signs an outgoing request once its final URL, headers and
+// body are known, which resty only settles just before the round trip
+type requestSigner interface {
+	sign(req *http.Request) error
+}
+
+// responseVerifier is implemented by signers that also authenticate the
+// server's response
+type responseVerifier interface {
+	verify(req *http.Request, resp *http.Response) error
+}
+
+// signerContextKey carries a requestSigner on a request context
+type signerContextKey struct{}
+
+// withRequestSigner attaches a signer to a request context
+func withRequestSigner(ctx context.Context, signer requestSigner) context.Context {
+	return context.WithValue(ctx, signerContextKey{}, signer)
+}
+
+// signingTransport applies the signer carried on the request context
+type signingTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip signs the request, sends it and verifies the response if asked to
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	signer, ok := req.Context().Value(signerContextKey{}).(requestSigner)
+	if !ok || !sameHostAsOriginal(req) {
+		// Like Authorization on a redirect, a signature is never sent to
+		// another host
+		return t.transport.RoundTrip(req)
+	}
+
+	signed := req.Clone(req.Context())
+	if err := signer.sign(signed); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.transport.RoundTrip(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	if verifier, ok := signer.(responseVerifier); ok {
+		if err := verifier.verify(signed, resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// sameHostAsOriginal reports whether a request goes to the host of the
+// request that started its redirect chain
+func sameHostAsOriginal(req *http.Request) bool {
+	original := req
+	for original.Response != nil && original.Response.Request != nil {
+		original = original.Response.Request
+	}
+	return strings.EqualFold(original.URL.Host, req.URL.Host)
+}
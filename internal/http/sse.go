@@ -0,0 +1,312 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"postgirl/internal/models"
+)
+
+// maxEventLine bounds a single line of an event stream
+const maxEventLine = 16 << 20
+
+// EventHandler receives the events of a streamed response as they arrive
+type EventHandler func(models.ServerSentEvent)
+
+// eventHandlerContextKey carries the handler for a request's streamed events
+type eventHandlerContextKey struct{}
+
+// WithEventHandler delivers the events of a streamed text/event-stream
+// response to handler as they arrive. Execute still returns the whole
+// transcript once the stream ends.
+func WithEventHandler(ctx context.Context, handler EventHandler) context.Context {
+	return context.WithValue(ctx, eventHandlerContextKey{}, handler)
+}
+
+// streams reports whether a request should be read as an event stream: its
+// settings ask for it or it only accepts text/event-stream
+func streams(req *models.Request, settings requestSettings) bool {
+	if settings.stream {
+		return true
+	}
+	return isEventStream(headerValue(req.Headers, "Accept"))
+}
+
+// headerValue returns the value of the first enabled header with the given
+// name, ignoring case
+func headerValue(headers models.KeyValues, name string) string {
+	for _, header := range headers.Enabled() {
+		if strings.EqualFold(header.Key, name) {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+// isEventStream reports whether a content type is text/event-stream
+func isEventStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// stream executes a request whose response is read as an event stream. Events
+// are passed to the context's handler as they arrive and collected into the
+// response, whose body is the raw stream. When the settings ask for it, a
+// stream the server closes is reopened with Last-Event-ID after the retry delay.
+// A response that is not an event stream is read whole, like Execute does.
+func (c *Client) stream(ctx context.Context, req *models.Request, env *models.Environment, settings requestSettings) (*models.Response, error) {
+	handler, _ := ctx.Value(eventHandlerContextKey{}).(EventHandler)
+
+	var (
+		first       *resty.Response
+		firstTracer *timingTracer
+		firstChain  *redirectChain
+		transcript  bytes.Buffer
+		events      []models.ServerSentEvent
+		streamErr   error
+		failures    int
+	)
+	parser := &eventParser{retry: settings.retryDelay}
+	parser.lastID = headerValue(req.Headers, "Last-Event-ID")
+
+	for {
+		tracer := newTimingTracer()
+		chain := &redirectChain{}
+		attemptCtx := withRedirectChain(httptrace.WithClientTrace(ctx, tracer.clientTrace()), chain)
+		r, cleanup, err := c.newRequest(attemptCtx, req, env)
+		if err != nil {
+			return nil, err
+		}
+		if r.Header.Get("Accept") == "" {
+			r.SetHeader("Accept", "text/event-stream")
+		}
+		if parser.lastID != "" {
+			r.SetHeader("Last-Event-ID", parser.lastID)
+		}
+		r.SetDoNotParseResponse(true)
+
+		resp, release, err := c.open(r, settings)
+		cleanup()
+		if err != nil {
+			// Retry failed connections like Execute does, but keep what an
+			// earlier connection received if it cannot be reopened
+			if failures < settings.retryCount && settings.shouldRetry(ctx, nil, err) {
+				failures++
+				if wait(ctx, parser.retry) {
+					continue
+				}
+			}
+			if first == nil {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+			streamErr = err
+			break
+		}
+
+		body := resp.RawBody()
+		if first == nil && !isEventStream(resp.Header().Get("Content-Type")) {
+			// Not a stream after all
+			raw, err := io.ReadAll(body)
+			body.Close()
+			release()
+			if err != nil {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+			return c.newResponse(req, resp.SetBody(raw), tracer, chain), nil
+		}
+		if first == nil {
+			first, firstTracer, firstChain = resp, tracer, chain
+		} else if resp.StatusCode() != http.StatusOK || !isEventStream(resp.Header().Get("Content-Type")) {
+			// The server ended the stream for good, with 204 No Content or otherwise
+			body.Close()
+			release()
+			if resp.StatusCode() != http.StatusNoContent {
+				streamErr = fmt.Errorf("reconnect failed with status %d", resp.StatusCode())
+			}
+			break
+		}
+		failures = 0
+
+		err = parser.read(io.TeeReader(body, &transcript), func(event models.ServerSentEvent) {
+			events = append(events, event)
+			if handler != nil {
+				handler(event)
+			}
+		})
+		body.Close()
+		release()
+		if ctx.Err() != nil {
+			streamErr = fmt.Errorf("stream closed: %w", ctx.Err())
+			break
+		}
+		if err != nil {
+			streamErr = err
+		}
+		if !settings.reconnect || !wait(ctx, parser.retry) {
+			break
+		}
+		streamErr = nil
+	}
+
+	response := c.newResponse(req, first.SetBody(transcript.Bytes()), firstTracer, firstChain)
+	response.Events = events
+	if streamErr != nil {
+		response.Error = streamErr.Error()
+	}
+	return response, nil
+}
+
+// open sends one connection attempt of a streamed request. The attempt
+// timeout only bounds waiting for the response headers; the stream itself may
+// stay open for as long as the server keeps it. Call release once the body has
+// been read.
+func (c *Client) open(r *resty.Request, settings requestSettings) (*resty.Response, func(), error) {
+	ctx, cancel := context.WithCancel(r.Context())
+	r.SetContext(ctx)
+
+	timedOut := func() bool { return false }
+	if settings.timeout > 0 {
+		timer := time.AfterFunc(settings.timeout, cancel)
+		timedOut = func() bool { return !timer.Stop() }
+	}
+	resp, err := r.Send()
+	if timedOut() {
+		if err == nil {
+			resp.RawBody().Close()
+		}
+		cancel()
+		return nil, nil, fmt.Errorf("no response within %s: %w", settings.timeout, context.DeadlineExceeded)
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return resp, cancel, nil
+}
+
+// wait waits for delay, reporting false if ctx is done first
+func wait(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// eventParser parses the text/event-stream format. The last event ID and
+// retry delay carry over between connections.
+type eventParser struct {
+	lastID string
+	retry  time.Duration
+
+	data     strings.Builder
+	hasData  bool
+	event    string
+	retrySet int
+}
+
+// read parses events from a stream until it ends, passing each to dispatch.
+// A final event without a terminating blank line is discarded.
+func (p *eventParser) read(r io.Reader, dispatch func(models.ServerSentEvent)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventLine)
+	scanner.Split(scanEventLines)
+	for scanner.Scan() {
+		if event, ok := p.line(scanner.Text()); ok {
+			dispatch(event)
+		}
+	}
+	p.reset()
+	return scanner.Err()
+}
+
+// line processes one line of the stream, returning an event when the line
+// completes one
+func (p *eventParser) line(line string) (models.ServerSentEvent, bool) {
+	if line == "" {
+		defer p.reset()
+		if !p.hasData {
+			return models.ServerSentEvent{}, false
+		}
+		return models.ServerSentEvent{
+			ID:         p.lastID,
+			Event:      p.event,
+			Data:       strings.TrimSuffix(p.data.String(), "\n"),
+			Retry:      p.retrySet,
+			ReceivedAt: time.Now(),
+		}, true
+	}
+	if strings.HasPrefix(line, ":") {
+		// Comment, often sent as a keep-alive
+		return models.ServerSentEvent{}, false
+	}
+
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "data":
+		p.data.WriteString(value)
+		p.data.WriteByte('\n')
+		p.hasData = true
+	case "event":
+		p.event = value
+	case "id":
+		if !strings.ContainsRune(value, 0) {
+			p.lastID = value
+		}
+	case "retry":
+		if ms, err := strconv.Atoi(value); err == nil && ms >= 0 && strings.Trim(value, "0123456789") == "" {
+			p.retry = time.Duration(ms) * time.Millisecond
+			p.retrySet = ms
+		}
+	}
+	return models.ServerSentEvent{}, false
+}
+
+// reset discards the event being built
+func (p *eventParser) reset() {
+	p.data.Reset()
+	p.hasData = false
+	p.event = ""
+	p.retrySet = 0
+}
+
+// scanEventLines splits a stream into lines ended by CRLF, LF or CR
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// A CR may be followed by an LF that has not arrived yet
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
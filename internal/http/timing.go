@@ -0,0 +1,127 @@
+package http
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"postgirl/internal/models"
+)
+
+// timingTracer records the phases of a request using net/http/httptrace.
+// Each attempt has a tracer of its own.
+type timingTracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	remoteAddr   net.Addr
+	reused       bool
+}
+
+// newTimingTracer creates a tracer whose total time starts now
+func newTimingTracer() *timingTracer {
+	return &timingTracer{start: time.Now()}
+}
+
+// clientTrace returns the httptrace hooks that feed the tracer
+func (t *timingTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			// Each hop of a redirect chain gets a connection of its own, so
+			// the phases are those of the final hop; the redirect chain keeps
+			// each earlier hop's timing. Total still spans the whole chain.
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+			t.connectStart, t.connectDone = time.Time{}, time.Time{}
+			t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+			t.wroteRequest, t.firstByte = time.Time{}, time.Time{}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.remoteAddr = info.Conn.RemoteAddr()
+			t.reused = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mark(&t.dnsDone)
+		},
+		ConnectStart: func(network, addr string) {
+			// Dual-stack dialing may start several connects; keep the first
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.mark(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mark(&t.tlsDone)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mark(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.mark(&t.firstByte)
+		},
+	}
+}
+
+// mark stores the current time in the given field
+func (t *timingTracer) mark(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*field = time.Now()
+}
+
+// timing converts the recorded timestamps into a ResponseTiming, where done
+// is the moment the response body was fully read
+func (t *timingTracer) timing(done time.Time) models.ResponseTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return models.ResponseTiming{
+		DNSLookup:        between(t.dnsStart, t.dnsDone),
+		TCPConnection:    between(t.connectStart, t.connectDone),
+		TLSHandshake:     between(t.tlsStart, t.tlsDone),
+		ServerProcessing: between(t.wroteRequest, t.firstByte),
+		ContentTransfer:  between(t.firstByte, done),
+		Total:            done.Sub(t.start),
+	}
+}
+
+// connection returns the remote address of the connection the last attempt
+// used and whether it was reused from the pool
+func (t *timingTracer) connection() (net.Addr, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.remoteAddr, t.reused
+}
+
+// between returns the duration from start to end, or zero if either phase
+// did not happen (e.g. DNS and TCP on a reused connection)
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
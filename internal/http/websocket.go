@@ -0,0 +1,303 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"postgirl/internal/models"
+)
+
+// webSocketWriteTimeout bounds sending a single message or control frame
+const webSocketWriteTimeout = 10 * time.Second
+
+// WebSocketHandler receives the messages of a WebSocket session as they are
+// sent and received
+type WebSocketHandler func(models.WebSocketMessage)
+
+// WebSocketConn is an open WebSocket connection. Every message sent and
+// received, including pings, pongs and the close, is passed to its handler.
+type WebSocketConn struct {
+	conn       *websocket.Conn
+	handler    WebSocketHandler
+	statusCode int
+	headers    models.KeyValues
+	writeMu    sync.Mutex
+	pingMu     sync.Mutex
+	pings      map[string]time.Time // outstanding pings by payload
+	closing    atomic.Bool
+	done       chan struct{}
+	err        error
+}
+
+// DialWebSocket opens a WebSocket connection for a request, sending its
+// enabled headers, query parameters, authentication and subprotocols with the
+// handshake. ctx and the request's timeout bound the handshake only; the
+// connection stays open until Close or until the server closes it.
+func (c *Client) DialWebSocket(ctx context.Context, req *models.Request, env *models.Environment, handler WebSocketHandler) (*WebSocketConn, error) {
+	settings := c.settingsFor(req.Settings)
+	if env != nil && env.Proxy != nil {
+		ctx = withProxyConfig(ctx, env.Proxy)
+	}
+
+	header := http.Header{}
+	for _, h := range req.Headers.Enabled() {
+		header.Add(h.Key, h.Value)
+	}
+	if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", c.config.UserAgent)
+	}
+	if req.Auth != nil {
+		if err := staticAuth(header, req.Auth, "WebSocket"); err != nil {
+			return nil, fmt.Errorf("failed to apply authentication: %w", err)
+		}
+	}
+
+	dialer := &websocket.Dialer{
+		Proxy:            c.proxy,
+		HandshakeTimeout: settings.timeout,
+	}
+	if c.config.CookieJar != nil {
+		dialer.Jar = c.config.CookieJar
+	}
+	if target := dialTargetFor(env); !target.isDefault() {
+		dialer.NetDialContext = target.dialContext(newDialer())
+	}
+	if req.WebSocket != nil {
+		dialer.Subprotocols = req.WebSocket.Subprotocols
+	}
+
+	target := appendQuery(req.URL, req.QueryParams.Enabled())
+	if c.config.Certificates != nil {
+		if u, err := url.Parse(target); err == nil && u.Scheme == "wss" {
+			_, dialer.TLSClientConfig, _ = c.config.Certificates.tlsConfig(u.Host, "443")
+		}
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, target, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("handshake failed with status %d: %w", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	w := &WebSocketConn{
+		conn:       conn,
+		handler:    handler,
+		statusCode: resp.StatusCode,
+		headers:    headerList(resp.Header),
+		pings:      make(map[string]time.Time),
+		done:       make(chan struct{}),
+	}
+	conn.SetPingHandler(w.handlePing)
+	conn.SetPongHandler(w.handlePong)
+	go w.read()
+	return w, nil
+}
+
+// staticAuth adds the headers for authentication types that don't depend on
+// the request body or a server challenge, for transports such as WebSocket
+// handshakes and gRPC metadata that can't use the others
+func staticAuth(header http.Header, auth *models.AuthConfig, transport string) error {
+	switch auth.Type {
+	case "basic":
+		username, ok := auth.Config["username"]
+		if !ok {
+			return fmt.Errorf("username required for basic auth")
+		}
+		credentials := username + ":" + auth.Config["password"]
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case "bearer":
+		token, ok := auth.Config["token"]
+		if !ok {
+			return fmt.Errorf("token required for bearer auth")
+		}
+		header.Set("Authorization", "Bearer "+token)
+	case "api_key":
+		value, ok := auth.Config["value"]
+		if !ok {
+			return fmt.Errorf("value required for API key auth")
+		}
+		name, ok := auth.Config["header"]
+		if !ok {
+			name = "X-API-Key"
+		}
+		header.Set(name, value)
+	default:
+		return fmt.Errorf("authentication type %s is not supported for %s", auth.Type, transport)
+	}
+	return nil
+}
+
+// Subprotocol returns the subprotocol the server selected, if any
+func (w *WebSocketConn) Subprotocol() string {
+	return w.conn.Subprotocol()
+}
+
+// StatusCode returns the status code of the handshake response
+func (w *WebSocketConn) StatusCode() int {
+	return w.statusCode
+}
+
+// Headers returns the headers of the handshake response
+func (w *WebSocketConn) Headers() models.KeyValues {
+	return w.headers
+}
+
+// Done is closed once the connection has closed; Err then says why
+func (w *WebSocketConn) Done() <-chan struct{} {
+	return w.done
+}
+
+// Err returns why the connection closed, or nil for a normal closure
+func (w *WebSocketConn) Err() error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+		return nil
+	}
+}
+
+// SendText sends a text message
+func (w *WebSocketConn) SendText(text string) error {
+	if err := w.write(websocket.TextMessage, []byte(text)); err != nil {
+		return err
+	}
+	w.record("sent", "text", text, 0)
+	return nil
+}
+
+// Ping sends a ping; the answering pong is logged with its round trip time
+func (w *WebSocketConn) Ping() error {
+	payload := strconv.FormatInt(time.Now().UnixNano(), 10)
+	w.pingMu.Lock()
+	w.pings[payload] = time.Now()
+	w.pingMu.Unlock()
+
+	if err := w.writeControl(websocket.PingMessage, []byte(payload)); err != nil {
+		return err
+	}
+	w.record("sent", "ping", payload, 0)
+	return nil
+}
+
+// Close closes the connection with a normal closure and waits for it to end
+func (w *WebSocketConn) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+	}
+	w.closing.Store(true)
+
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := w.writeControl(websocket.CloseMessage, message); err == nil {
+		w.record("sent", "close", strconv.Itoa(websocket.CloseNormalClosure), 0)
+	}
+
+	// Give the server a moment to answer the close before dropping the connection
+	select {
+	case <-w.done:
+	case <-time.After(time.Second):
+		w.conn.Close()
+		<-w.done
+	}
+	return nil
+}
+
+// read receives messages until the connection closes
+func (w *WebSocketConn) read() {
+	defer close(w.done)
+	defer w.conn.Close()
+
+	for {
+		messageType, data, err := w.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				text := strconv.Itoa(closeErr.Code)
+				if closeErr.Text != "" {
+					text += " " + closeErr.Text
+				}
+				w.record("received", "close", text, 0)
+				if closeErr.Code != websocket.CloseNormalClosure && closeErr.Code != websocket.CloseGoingAway {
+					w.err = closeErr
+				}
+				return
+			}
+			if !w.closing.Load() {
+				w.err = err
+			}
+			return
+		}
+
+		switch messageType {
+		case websocket.TextMessage:
+			w.record("received", "text", string(data), 0)
+		case websocket.BinaryMessage:
+			w.record("received", "binary", base64.StdEncoding.EncodeToString(data), 0)
+		}
+	}
+}
+
+// handlePing logs a ping from the server and answers it
+func (w *WebSocketConn) handlePing(payload string) error {
+	w.record("received", "ping", payload, 0)
+	err := w.writeControl(websocket.PongMessage, []byte(payload))
+	if err == nil {
+		w.record("sent", "pong", payload, 0)
+	}
+	return err
+}
+
+// handlePong logs a pong, with the round trip time of the ping it answers
+func (w *WebSocketConn) handlePong(payload string) error {
+	w.pingMu.Lock()
+	sent, ok := w.pings[payload]
+	delete(w.pings, payload)
+	w.pingMu.Unlock()
+
+	var rtt time.Duration
+	if ok {
+		rtt = time.Since(sent)
+	}
+	w.record("received", "pong", payload, rtt)
+	return nil
+}
+
+// write sends a data message
+func (w *WebSocketConn) write(messageType int, data []byte) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	w.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+	return w.conn.WriteMessage(messageType, data)
+}
+
+// writeControl sends a control frame
+func (w *WebSocketConn) writeControl(messageType int, data []byte) error {
+	return w.conn.WriteControl(messageType, data, time.Now().Add(webSocketWriteTimeout))
+}
+
+// record passes a message to the handler
+func (w *WebSocketConn) record(direction, messageType, data string, rtt time.Duration) {
+	if w.handler == nil {
+		return
+	}
+	w.handler(models.WebSocketMessage{
+		Direction: direction,
+		Type:      messageType,
+		Data:      data,
+		RTT:       rtt,
+		Time:      time.Now(),
+	})
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// Certificate holds the client certificate and TLS settings used for
+// requests to hosts matching Host. The private key, PKCS#12 bundle and
+// passphrase are stored as they are, unencrypted; clients are shown a
+// CertificateSummary instead.
+type Certificate struct {
+	ID                 string    `json:"id"`
+	Host               string    `json:"host"`       // api.example.com, *.example.com, host:8443, or * for all hosts
+	CertPEM            string    `json:"cert_pem"`   // PEM client certificate chain
+	KeyPEM             string    `json:"key_pem"`    // PEM private key
+	PFX                string    `json:"pfx"`        // base64 PKCS#12 bundle, used instead of CertPEM/KeyPEM
+	Passphrase         string    `json:"passphrase"` // PKCS#12 or encrypted PEM key passphrase
+	InsecureSkipVerify bool      `json:"insecure_skip_verify"`
+	MinTLSVersion      string    `json:"min_tls_version"` // 1.0, 1.1, 1.2, 1.3
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// CertificateSummary is a Certificate without its secrets, saying only
+// whether each is set
+type CertificateSummary struct {
+	ID                 string    `json:"id"`
+	Host               string    `json:"host"`
+	CertPEM            string    `json:"cert_pem"`
+	HasKey             bool      `json:"has_key"`
+	HasPFX             bool      `json:"has_pfx"`
+	HasPassphrase      bool      `json:"has_passphrase"`
+	InsecureSkipVerify bool      `json:"insecure_skip_verify"`
+	MinTLSVersion      string    `json:"min_tls_version"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// Summary returns the certificate without its secrets
+func (c *Certificate) Summary() CertificateSummary {
+	return CertificateSummary{
+		ID:                 c.ID,
+		Host:               c.Host,
+		CertPEM:            c.CertPEM,
+		HasKey:             c.KeyPEM != "",
+		HasPFX:             c.PFX != "",
+		HasPassphrase:      c.Passphrase != "",
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinTLSVersion:      c.MinTLSVersion,
+		CreatedAt:          c.CreatedAt,
+		UpdatedAt:          c.UpdatedAt,
+	}
+}
+
+// CACertificate is an extra PEM bundle of certificate authorities trusted
+// in addition to the system roots
+type CACertificate struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	PEM       string    `json:"pem"`
+	CreatedAt time.Time `json:"created_at"`
+}
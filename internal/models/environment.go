@@ -1,20 +1,25 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Environment represents an environment with variables
 type Environment struct {
-	ID        string            `json:"id"`
-	Name      string            `json:"name"`
-	Variables map[string]string `json:"variables"`
-	IsActive  bool              `json:"is_active"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Variables EnvironmentVariables `json:"variables"`
+	IsActive  bool                 `json:"is_active"`
+	Proxy     *ProxyConfig         `json:"proxy,omitempty"` // overrides the global proxy settings
+	Hosts     map[string]string    `json:"hosts,omitempty"`      // host name to IP address, like /etc/hosts
+	DNSServer string               `json:"dns_server,omitempty"` // IP address with an optional port, for the other names
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
 }
 
-// EnvironmentVariable represents a single environment variable
+// EnvironmentVariable represents a single environment variable. Disabled
+// variables are kept but not substituted.
 type EnvironmentVariable struct {
 	Key         string `json:"key"`
 	Value       string `json:"value"`
@@ -22,6 +27,52 @@ type EnvironmentVariable struct {
 	Enabled     bool   `json:"enabled"`
 }
 
+// UnmarshalJSON treats variables without an "enabled" field as enabled
+func (v *EnvironmentVariable) UnmarshalJSON(data []byte) error {
+	type plain EnvironmentVariable
+	variable := plain{Enabled: true}
+	if err := json.Unmarshal(data, &variable); err != nil {
+		return err
+	}
+	*v = EnvironmentVariable(variable)
+	return nil
+}
+
+// EnvironmentVariables is the ordered list of an environment's variables
+type EnvironmentVariables []EnvironmentVariable
+
+// Set updates the first variable with the given key, or appends an enabled one
+func (vars *EnvironmentVariables) Set(key, value string) {
+	for i := range *vars {
+		if (*vars)[i].Key == key {
+			(*vars)[i].Value = value
+			return
+		}
+	}
+	*vars = append(*vars, EnvironmentVariable{Key: key, Value: value, Enabled: true})
+}
+
+// Get returns the value of the first enabled variable with the given key
+func (vars EnvironmentVariables) Get(key string) (string, bool) {
+	for _, v := range vars {
+		if v.Enabled && v.Key == key {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+// Map returns the enabled variables as a map
+func (vars EnvironmentVariables) Map() map[string]string {
+	m := make(map[string]string, len(vars))
+	for _, v := range vars {
+		if _, exists := m[v.Key]; v.Enabled && !exists {
+			m[v.Key] = v.Value
+		}
+	}
+	return m
+}
+
 // EnvironmentTemplate represents a template for creating environments
 type EnvironmentTemplate struct {
 	Name        string                 `json:"name"`
@@ -0,0 +1,47 @@
+package models
+
+// Test result statuses
+const (
+	TestPassed  = "passed"
+	TestFailed  = "failed"  // an assertion did not hold
+	TestError   = "error"   // the test threw something other than an assertion
+	TestSkipped = "skipped" // declared with pm.test.skip
+)
+
+// Execution is a run of a request: the request as it was sent, its response,
+// and what the request's scripts and tests made of them. It is kept in
+// history under the ID of its response.
+type Execution struct {
+	ID           string         `json:"id"`
+	Request      *Request       `json:"request"` // with variables substituted and the pre-request script applied
+	Response     *Response      `json:"response"`
+	TestResults  []TestResult   `json:"test_results"`
+	Console      []ConsoleEntry `json:"console"`
+	ScriptErrors []ScriptError  `json:"script_errors"`
+	// Warnings are problems that did not fail the run, such as not being
+	// able to save it to history
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// TestResult is the outcome of one pm.test, or of a test script that
+// declares none
+type TestResult struct {
+	Test    string `json:"test"` // the request test whose script ran it
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ConsoleEntry is a line a script logged with console
+type ConsoleEntry struct {
+	Script  string `json:"script"` // pre-request, post-response or the test's name
+	Level   string `json:"level"`  // log, info, warn, error or debug
+	Message string `json:"message"`
+}
+
+// ScriptError is an error that stopped a script without failing the run, as
+// with the post-response script; a failing pre-request script fails the run
+type ScriptError struct {
+	Script  string `json:"script"`
+	Message string `json:"message"`
+}
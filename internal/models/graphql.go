@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+)
+
+// GraphQLSchema is the schema of a GraphQL endpoint, as returned by
+// introspection
+type GraphQLSchema struct {
+	Endpoint         string             `json:"endpoint"`
+	QueryType        string             `json:"query_type"`
+	MutationType     string             `json:"mutation_type,omitempty"`
+	SubscriptionType string             `json:"subscription_type,omitempty"`
+	Types            []GraphQLType      `json:"types"`
+	Directives       []GraphQLDirective `json:"directives,omitempty"`
+	FetchedAt        time.Time          `json:"fetched_at"`
+}
+
+// GraphQLType is a named type of a schema
+type GraphQLType struct {
+	Kind          string              `json:"kind"` // SCALAR, OBJECT, INTERFACE, UNION, ENUM, INPUT_OBJECT
+	Name          string              `json:"name"`
+	Description   string              `json:"description,omitempty"`
+	Fields        []GraphQLField      `json:"fields,omitempty"`       // object, interface
+	InputFields   []GraphQLInputValue `json:"input_fields,omitempty"` // input object
+	Interfaces    []string            `json:"interfaces,omitempty"`
+	EnumValues    []GraphQLEnumValue  `json:"enum_values,omitempty"`
+	PossibleTypes []string            `json:"possible_types,omitempty"` // interface, union
+}
+
+// GraphQLField is a field of an object or interface type
+type GraphQLField struct {
+	Name              string              `json:"name"`
+	Description       string              `json:"description,omitempty"`
+	Args              []GraphQLInputValue `json:"args,omitempty"`
+	Type              GraphQLTypeRef      `json:"type"`
+	IsDeprecated      bool                `json:"is_deprecated,omitempty"`
+	DeprecationReason string              `json:"deprecation_reason,omitempty"`
+}
+
+// GraphQLInputValue is an argument or an input object field
+type GraphQLInputValue struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description,omitempty"`
+	Type         GraphQLTypeRef `json:"type"`
+	DefaultValue *string        `json:"default_value,omitempty"`
+}
+
+// GraphQLEnumValue is a value of an enum type
+type GraphQLEnumValue struct {
+	Name              string `json:"name"`
+	Description       string `json:"description,omitempty"`
+	IsDeprecated      bool   `json:"is_deprecated,omitempty"`
+	DeprecationReason string `json:"deprecation_reason,omitempty"`
+}
+
+// GraphQLDirective is a directive the schema supports
+type GraphQLDirective struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Locations   []string            `json:"locations,omitempty"`
+	Args        []GraphQLInputValue `json:"args,omitempty"`
+}
+
+// GraphQLTypeRef refers to a type, wrapped in NON_NULL and LIST as needed
+type GraphQLTypeRef struct {
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name,omitempty"`
+	OfType *GraphQLTypeRef `json:"of_type,omitempty"`
+}
+
+// NamedType returns the name of the type inside any NON_NULL and LIST wrappers
+func (t GraphQLTypeRef) NamedType() string {
+	for t.OfType != nil {
+		t = *t.OfType
+	}
+	return t.Name
+}
+
+// String renders the reference in GraphQL syntax, such as [User!]!
+func (t GraphQLTypeRef) String() string {
+	switch {
+	case t.Kind == "NON_NULL" && t.OfType != nil:
+		return t.OfType.String() + "!"
+	case t.Kind == "LIST" && t.OfType != nil:
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+// Type returns a named type of the schema
+func (s *GraphQLSchema) Type(name string) (*GraphQLType, bool) {
+	for i := range s.Types {
+		if s.Types[i].Name == name {
+			return &s.Types[i], true
+		}
+	}
+	return nil, false
+}
+
+// Field returns a field of an object or interface type
+func (t *GraphQLType) Field(name string) (*GraphQLField, bool) {
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// GRPCConfig holds the gRPC-specific parts of a request. The request's URL
+// names the server and method, as grpc://host:port/package.Service/Method
+// (grpcs:// for TLS); its body holds the request message as JSON, or a JSON
+// array of messages for client-streaming and bidirectional methods; its
+// headers are sent as metadata.
+type GRPCConfig struct {
+	// ProtoFiles lists .proto files defining the service; without them the
+	// service is described by server reflection
+	ProtoFiles []string `json:"proto_files,omitempty"`
+	// ImportPaths are searched for the files the proto files import. The
+	// directories of the proto files are used when none are given.
+	ImportPaths []string `json:"import_paths,omitempty"`
+}
+
+// GRPCService describes a gRPC service and its methods
+type GRPCService struct {
+	Name    string       `json:"name"`
+	Methods []GRPCMethod `json:"methods"`
+}
+
+// GRPCMethod describes a method of a gRPC service
+type GRPCMethod struct {
+	Name            string `json:"name"`
+	FullName        string `json:"full_name"` // package.Service/Method
+	ClientStreaming bool   `json:"client_streaming"`
+	ServerStreaming bool   `json:"server_streaming"`
+	InputType       string `json:"input_type"`
+	OutputType      string `json:"output_type"`
+	// InputTemplate is the request message with every field at its default, as JSON
+	InputTemplate string `json:"input_template"`
+}
+
+// GRPCResult is the outcome of a gRPC call. The response's headers hold the
+// header metadata and its body the received message, or a JSON array of them
+// for server-streaming and bidirectional methods.
+type GRPCResult struct {
+	Method   string        `json:"method"`
+	Code     int           `json:"code"`
+	Status   string        `json:"status"` // the code's name, e.g. NOT_FOUND
+	Message  string        `json:"message,omitempty"`
+	Details  []string      `json:"details,omitempty"` // status details, as JSON
+	Trailers KeyValues     `json:"trailers"`
+	Messages []GRPCMessage `json:"messages"`
+}
+
+// GRPCMessage is a message sent or received during a gRPC call
+type GRPCMessage struct {
+	Direction string    `json:"direction"` // sent, received
+	Data      string    `json:"data"`      // protobuf JSON
+	Time      time.Time `json:"time"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// ProxyConfig represents proxy settings, applied globally or as an
+// environment override
+type ProxyConfig struct {
+	Mode     string   `json:"mode"` // system (HTTP_PROXY/HTTPS_PROXY/NO_PROXY), none, manual
+	URL      string   `json:"url"`  // http://, https:// or socks5:// proxy address
+	Username string   `json:"username"`
+	Password string   `json:"password,omitempty"`
+	Bypass   []string `json:"bypass"` // hosts, domains, IPs, CIDRs or <local> that skip the proxy
+	// HasPassword stands in for the password in settings sent to clients
+	HasPassword bool `json:"has_password,omitempty"`
+}
+
+// WithoutPassword returns a copy of the settings to send to clients, saying
+// only whether a password is set
+func (p *ProxyConfig) WithoutPassword() *ProxyConfig {
+	if p == nil {
+		return nil
+	}
+	proxy := *p
+	proxy.HasPassword = p.Password != ""
+	proxy.Password = ""
+	return &proxy
+}
+
+// KeepPassword fills in a password left empty from the settings being
+// replaced, since clients are never sent it, as long as the username is the same
+func (p *ProxyConfig) KeepPassword(stored *ProxyConfig) {
+	if p == nil {
+		return
+	}
+	p.HasPassword = false
+	if stored != nil && p.Password == "" && p.Username != "" && p.Username == stored.Username {
+		p.Password = stored.Password
+	}
+}
@@ -1,38 +1,141 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
 // Request represents an HTTP request
 type Request struct {
-	ID            string            `json:"id"`
-	Name          string            `json:"name"`
-	Method        string            `json:"method"`
-	URL           string            `json:"url"`
-	Headers       map[string]string `json:"headers"`
-	QueryParams   map[string]string `json:"query_params"`
-	Body          *RequestBody      `json:"body"`
-	Auth          *AuthConfig       `json:"auth"`
-	PreScript     string            `json:"pre_script"`
-	PostScript    string            `json:"post_script"`
-	Tests         []Test            `json:"tests"`
-	CollectionID  string            `json:"collection_id"`
-	FolderID      string            `json:"folder_id"`
-	EnvironmentID string            `json:"environment_id"`
-	CreatedAt     time.Time         `json:"created_at"`
-	UpdatedAt     time.Time         `json:"updated_at"`
+	ID            string           `json:"id"`
+	Name          string           `json:"name"`
+	Type          string           `json:"type,omitempty"` // http (the default), websocket or grpc
+	Method        string           `json:"method"`
+	URL           string           `json:"url"`
+	Headers       KeyValues        `json:"headers"`
+	QueryParams   KeyValues        `json:"query_params"`
+	Body          *RequestBody     `json:"body"`
+	Auth          *AuthConfig      `json:"auth"`
+	PreScript     string           `json:"pre_script"`
+	PostScript    string           `json:"post_script"`
+	Tests         []Test           `json:"tests"`
+	Settings      *RequestSettings `json:"settings,omitempty"`
+	WebSocket     *WebSocketConfig `json:"websocket,omitempty"`
+	GRPC          *GRPCConfig      `json:"grpc,omitempty"`
+	CollectionID  string           `json:"collection_id"`
+	FolderID      string           `json:"folder_id"`
+	EnvironmentID string           `json:"environment_id"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
 }
 
 // RequestBody represents the body of an HTTP request
 type RequestBody struct {
-	Type    string `json:"type"`    // json, xml, form, raw
-	Content string `json:"content"`
+	Type          string     `json:"type"` // json, xml, form, raw, multipart, urlencoded, binary, graphql
+	Content       string     `json:"content"`
+	Parts         []BodyPart `json:"parts,omitempty"`          // multipart
+	Fields        KeyValues  `json:"fields,omitempty"`         // urlencoded
+	FilePath      string     `json:"file_path,omitempty"`      // binary
+	Query         string     `json:"query,omitempty"`          // graphql
+	Variables     string     `json:"variables,omitempty"`      // graphql, a JSON object
+	OperationName string     `json:"operation_name,omitempty"` // graphql
+}
+
+// BodyPart represents one part of a multipart body
+type BodyPart struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // text, file
+	Value       string `json:"value,omitempty"`
+	FilePath    string `json:"file_path,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+}
+
+// KeyValue represents one entry of an ordered key/value list, such as a
+// header or query parameter. Disabled entries are kept but not sent.
+type KeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description,omitempty"`
+}
+
+// UnmarshalJSON treats entries without an "enabled" field as enabled
+func (kv *KeyValue) UnmarshalJSON(data []byte) error {
+	type plain KeyValue
+	entry := plain{Enabled: true}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	*kv = KeyValue(entry)
+	return nil
+}
+
+// KeyValues is an ordered list of key/value entries that may repeat keys
+type KeyValues []KeyValue
+
+// Add appends an enabled entry
+func (kvs *KeyValues) Add(key, value string) {
+	*kvs = append(*kvs, KeyValue{Key: key, Value: value, Enabled: true})
+}
+
+// Get returns the value of the first enabled entry with the given key
+func (kvs KeyValues) Get(key string) string {
+	for _, kv := range kvs {
+		if kv.Enabled && kv.Key == key {
+			return kv.Value
+		}
+	}
+	return ""
+}
+
+// Enabled returns the entries that are enabled, in order
+func (kvs KeyValues) Enabled() KeyValues {
+	enabled := make(KeyValues, 0, len(kvs))
+	for _, kv := range kvs {
+		if kv.Enabled {
+			enabled = append(enabled, kv)
+		}
+	}
+	return enabled
+}
+
+// Map returns the enabled entries as a map, keeping the first value of each key
+func (kvs KeyValues) Map() map[string]string {
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		if _, exists := m[kv.Key]; kv.Enabled && !exists {
+			m[kv.Key] = kv.Value
+		}
+	}
+	return m
+}
+
+// RequestSettings overrides the HTTP client's settings for a single request.
+// Unset fields keep the client's settings.
+type RequestSettings struct {
+	Timeout         int   `json:"timeout,omitempty"` // milliseconds
+	RetryCount      *int  `json:"retry_count,omitempty"`
+	RetryOnStatus   []int `json:"retry_on_status,omitempty"` // status codes that are retried
+	FollowRedirects *bool `json:"follow_redirects,omitempty"`
+	MaxRedirects    int   `json:"max_redirects,omitempty"`
+	KeepAlive       *bool `json:"keep_alive,omitempty"`
+	// Stream reads a text/event-stream response event by event as it arrives
+	// instead of waiting for the whole body
+	Stream bool `json:"stream,omitempty"`
+	// Reconnect reopens a stream the server closed, sending Last-Event-ID
+	Reconnect bool `json:"reconnect,omitempty"`
+	// Compression encodes the request body with gzip, deflate, br or zstd
+	Compression string `json:"compression,omitempty"`
+	// AcceptEncoding lists the encodings offered for the response, unless an
+	// Accept-Encoding header is set; "identity" asks for it uncompressed
+	AcceptEncoding string `json:"accept_encoding,omitempty"`
 }
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
-	Type   string            `json:"type"`   // basic, bearer, api_key, oauth2
+	Type   string            `json:"type"` // basic, bearer, api_key, oauth2, digest, hawk, aws_sigv4, jwt
 	Config map[string]string `json:"config"`
 }
 
@@ -42,3 +145,66 @@ type Test struct {
 	Script   string `json:"script"`
 	Expected string `json:"expected"`
 }
+
+// Clone returns a deep copy of the request
+func (r *Request) Clone() *Request {
+	c := *r
+	c.Headers = append(KeyValues(nil), r.Headers...)
+	c.QueryParams = append(KeyValues(nil), r.QueryParams...)
+	if r.Body != nil {
+		body := *r.Body
+		body.Parts = append([]BodyPart(nil), r.Body.Parts...)
+		body.Fields = append(KeyValues(nil), r.Body.Fields...)
+		c.Body = &body
+	}
+	if r.Auth != nil {
+		auth := *r.Auth
+		if r.Auth.Config != nil {
+			auth.Config = make(map[string]string, len(r.Auth.Config))
+			for k, v := range r.Auth.Config {
+				auth.Config[k] = v
+			}
+		}
+		c.Auth = &auth
+	}
+	c.Tests = append([]Test(nil), r.Tests...)
+	if r.Settings != nil {
+		settings := *r.Settings
+		settings.RetryOnStatus = append([]int(nil), r.Settings.RetryOnStatus...)
+		c.Settings = &settings
+	}
+	if r.WebSocket != nil {
+		ws := *r.WebSocket
+		ws.Subprotocols = append([]string(nil), r.WebSocket.Subprotocols...)
+		ws.Templates = append([]MessageTemplate(nil), r.WebSocket.Templates...)
+		c.WebSocket = &ws
+	}
+	if r.GRPC != nil {
+		grpc := *r.GRPC
+		grpc.ProtoFiles = append([]string(nil), r.GRPC.ProtoFiles...)
+		grpc.ImportPaths = append([]string(nil), r.GRPC.ImportPaths...)
+		c.GRPC = &grpc
+	}
+	return &c
+}
+
+// IsWebSocket reports whether the request opens a WebSocket session rather
+// than sending an HTTP request
+func (r *Request) IsWebSocket() bool {
+	if r.Type != "" {
+		return r.Type == RequestTypeWebSocket
+	}
+	scheme, _, _ := strings.Cut(r.URL, "://")
+	scheme = strings.ToLower(scheme)
+	return scheme == "ws" || scheme == "wss"
+}
+
+// IsGRPC reports whether the request is a gRPC call rather than an HTTP request
+func (r *Request) IsGRPC() bool {
+	if r.Type != "" {
+		return r.Type == RequestTypeGRPC
+	}
+	scheme, _, _ := strings.Cut(r.URL, "://")
+	scheme = strings.ToLower(scheme)
+	return scheme == "grpc" || scheme == "grpcs"
+}
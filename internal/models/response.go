@@ -6,22 +6,51 @@ import (
 
 // Response represents an HTTP response
 type Response struct {
-	ID         string            `json:"id"`
-	RequestID  string            `json:"request_id"`
-	StatusCode int               `json:"status_code"`
-	Headers    map[string]string `json:"headers"`
-	Body       string            `json:"body"`
-	Size       int64             `json:"size"`
-	Duration   time.Duration     `json:"duration"`
-	CreatedAt  time.Time         `json:"created_at"`
+	ID         string          `json:"id"`
+	RequestID  string          `json:"request_id"`
+	StatusCode int             `json:"status_code"`
+	Headers    KeyValues       `json:"headers"`
+	Body       string          `json:"body"` // empty for binary responses
+	Size       int64           `json:"size"` // the decoded body
+	Duration   time.Duration   `json:"duration"`
+	Timing     ResponseTiming  `json:"timing"`
+	Cookies    []Cookie        `json:"cookies"`
+	Connection *ConnectionInfo `json:"connection,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	// RawBody holds the body bytes as received; stored responses may leave it
+	// nil and load it on demand
+	RawBody     []byte `json:"-"`
+	ContentType string `json:"content_type"`
+	Binary      bool   `json:"binary"`
+	// BlobID names the blob store entry holding the body, if it was spilled there
+	BlobID string `json:"blob_id,omitempty"`
+	// WireSize is the body as received, before its Content-Encoding was
+	// decoded, and HeaderSize the status line and headers; together they are
+	// what the response cost in bandwidth
+	WireSize        int64  `json:"wire_size"`
+	HeaderSize      int64  `json:"header_size"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	// WireBody holds the body as received when it was encoded; stored
+	// responses leave it nil and load it on demand
+	WireBody []byte `json:"-"`
+	// Cancelled marks a run that was aborted before it completed; Error says why
+	Cancelled bool   `json:"cancelled,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// Redirects lists the redirect responses that led to this one, in order
+	Redirects []RedirectHop `json:"redirects,omitempty"`
+	// Events is the transcript of a streamed text/event-stream response,
+	// across any reconnects
+	Events []ServerSentEvent `json:"events,omitempty"`
+	// GRPC holds the status, trailers and messages of a gRPC call
+	GRPC *GRPCResult `json:"grpc,omitempty"`
 }
 
 // ResponseInfo represents response metadata
 type ResponseInfo struct {
-	StatusText string            `json:"status_text"`
-	Headers    map[string]string `json:"headers"`
-	Cookies    []Cookie          `json:"cookies"`
-	Timing     ResponseTiming    `json:"timing"`
+	StatusText string         `json:"status_text"`
+	Headers    KeyValues      `json:"headers"`
+	Cookies    []Cookie       `json:"cookies"`
+	Timing     ResponseTiming `json:"timing"`
 }
 
 // Cookie represents an HTTP cookie
@@ -33,6 +62,7 @@ type Cookie struct {
 	Expires  time.Time `json:"expires"`
 	Secure   bool      `json:"secure"`
 	HTTPOnly bool      `json:"http_only"`
+	HostOnly bool      `json:"host_only"`
 }
 
 // ResponseTiming represents timing information
@@ -44,3 +74,56 @@ type ResponseTiming struct {
 	ContentTransfer  time.Duration `json:"content_transfer"`
 	Total            time.Duration `json:"total"`
 }
+
+// RedirectHop is a redirect response that was followed on the way to the final response
+type RedirectHop struct {
+	Method     string         `json:"method"`
+	URL        string         `json:"url"`
+	StatusCode int            `json:"status_code"`
+	Location   string         `json:"location"`
+	Headers    KeyValues      `json:"headers"`
+	Timing     ResponseTiming `json:"timing"`
+}
+
+// ServerSentEvent is an event received from a text/event-stream response
+type ServerSentEvent struct {
+	ID         string    `json:"id,omitempty"`    // last event ID in effect when it was dispatched
+	Event      string    `json:"event,omitempty"` // empty for the default "message" type
+	Data       string    `json:"data"`
+	Retry      int       `json:"retry,omitempty"` // reconnection time in milliseconds, if the frame set one
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// ConnectionInfo describes the connection a response was received on
+type ConnectionInfo struct {
+	RemoteIP   string   `json:"remote_ip"`
+	RemotePort int      `json:"remote_port"`
+	Protocol   string   `json:"protocol"` // HTTP/1.1, HTTP/2.0
+	Reused     bool     `json:"reused"`
+	TLS        *TLSInfo `json:"tls,omitempty"`
+	Proxy      string   `json:"proxy,omitempty"`    // host:port of the proxy the request went through
+	Warnings   []string `json:"warnings,omitempty"` // settings that did not apply to the connection
+}
+
+// TLSInfo represents the negotiated TLS session
+type TLSInfo struct {
+	Version          string            `json:"version"`
+	CipherSuite      string            `json:"cipher_suite"`
+	ALPN             string            `json:"alpn"`
+	ServerName       string            `json:"server_name"`
+	Resumed          bool              `json:"resumed"`
+	PeerCertificates []CertificateInfo `json:"peer_certificates"`
+	Warnings         []string          `json:"warnings"`
+}
+
+// CertificateInfo summarizes a certificate from the peer's chain
+type CertificateInfo struct {
+	Subject           string    `json:"subject"`
+	Issuer            string    `json:"issuer"`
+	SANs              []string  `json:"sans"`
+	SerialNumber      string    `json:"serial_number"`
+	NotBefore         time.Time `json:"not_before"`
+	NotAfter          time.Time `json:"not_after"`
+	DaysUntilExpiry   int       `json:"days_until_expiry"`
+	FingerprintSHA256 string    `json:"fingerprint_sha256"`
+}
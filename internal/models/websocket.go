@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Request types
+const (
+	RequestTypeHTTP      = "http"
+	RequestTypeWebSocket = "websocket"
+	RequestTypeGRPC      = "grpc"
+)
+
+// WebSocketConfig holds the WebSocket-specific parts of a request
+type WebSocketConfig struct {
+	Subprotocols []string          `json:"subprotocols,omitempty"`
+	Templates    []MessageTemplate `json:"templates,omitempty"`
+}
+
+// MessageTemplate is a saved message; its {{variables}} are substituted when it is sent
+type MessageTemplate struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// WebSocketMessage is one entry of a session's message log
+type WebSocketMessage struct {
+	Direction string        `json:"direction"`     // sent, received
+	Type      string        `json:"type"`          // text, binary, ping, pong, close
+	Data      string        `json:"data"`          // base64 for binary messages
+	RTT       time.Duration `json:"rtt,omitempty"` // for a pong answering our ping
+	Time      time.Time     `json:"time"`
+}
+
+// WebSocketSession is a WebSocket connection opened from a request, with its
+// message log
+type WebSocketSession struct {
+	ID          string             `json:"id"`
+	RequestID   string             `json:"request_id"`
+	URL         string             `json:"url"`
+	Subprotocol string             `json:"subprotocol,omitempty"` // negotiated with the server
+	StatusCode  int                `json:"status_code"`           // of the handshake response
+	Headers     KeyValues          `json:"headers"`               // of the handshake response
+	Open        bool               `json:"open"`
+	Messages    []WebSocketMessage `json:"messages"`
+	// LastRTT is the round trip time of the last ping that was answered
+	LastRTT   time.Duration `json:"last_rtt,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	ClosedAt  time.Time     `json:"closed_at"`
+}
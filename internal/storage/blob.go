@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore keeps large or binary response bodies outside the database,
+// addressed by the SHA-256 of their content
+type BlobStore struct {
+	dir string
+}
+
+// NewBlobStore creates a blob store rooted at dir, creating it if needed
+func NewBlobStore(dir string) (*BlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &BlobStore{dir: dir}, nil
+}
+
+// Put stores data and returns its ID. Identical content is stored once.
+func (b *BlobStore) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])
+
+	path := b.path(id)
+	if _, err := os.Stat(path); err == nil {
+		return id, nil
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated blob
+	tmp, err := os.CreateTemp(b.dir, id+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store blob: %w", err)
+	}
+	return id, nil
+}
+
+// Get returns the content of a blob
+func (b *BlobStore) Get(id string) ([]byte, error) {
+	data, err := os.ReadFile(b.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", id, err)
+	}
+	return data, nil
+}
+
+// path returns the file holding a blob. IDs are hex digests, so only the base
+// name is used to keep a malformed ID from escaping the directory.
+func (b *BlobStore) path(id string) string {
+	return filepath.Join(b.dir, filepath.Base(id))
+}
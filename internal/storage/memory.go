@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -9,20 +10,34 @@ import (
 
 // MemoryStorage is an in-memory storage implementation
 type MemoryStorage struct {
-	requests    map[string]*models.Request
-	responses   map[string]*models.Response
-	collections map[string]*models.Collection
-	environments map[string]*models.Environment
-	mutex       sync.RWMutex
+	requests       map[string]*models.Request
+	responses      map[string]*models.Response
+	executions     map[string]*models.Execution
+	webSockets     map[string]*models.WebSocketSession
+	graphQLSchemas map[string]*models.GraphQLSchema
+	collections    map[string]*models.Collection
+	environments   map[string]*models.Environment
+	cookies        map[string]*models.Cookie
+	certificates   map[string]*models.Certificate
+	caCertificates map[string]*models.CACertificate
+	settings       map[string]string
+	mutex          sync.RWMutex
 }
 
 // NewMemoryStorage creates a new in-memory storage
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		requests:     make(map[string]*models.Request),
-		responses:    make(map[string]*models.Response),
-		collections:  make(map[string]*models.Collection),
-		environments: make(map[string]*models.Environment),
+		requests:       make(map[string]*models.Request),
+		responses:      make(map[string]*models.Response),
+		executions:     make(map[string]*models.Execution),
+		webSockets:     make(map[string]*models.WebSocketSession),
+		graphQLSchemas: make(map[string]*models.GraphQLSchema),
+		collections:    make(map[string]*models.Collection),
+		environments:   make(map[string]*models.Environment),
+		cookies:        make(map[string]*models.Cookie),
+		certificates:   make(map[string]*models.Certificate),
+		caCertificates: make(map[string]*models.CACertificate),
+		settings:       make(map[string]string),
 	}
 }
 
@@ -79,6 +94,39 @@ func (m *MemoryStorage) SaveResponse(resp *models.Response) error {
 	return nil
 }
 
+// GetResponse returns a response by ID
+func (m *MemoryStorage) GetResponse(id string) (*models.Response, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	resp, exists := m.responses[id]
+	if !exists {
+		return nil, fmt.Errorf("response not found: %s", id)
+	}
+	return resp, nil
+}
+
+// GetResponseBody returns the raw body of a response
+func (m *MemoryStorage) GetResponseBody(id string) ([]byte, error) {
+	resp, err := m.GetResponse(id)
+	if err != nil {
+		return nil, err
+	}
+	if resp.RawBody != nil {
+		return resp.RawBody, nil
+	}
+	return []byte(resp.Body), nil
+}
+
+// GetResponseWireBody returns the body of a response as it was received
+func (m *MemoryStorage) GetResponseWireBody(id string) ([]byte, error) {
+	resp, err := m.GetResponse(id)
+	if err != nil {
+		return nil, err
+	}
+	return resp.WireBody, nil
+}
+
 // GetResponsesForRequest returns all responses for a request
 func (m *MemoryStorage) GetResponsesForRequest(requestID string) ([]*models.Response, error) {
 	m.mutex.RLock()
@@ -93,6 +141,83 @@ func (m *MemoryStorage) GetResponsesForRequest(requestID string) ([]*models.Resp
 	return responses, nil
 }
 
+// SaveExecution saves a run and its response to memory
+func (m *MemoryStorage) SaveExecution(exec *models.Execution) error {
+	if err := m.SaveResponse(exec.Response); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.executions[exec.ID] = exec
+	return nil
+}
+
+// GetExecution returns a run by the ID of its response
+func (m *MemoryStorage) GetExecution(id string) (*models.Execution, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	exec, exists := m.executions[id]
+	if !exists {
+		return nil, fmt.Errorf("execution not found: %s", id)
+	}
+	return exec, nil
+}
+
+// SaveWebSocketSession saves a WebSocket session to memory
+func (m *MemoryStorage) SaveWebSocketSession(session *models.WebSocketSession) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.webSockets[session.ID] = session
+	return nil
+}
+
+// GetWebSocketSession returns a WebSocket session by ID
+func (m *MemoryStorage) GetWebSocketSession(id string) (*models.WebSocketSession, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	session, exists := m.webSockets[id]
+	if !exists {
+		return nil, fmt.Errorf("websocket session not found: %s", id)
+	}
+	return session, nil
+}
+
+// GetWebSocketSessionsForRequest returns all WebSocket sessions for a request
+func (m *MemoryStorage) GetWebSocketSessionsForRequest(requestID string) ([]*models.WebSocketSession, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	sessions := make([]*models.WebSocketSession, 0)
+	for _, session := range m.webSockets {
+		if session.RequestID == requestID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// SaveGraphQLSchema caches the schema of a GraphQL endpoint in memory
+func (m *MemoryStorage) SaveGraphQLSchema(schema *models.GraphQLSchema) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.graphQLSchemas[schema.Endpoint] = schema
+	return nil
+}
+
+// GetGraphQLSchema returns the cached schema of a GraphQL endpoint, or nil if there is none
+func (m *MemoryStorage) GetGraphQLSchema(endpoint string) (*models.GraphQLSchema, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.graphQLSchemas[endpoint], nil
+}
+
 // SaveCollection saves a collection to memory
 func (m *MemoryStorage) SaveCollection(coll *models.Collection) error {
 	m.mutex.Lock()
@@ -178,3 +303,110 @@ func (m *MemoryStorage) DeleteEnvironment(id string) error {
 	delete(m.environments, id)
 	return nil
 }
+
+// SaveCookie saves a cookie to memory
+func (m *MemoryStorage) SaveCookie(cookie *models.Cookie) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	m.cookies[cookie.Domain+";"+cookie.Path+";"+cookie.Name] = cookie
+	return nil
+}
+
+// GetAllCookies returns all cookies
+func (m *MemoryStorage) GetAllCookies() ([]*models.Cookie, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	cookies := make([]*models.Cookie, 0, len(m.cookies))
+	for _, cookie := range m.cookies {
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// DeleteCookie deletes a cookie
+func (m *MemoryStorage) DeleteCookie(domain, path, name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	delete(m.cookies, domain+";"+path+";"+name)
+	return nil
+}
+
+// SaveCertificate saves a client certificate to memory
+func (m *MemoryStorage) SaveCertificate(cert *models.Certificate) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	m.certificates[cert.ID] = cert
+	return nil
+}
+
+// GetAllCertificates returns all client certificates
+func (m *MemoryStorage) GetAllCertificates() ([]*models.Certificate, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	certs := make([]*models.Certificate, 0, len(m.certificates))
+	for _, cert := range m.certificates {
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// DeleteCertificate deletes a client certificate
+func (m *MemoryStorage) DeleteCertificate(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	delete(m.certificates, id)
+	return nil
+}
+
+// SaveCACertificate saves a CA bundle to memory
+func (m *MemoryStorage) SaveCACertificate(ca *models.CACertificate) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	m.caCertificates[ca.ID] = ca
+	return nil
+}
+
+// GetAllCACertificates returns all CA bundles
+func (m *MemoryStorage) GetAllCACertificates() ([]*models.CACertificate, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	cas := make([]*models.CACertificate, 0, len(m.caCertificates))
+	for _, ca := range m.caCertificates {
+		cas = append(cas, ca)
+	}
+	return cas, nil
+}
+
+// DeleteCACertificate deletes a CA bundle
+func (m *MemoryStorage) DeleteCACertificate(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	delete(m.caCertificates, id)
+	return nil
+}
+
+// SaveSetting saves an application setting to memory
+func (m *MemoryStorage) SaveSetting(key, value string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	m.settings[key] = value
+	return nil
+}
+
+// GetSetting returns an application setting, or an empty string if it is not set
+func (m *MemoryStorage) GetSetting(key string) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	return m.settings[key], nil
+}
@@ -3,16 +3,24 @@ package sqlite
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"postgirl/internal/models"
+	"postgirl/internal/storage"
 )
 
+// blobThreshold is the body size above which responses spill to the blob store
+const blobThreshold = 1 << 20
+
 // SQLiteStorage represents the SQLite storage implementation
 type SQLiteStorage struct {
-	db *sql.DB
+	db    *sql.DB
+	blobs *storage.BlobStore // nil for in-memory databases
 }
 
 // NewSQLiteStorage creates a new SQLite storage instance
@@ -22,14 +30,22 @@ func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	storage := &SQLiteStorage{db: db}
+	s := &SQLiteStorage{db: db}
 	
 	// Create tables
-	if err := storage.createTables(); err != nil {
+	if err := s.createTables(); err != nil {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
-	return storage, nil
+	// Large and binary response bodies live in a directory next to the database
+	if path != ":memory:" && !strings.HasPrefix(path, "file::memory:") {
+		s.blobs, err = storage.NewBlobStore(path + ".blobs")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
 }
 
 // Close closes the database connection
@@ -83,6 +99,66 @@ func (s *SQLiteStorage) createTables() error {
 			duration INTEGER,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE TABLE IF NOT EXISTS executions (
+			id TEXT PRIMARY KEY,
+			request TEXT,
+			test_results TEXT,
+			console TEXT,
+			script_errors TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS cookies (
+			domain TEXT NOT NULL,
+			path TEXT NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT,
+			expires DATETIME,
+			secure BOOLEAN DEFAULT FALSE,
+			http_only BOOLEAN DEFAULT FALSE,
+			host_only BOOLEAN DEFAULT FALSE,
+			PRIMARY KEY (domain, path, name)
+		)`,
+		// key_pem, pfx and passphrase hold client credentials in plain text;
+		// the database file is only as safe as its file permissions
+		`CREATE TABLE IF NOT EXISTS certificates (
+			id TEXT PRIMARY KEY,
+			host TEXT NOT NULL,
+			cert_pem TEXT,
+			key_pem TEXT,
+			pfx TEXT,
+			passphrase TEXT,
+			insecure_skip_verify BOOLEAN DEFAULT FALSE,
+			min_tls_version TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS websocket_sessions (
+			id TEXT PRIMARY KEY,
+			request_id TEXT NOT NULL,
+			url TEXT NOT NULL,
+			subprotocol TEXT,
+			status_code INTEGER,
+			headers TEXT,
+			messages TEXT,
+			last_rtt INTEGER,
+			error TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			closed_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS graphql_schemas (
+			endpoint TEXT PRIMARY KEY,
+			schema TEXT NOT NULL,
+			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS ca_certificates (
+			id TEXT PRIMARY KEY,
+			name TEXT,
+			pem TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
 	}
 
 	for _, query := range queries {
@@ -91,9 +167,144 @@ func (s *SQLiteStorage) createTables() error {
 		}
 	}
 
+	if err := s.migrateColumns(); err != nil {
+		return err
+	}
+	return s.migrateKeyValueLists()
+}
+
+// migrateColumns adds columns introduced after a table was first created
+func (s *SQLiteStorage) migrateColumns() error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"responses", "timing", "TEXT"},
+		{"responses", "cookies", "TEXT"},
+		{"responses", "connection", "TEXT"},
+		{"responses", "content_type", "TEXT"},
+		{"responses", "is_binary", "INTEGER NOT NULL DEFAULT 0"},
+		{"responses", "blob_id", "TEXT"},
+		{"responses", "cancelled", "INTEGER NOT NULL DEFAULT 0"},
+		{"responses", "error", "TEXT"},
+		{"responses", "redirects", "TEXT"},
+		{"responses", "events", "TEXT"},
+		{"environments", "proxy", "TEXT"},
+		{"requests", "settings", "TEXT"},
+		{"requests", "type", "TEXT"},
+		{"requests", "websocket", "TEXT"},
+		{"requests", "grpc", "TEXT"},
+		{"responses", "grpc", "TEXT"},
+		{"responses", "wire_size", "INTEGER NOT NULL DEFAULT 0"},
+		{"responses", "header_size", "INTEGER NOT NULL DEFAULT 0"},
+		{"responses", "content_encoding", "TEXT"},
+		{"responses", "wire_body", "BLOB"},
+		{"responses", "wire_blob_id", "TEXT"},
+		{"environments", "hosts", "TEXT"},
+		{"environments", "dns_server", "TEXT"},
+	}
+
+	for _, c := range columns {
+		exists, err := s.columnExists(c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.column, err)
+		}
+	}
+
 	return nil
 }
 
+// migrateKeyValueLists rewrites headers, query parameters and environment
+// variables stored as JSON objects, from before they became ordered lists, as
+// lists of enabled entries sorted by key
+func (s *SQLiteStorage) migrateKeyValueLists() error {
+	columns := []struct {
+		table  string
+		column string
+	}{
+		{"requests", "headers"},
+		{"requests", "query_params"},
+		{"responses", "headers"},
+		{"environments", "variables"},
+	}
+
+	for _, c := range columns {
+		rows, err := s.db.Query(fmt.Sprintf("SELECT id, %s FROM %s WHERE %s LIKE '{%%'", c.column, c.table, c.column))
+		if err != nil {
+			return fmt.Errorf("failed to read %s.%s: %w", c.table, c.column, err)
+		}
+
+		migrated := make(map[string]string)
+		for rows.Next() {
+			var id, value string
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return err
+			}
+			var old map[string]string
+			if err := json.Unmarshal([]byte(value), &old); err != nil {
+				continue
+			}
+			keys := make([]string, 0, len(old))
+			for key := range old {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			list := models.KeyValues{}
+			for _, key := range keys {
+				list.Add(key, old[key])
+			}
+			data, _ := json.Marshal(list)
+			migrated[id] = string(data)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, value := range migrated {
+			query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", c.table, c.column)
+			if _, err := s.db.Exec(query, value, id); err != nil {
+				return fmt.Errorf("failed to migrate %s.%s: %w", c.table, c.column, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// columnExists reports whether a table already has the given column
+func (s *SQLiteStorage) columnExists(table, column string) (bool, error) {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
 // SaveRequest saves a request to the database
 func (s *SQLiteStorage) SaveRequest(req *models.Request) error {
 	headers, _ := json.Marshal(req.Headers)
@@ -101,15 +312,30 @@ func (s *SQLiteStorage) SaveRequest(req *models.Request) error {
 	body, _ := json.Marshal(req.Body)
 	auth, _ := json.Marshal(req.Auth)
 	tests, _ := json.Marshal(req.Tests)
+	var settings sql.NullString
+	if req.Settings != nil {
+		data, _ := json.Marshal(req.Settings)
+		settings = sql.NullString{String: string(data), Valid: true}
+	}
+	var webSocket sql.NullString
+	if req.WebSocket != nil {
+		data, _ := json.Marshal(req.WebSocket)
+		webSocket = sql.NullString{String: string(data), Valid: true}
+	}
+	var grpc sql.NullString
+	if req.GRPC != nil {
+		data, _ := json.Marshal(req.GRPC)
+		grpc = sql.NullString{String: string(data), Valid: true}
+	}
 
 	query := `INSERT OR REPLACE INTO requests 
-		(id, name, method, url, headers, query_params, body, auth, pre_script, post_script, tests, collection_id, folder_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		(id, name, type, method, url, headers, query_params, body, auth, pre_script, post_script, tests, settings, websocket, grpc, collection_id, folder_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := s.db.Exec(query,
-		req.ID, req.Name, req.Method, req.URL,
+		req.ID, req.Name, req.Type, req.Method, req.URL,
 		string(headers), string(queryParams), string(body), string(auth),
-		req.PreScript, req.PostScript, string(tests),
+		req.PreScript, req.PostScript, string(tests), settings, webSocket, grpc,
 		req.CollectionID, req.FolderID, req.CreatedAt, req.UpdatedAt)
 
 	return err
@@ -117,18 +343,19 @@ func (s *SQLiteStorage) SaveRequest(req *models.Request) error {
 
 // GetRequest retrieves a request by ID
 func (s *SQLiteStorage) GetRequest(id string) (*models.Request, error) {
-	query := `SELECT id, name, method, url, headers, query_params, body, auth, pre_script, post_script, tests, collection_id, folder_id, created_at, updated_at
+	query := `SELECT id, name, type, method, url, headers, query_params, body, auth, pre_script, post_script, tests, settings, websocket, grpc, collection_id, folder_id, created_at, updated_at
 		FROM requests WHERE id = ?`
 
 	row := s.db.QueryRow(query, id)
 	
 	var req models.Request
 	var headers, queryParams, body, auth, tests string
+	var requestType, settings, webSocket, grpc sql.NullString
 	
 	err := row.Scan(
-		&req.ID, &req.Name, &req.Method, &req.URL,
-		&headers, &queryParams, &body, &auth, &tests,
-		&req.PreScript, &req.PostScript,
+		&req.ID, &req.Name, &requestType, &req.Method, &req.URL,
+		&headers, &queryParams, &body, &auth,
+		&req.PreScript, &req.PostScript, &tests, &settings, &webSocket, &grpc,
 		&req.CollectionID, &req.FolderID, &req.CreatedAt, &req.UpdatedAt)
 
 	if err != nil {
@@ -141,6 +368,16 @@ func (s *SQLiteStorage) GetRequest(id string) (*models.Request, error) {
 	json.Unmarshal([]byte(body), &req.Body)
 	json.Unmarshal([]byte(auth), &req.Auth)
 	json.Unmarshal([]byte(tests), &req.Tests)
+	if settings.Valid {
+		json.Unmarshal([]byte(settings.String), &req.Settings)
+	}
+	req.Type = requestType.String
+	if webSocket.Valid {
+		json.Unmarshal([]byte(webSocket.String), &req.WebSocket)
+	}
+	if grpc.Valid {
+		json.Unmarshal([]byte(grpc.String), &req.GRPC)
+	}
 
 	return &req, nil
 }
@@ -148,21 +385,117 @@ func (s *SQLiteStorage) GetRequest(id string) (*models.Request, error) {
 // SaveResponse saves a response to the database
 func (s *SQLiteStorage) SaveResponse(resp *models.Response) error {
 	headers, _ := json.Marshal(resp.Headers)
+	timing, _ := json.Marshal(resp.Timing)
+	cookies, _ := json.Marshal(resp.Cookies)
+	connection, _ := json.Marshal(resp.Connection)
+	redirects, _ := json.Marshal(resp.Redirects)
+	events, _ := json.Marshal(resp.Events)
+	var grpc sql.NullString
+	if resp.GRPC != nil {
+		data, _ := json.Marshal(resp.GRPC)
+		grpc = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Binary and large bodies go to the blob store; without one, binary bodies
+	// are kept in the body column as a BLOB
+	var body interface{} = resp.Body
+	raw := resp.RawBody
+	if raw == nil {
+		raw = []byte(resp.Body)
+	}
+	if resp.Binary || len(raw) > blobThreshold {
+		if s.blobs != nil {
+			id, err := s.blobs.Put(raw)
+			if err != nil {
+				return err
+			}
+			resp.BlobID = id
+			body = ""
+		} else if resp.Binary {
+			body = raw
+		}
+	}
+
+	// The body as received is encoded, so it is binary and goes the same way
+	wireBody, wireBlobID := resp.WireBody, ""
+	if wireBody != nil && s.blobs != nil {
+		id, err := s.blobs.Put(wireBody)
+		if err != nil {
+			return err
+		}
+		wireBody, wireBlobID = nil, id
+	}
 
 	query := `INSERT INTO responses 
-		(id, request_id, status_code, headers, body, size, duration, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		(id, request_id, status_code, headers, body, size, wire_size, header_size, content_encoding, wire_body, wire_blob_id, duration, timing, cookies, connection, content_type, is_binary, blob_id, cancelled, error, redirects, events, grpc, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := s.db.Exec(query,
 		resp.ID, resp.RequestID, resp.StatusCode,
-		string(headers), resp.Body, resp.Size, resp.Duration.Milliseconds(), resp.CreatedAt)
+		string(headers), body, resp.Size, resp.WireSize, resp.HeaderSize, resp.ContentEncoding, wireBody, wireBlobID, resp.Duration.Milliseconds(), string(timing), string(cookies), string(connection),
+		resp.ContentType, resp.Binary, resp.BlobID, resp.Cancelled, resp.Error, string(redirects), string(events), grpc, resp.CreatedAt)
 
 	return err
 }
 
+// GetResponse retrieves a response by ID, with its body loaded
+func (s *SQLiteStorage) GetResponse(id string) (*models.Response, error) {
+	query := `SELECT id, request_id, status_code, headers, body, size, wire_size, header_size, content_encoding, duration, timing, cookies, connection, content_type, is_binary, blob_id, cancelled, error, redirects, events, grpc, created_at
+		FROM responses WHERE id = ?`
+
+	resp, err := scanResponse(s.db.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.BlobID != "" {
+		if s.blobs == nil {
+			return nil, fmt.Errorf("response %s body is in a blob store that is not available", id)
+		}
+		raw, err := s.blobs.Get(resp.BlobID)
+		if err != nil {
+			return nil, err
+		}
+		resp.RawBody = raw
+		if !resp.Binary {
+			resp.Body = string(raw)
+		}
+	}
+	return resp, nil
+}
+
+// GetResponseBody retrieves the raw body of a response
+func (s *SQLiteStorage) GetResponseBody(id string) ([]byte, error) {
+	resp, err := s.GetResponse(id)
+	if err != nil {
+		return nil, err
+	}
+	if resp.RawBody != nil {
+		return resp.RawBody, nil
+	}
+	return []byte(resp.Body), nil
+}
+
+// GetResponseWireBody retrieves the body of a response as it was received,
+// before its Content-Encoding was decoded
+func (s *SQLiteStorage) GetResponseWireBody(id string) ([]byte, error) {
+	var wire []byte
+	var blobID sql.NullString
+	if err := s.db.QueryRow(`SELECT wire_body, wire_blob_id FROM responses WHERE id = ?`, id).Scan(&wire, &blobID); err != nil {
+		return nil, err
+	}
+	if blobID.String == "" {
+		return wire, nil
+	}
+	if s.blobs == nil {
+		return nil, fmt.Errorf("response %s body is in a blob store that is not available", id)
+	}
+	return s.blobs.Get(blobID.String)
+}
+
 // GetResponses retrieves responses for a request
 func (s *SQLiteStorage) GetResponsesForRequest(requestID string) ([]*models.Response, error) {
-	query := `SELECT id, request_id, status_code, headers, body, size, duration, created_at
+	query := `SELECT id, request_id, status_code, headers, body, size, wire_size, header_size, content_encoding, duration, timing, cookies, connection, content_type, is_binary, blob_id, cancelled, error, redirects, events, grpc, created_at
 		FROM responses WHERE request_id = ? ORDER BY created_at DESC`
 
 	rows, err := s.db.Query(query, requestID)
@@ -171,30 +504,226 @@ func (s *SQLiteStorage) GetResponsesForRequest(requestID string) ([]*models.Resp
 	}
 	defer rows.Close()
 
+	// Bodies kept in the blob store are left unloaded; see GetResponse
 	var responses []*models.Response
 	for rows.Next() {
-		var resp models.Response
-		var headers string
-		var duration int64
+		resp, err := scanResponse(rows)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
 
-		err := rows.Scan(
-			&resp.ID, &resp.RequestID, &resp.StatusCode,
-			&headers, &resp.Body, &resp.Size, &duration, &resp.CreatedAt)
+	return responses, nil
+}
+
+// SaveExecution saves a run to the database: its response, and the request
+// as sent with the results of its scripts and tests under the same ID
+func (s *SQLiteStorage) SaveExecution(exec *models.Execution) error {
+	if err := s.SaveResponse(exec.Response); err != nil {
+		return err
+	}
+
+	request, _ := json.Marshal(exec.Request)
+	testResults, _ := json.Marshal(exec.TestResults)
+	console, _ := json.Marshal(exec.Console)
+	scriptErrors, _ := json.Marshal(exec.ScriptErrors)
+
+	query := `INSERT OR REPLACE INTO executions
+		(id, request, test_results, console, script_errors)
+		VALUES (?, ?, ?, ?, ?)`
+
+	_, err := s.db.Exec(query, exec.ID, string(request), string(testResults), string(console), string(scriptErrors))
+	return err
+}
+
+// GetExecution retrieves a run by the ID of its response
+func (s *SQLiteStorage) GetExecution(id string) (*models.Execution, error) {
+	resp, err := s.GetResponse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT request, test_results, console, script_errors FROM executions WHERE id = ?`
+
+	exec := &models.Execution{ID: id, Response: resp}
+	var request, testResults, console, scriptErrors string
+	err = s.db.QueryRow(query, id).Scan(&request, &testResults, &console, &scriptErrors)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Responses saved before runs were kept have only the response
+		return exec, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(request), &exec.Request)
+	json.Unmarshal([]byte(testResults), &exec.TestResults)
+	json.Unmarshal([]byte(console), &exec.Console)
+	json.Unmarshal([]byte(scriptErrors), &exec.ScriptErrors)
+	return exec, nil
+}
+
+// scanResponse reads a response row selected with the columns used by GetResponse
+func scanResponse(row interface{ Scan(...interface{}) error }) (*models.Response, error) {
+	var resp models.Response
+	var headers string
+	var body []byte
+	var timing, cookies, connection, contentType, contentEncoding, blobID, errorText, redirects, events, grpc sql.NullString
+	var duration int64
+
+	err := row.Scan(
+		&resp.ID, &resp.RequestID, &resp.StatusCode,
+		&headers, &body, &resp.Size, &resp.WireSize, &resp.HeaderSize, &contentEncoding, &duration, &timing, &cookies, &connection,
+		&contentType, &resp.Binary, &blobID, &resp.Cancelled, &errorText, &redirects, &events, &grpc, &resp.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(headers), &resp.Headers)
+	if timing.Valid {
+		json.Unmarshal([]byte(timing.String), &resp.Timing)
+	}
+	if cookies.Valid {
+		json.Unmarshal([]byte(cookies.String), &resp.Cookies)
+	}
+	if connection.Valid {
+		json.Unmarshal([]byte(connection.String), &resp.Connection)
+	}
+	if redirects.Valid {
+		json.Unmarshal([]byte(redirects.String), &resp.Redirects)
+	}
+	if events.Valid {
+		json.Unmarshal([]byte(events.String), &resp.Events)
+	}
+	if grpc.Valid {
+		json.Unmarshal([]byte(grpc.String), &resp.GRPC)
+	}
+	resp.ContentType = contentType.String
+	resp.ContentEncoding = contentEncoding.String
+	resp.BlobID = blobID.String
+	resp.Error = errorText.String
+	if resp.Binary {
+		if resp.BlobID == "" {
+			resp.RawBody = body
+		}
+	} else {
+		resp.Body = string(body)
+	}
+	resp.Duration = time.Duration(duration) * time.Millisecond
+	return &resp, nil
+}
+
+// SaveWebSocketSession saves a WebSocket session with its message log
+func (s *SQLiteStorage) SaveWebSocketSession(session *models.WebSocketSession) error {
+	headers, _ := json.Marshal(session.Headers)
+	messages, _ := json.Marshal(session.Messages)
+
+	query := `INSERT OR REPLACE INTO websocket_sessions
+		(id, request_id, url, subprotocol, status_code, headers, messages, last_rtt, error, created_at, closed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.Exec(query,
+		session.ID, session.RequestID, session.URL, session.Subprotocol, session.StatusCode,
+		string(headers), string(messages), session.LastRTT.Microseconds(), session.Error,
+		session.CreatedAt, session.ClosedAt)
+
+	return err
+}
+
+// GetWebSocketSession retrieves a WebSocket session by ID
+func (s *SQLiteStorage) GetWebSocketSession(id string) (*models.WebSocketSession, error) {
+	query := `SELECT id, request_id, url, subprotocol, status_code, headers, messages, last_rtt, error, created_at, closed_at
+		FROM websocket_sessions WHERE id = ?`
+
+	return scanWebSocketSession(s.db.QueryRow(query, id))
+}
+
+// GetWebSocketSessionsForRequest retrieves the WebSocket sessions opened from a request
+func (s *SQLiteStorage) GetWebSocketSessionsForRequest(requestID string) ([]*models.WebSocketSession, error) {
+	query := `SELECT id, request_id, url, subprotocol, status_code, headers, messages, last_rtt, error, created_at, closed_at
+		FROM websocket_sessions WHERE request_id = ? ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(query, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.WebSocketSession
+	for rows.Next() {
+		session, err := scanWebSocketSession(rows)
 		if err != nil {
 			return nil, err
 		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
 
-		json.Unmarshal([]byte(headers), &resp.Headers)
-		resp.Duration = time.Duration(duration) * time.Millisecond
-		responses = append(responses, &resp)
+// scanWebSocketSession reads a session row selected with the columns used by GetWebSocketSession
+func scanWebSocketSession(row interface{ Scan(...interface{}) error }) (*models.WebSocketSession, error) {
+	var session models.WebSocketSession
+	var subprotocol, headers, messages, errorText sql.NullString
+	var lastRTT sql.NullInt64
+	var closedAt sql.NullTime
+
+	err := row.Scan(
+		&session.ID, &session.RequestID, &session.URL, &subprotocol, &session.StatusCode,
+		&headers, &messages, &lastRTT, &errorText, &session.CreatedAt, &closedAt)
+	if err != nil {
+		return nil, err
 	}
 
-	return responses, nil
+	session.Subprotocol = subprotocol.String
+	if headers.Valid {
+		json.Unmarshal([]byte(headers.String), &session.Headers)
+	}
+	if messages.Valid {
+		json.Unmarshal([]byte(messages.String), &session.Messages)
+	}
+	session.LastRTT = time.Duration(lastRTT.Int64) * time.Microsecond
+	session.Error = errorText.String
+	session.ClosedAt = closedAt.Time
+	return &session, nil
+}
+
+// SaveGraphQLSchema caches the schema of a GraphQL endpoint
+func (s *SQLiteStorage) SaveGraphQLSchema(schema *models.GraphQLSchema) error {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT OR REPLACE INTO graphql_schemas (endpoint, schema, fetched_at) VALUES (?, ?, ?)`
+	_, err = s.db.Exec(query, schema.Endpoint, string(data), schema.FetchedAt)
+	return err
+}
+
+// GetGraphQLSchema retrieves the cached schema of a GraphQL endpoint, or nil if there is none
+func (s *SQLiteStorage) GetGraphQLSchema(endpoint string) (*models.GraphQLSchema, error) {
+	query := `SELECT schema FROM graphql_schemas WHERE endpoint = ?`
+
+	var data string
+	err := s.db.QueryRow(query, endpoint).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var schema models.GraphQLSchema
+	if err := json.Unmarshal([]byte(data), &schema); err != nil {
+		return nil, fmt.Errorf("invalid cached schema: %w", err)
+	}
+	return &schema, nil
 }
 
 // ListRequests returns all requests
 func (s *SQLiteStorage) GetAllRequests() ([]*models.Request, error) {
-	query := `SELECT id, name, method, url, headers, query_params, body, auth, pre_script, post_script, tests, collection_id, folder_id, created_at, updated_at
+	query := `SELECT id, name, type, method, url, headers, query_params, body, auth, pre_script, post_script, tests, settings, websocket, grpc, collection_id, folder_id, created_at, updated_at
 		FROM requests ORDER BY updated_at DESC`
 
 	rows, err := s.db.Query(query)
@@ -207,11 +736,12 @@ func (s *SQLiteStorage) GetAllRequests() ([]*models.Request, error) {
 	for rows.Next() {
 		var req models.Request
 		var headers, queryParams, body, auth, tests string
+		var requestType, settings, webSocket, grpc sql.NullString
 		
 		err := rows.Scan(
-			&req.ID, &req.Name, &req.Method, &req.URL,
-			&headers, &queryParams, &body, &auth, &tests,
-			&req.PreScript, &req.PostScript,
+			&req.ID, &req.Name, &requestType, &req.Method, &req.URL,
+			&headers, &queryParams, &body, &auth,
+			&req.PreScript, &req.PostScript, &tests, &settings, &webSocket, &grpc,
 			&req.CollectionID, &req.FolderID, &req.CreatedAt, &req.UpdatedAt)
 		if err != nil {
 			return nil, err
@@ -223,6 +753,16 @@ func (s *SQLiteStorage) GetAllRequests() ([]*models.Request, error) {
 		json.Unmarshal([]byte(body), &req.Body)
 		json.Unmarshal([]byte(auth), &req.Auth)
 		json.Unmarshal([]byte(tests), &req.Tests)
+		if settings.Valid {
+			json.Unmarshal([]byte(settings.String), &req.Settings)
+		}
+		req.Type = requestType.String
+		if webSocket.Valid {
+			json.Unmarshal([]byte(webSocket.String), &req.WebSocket)
+		}
+		if grpc.Valid {
+			json.Unmarshal([]byte(grpc.String), &req.GRPC)
+		}
 
 		requests = append(requests, &req)
 	}
@@ -297,41 +837,59 @@ func (s *SQLiteStorage) GetAllCollections() ([]*models.Collection, error) {
 // SaveEnvironment saves an environment to the database
 func (s *SQLiteStorage) SaveEnvironment(env *models.Environment) error {
 	variables, _ := json.Marshal(env.Variables)
+	var proxy sql.NullString
+	if env.Proxy != nil {
+		data, _ := json.Marshal(env.Proxy)
+		proxy = sql.NullString{String: string(data), Valid: true}
+	}
+	var hosts sql.NullString
+	if len(env.Hosts) > 0 {
+		data, _ := json.Marshal(env.Hosts)
+		hosts = sql.NullString{String: string(data), Valid: true}
+	}
 
 	query := `INSERT OR REPLACE INTO environments 
-		(id, name, variables, is_active, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)`
+		(id, name, variables, is_active, proxy, hosts, dns_server, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := s.db.Exec(query,
-		env.ID, env.Name, string(variables), env.IsActive, env.CreatedAt, env.UpdatedAt)
+		env.ID, env.Name, string(variables), env.IsActive, proxy, hosts, env.DNSServer, env.CreatedAt, env.UpdatedAt)
 
 	return err
 }
 
 // GetEnvironment retrieves an environment by ID
 func (s *SQLiteStorage) GetEnvironment(id string) (*models.Environment, error) {
-	query := `SELECT id, name, variables, is_active, created_at, updated_at
+	query := `SELECT id, name, variables, is_active, proxy, hosts, dns_server, created_at, updated_at
 		FROM environments WHERE id = ?`
 
 	row := s.db.QueryRow(query, id)
 	
 	var env models.Environment
 	var variables string
+	var proxy, hosts, dnsServer sql.NullString
 	
 	err := row.Scan(
-		&env.ID, &env.Name, &variables, &env.IsActive, &env.CreatedAt, &env.UpdatedAt)
+		&env.ID, &env.Name, &variables, &env.IsActive, &proxy, &hosts, &dnsServer, &env.CreatedAt, &env.UpdatedAt)
 
 	if err != nil {
 		return nil, err
 	}
 
 	json.Unmarshal([]byte(variables), &env.Variables)
+	if proxy.Valid {
+		json.Unmarshal([]byte(proxy.String), &env.Proxy)
+	}
+	if hosts.Valid {
+		json.Unmarshal([]byte(hosts.String), &env.Hosts)
+	}
+	env.DNSServer = dnsServer.String
 	return &env, nil
 }
 
 // ListEnvironments returns all environments
 func (s *SQLiteStorage) GetAllEnvironments() ([]*models.Environment, error) {
-	query := `SELECT id, name, variables, is_active, created_at, updated_at
+	query := `SELECT id, name, variables, is_active, proxy, hosts, dns_server, created_at, updated_at
 		FROM environments ORDER BY updated_at DESC`
 
 	rows, err := s.db.Query(query)
@@ -344,14 +902,22 @@ func (s *SQLiteStorage) GetAllEnvironments() ([]*models.Environment, error) {
 	for rows.Next() {
 		var env models.Environment
 		var variables string
+		var proxy, hosts, dnsServer sql.NullString
 		
 		err := rows.Scan(
-			&env.ID, &env.Name, &variables, &env.IsActive, &env.CreatedAt, &env.UpdatedAt)
+			&env.ID, &env.Name, &variables, &env.IsActive, &proxy, &hosts, &dnsServer, &env.CreatedAt, &env.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
 
 		json.Unmarshal([]byte(variables), &env.Variables)
+		if proxy.Valid {
+			json.Unmarshal([]byte(proxy.String), &env.Proxy)
+		}
+		if hosts.Valid {
+			json.Unmarshal([]byte(hosts.String), &env.Hosts)
+		}
+		env.DNSServer = dnsServer.String
 		environments = append(environments, &env)
 	}
 
@@ -378,3 +944,166 @@ func (s *SQLiteStorage) DeleteEnvironment(id string) error {
 	_, err := s.db.Exec(query, id)
 	return err
 }
+
+// SaveCookie saves a cookie to the database
+func (s *SQLiteStorage) SaveCookie(cookie *models.Cookie) error {
+	query := `INSERT OR REPLACE INTO cookies 
+		(domain, path, name, value, expires, secure, http_only, host_only)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.Exec(query,
+		cookie.Domain, cookie.Path, cookie.Name, cookie.Value,
+		cookie.Expires, cookie.Secure, cookie.HTTPOnly, cookie.HostOnly)
+
+	return err
+}
+
+// GetAllCookies returns all cookies
+func (s *SQLiteStorage) GetAllCookies() ([]*models.Cookie, error) {
+	query := `SELECT domain, path, name, value, expires, secure, http_only, host_only
+		FROM cookies ORDER BY domain, path, name`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cookies []*models.Cookie
+	for rows.Next() {
+		var cookie models.Cookie
+
+		err := rows.Scan(
+			&cookie.Domain, &cookie.Path, &cookie.Name, &cookie.Value,
+			&cookie.Expires, &cookie.Secure, &cookie.HTTPOnly, &cookie.HostOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		cookies = append(cookies, &cookie)
+	}
+
+	return cookies, nil
+}
+
+// DeleteCookie deletes a cookie by domain, path and name
+func (s *SQLiteStorage) DeleteCookie(domain, path, name string) error {
+	query := `DELETE FROM cookies WHERE domain = ? AND path = ? AND name = ?`
+	_, err := s.db.Exec(query, domain, path, name)
+	return err
+}
+
+// SaveCertificate saves a client certificate to the database
+func (s *SQLiteStorage) SaveCertificate(cert *models.Certificate) error {
+	query := `INSERT OR REPLACE INTO certificates 
+		(id, host, cert_pem, key_pem, pfx, passphrase, insecure_skip_verify, min_tls_version, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.Exec(query,
+		cert.ID, cert.Host, cert.CertPEM, cert.KeyPEM, cert.PFX, cert.Passphrase,
+		cert.InsecureSkipVerify, cert.MinTLSVersion, cert.CreatedAt, cert.UpdatedAt)
+
+	return err
+}
+
+// GetAllCertificates returns all client certificates
+func (s *SQLiteStorage) GetAllCertificates() ([]*models.Certificate, error) {
+	query := `SELECT id, host, cert_pem, key_pem, pfx, passphrase, insecure_skip_verify, min_tls_version, created_at, updated_at
+		FROM certificates ORDER BY host`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certs []*models.Certificate
+	for rows.Next() {
+		var cert models.Certificate
+
+		err := rows.Scan(
+			&cert.ID, &cert.Host, &cert.CertPEM, &cert.KeyPEM, &cert.PFX, &cert.Passphrase,
+			&cert.InsecureSkipVerify, &cert.MinTLSVersion, &cert.CreatedAt, &cert.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, &cert)
+	}
+
+	return certs, nil
+}
+
+// DeleteCertificate deletes a client certificate by ID
+func (s *SQLiteStorage) DeleteCertificate(id string) error {
+	query := `DELETE FROM certificates WHERE id = ?`
+	_, err := s.db.Exec(query, id)
+	return err
+}
+
+// SaveCACertificate saves a CA bundle to the database
+func (s *SQLiteStorage) SaveCACertificate(ca *models.CACertificate) error {
+	query := `INSERT OR REPLACE INTO ca_certificates 
+		(id, name, pem, created_at)
+		VALUES (?, ?, ?, ?)`
+
+	_, err := s.db.Exec(query, ca.ID, ca.Name, ca.PEM, ca.CreatedAt)
+
+	return err
+}
+
+// GetAllCACertificates returns all CA bundles
+func (s *SQLiteStorage) GetAllCACertificates() ([]*models.CACertificate, error) {
+	query := `SELECT id, name, pem, created_at
+		FROM ca_certificates ORDER BY created_at`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cas []*models.CACertificate
+	for rows.Next() {
+		var ca models.CACertificate
+
+		err := rows.Scan(&ca.ID, &ca.Name, &ca.PEM, &ca.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		cas = append(cas, &ca)
+	}
+
+	return cas, nil
+}
+
+// DeleteCACertificate deletes a CA bundle by ID
+func (s *SQLiteStorage) DeleteCACertificate(id string) error {
+	query := `DELETE FROM ca_certificates WHERE id = ?`
+	_, err := s.db.Exec(query, id)
+	return err
+}
+
+// SaveSetting saves an application setting
+func (s *SQLiteStorage) SaveSetting(key, value string) error {
+	query := `INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)`
+	_, err := s.db.Exec(query, key, value)
+	return err
+}
+
+// GetSetting returns an application setting, or an empty string if it is not set
+func (s *SQLiteStorage) GetSetting(key string) (string, error) {
+	query := `SELECT value FROM settings WHERE key = ?`
+
+	var value sql.NullString
+	err := s.db.QueryRow(query, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return value.String, nil
+}
@@ -12,8 +12,24 @@ type Storage interface {
 
 	// Response methods
 	SaveResponse(resp *models.Response) error
+	GetResponse(id string) (*models.Response, error)
+	GetResponseBody(id string) ([]byte, error)
+	GetResponseWireBody(id string) ([]byte, error)
 	GetResponsesForRequest(requestID string) ([]*models.Response, error)
 
+	// Execution methods; saving a run saves its response too
+	SaveExecution(exec *models.Execution) error
+	GetExecution(id string) (*models.Execution, error)
+
+	// WebSocket session methods
+	SaveWebSocketSession(session *models.WebSocketSession) error
+	GetWebSocketSession(id string) (*models.WebSocketSession, error)
+	GetWebSocketSessionsForRequest(requestID string) ([]*models.WebSocketSession, error)
+
+	// GraphQL schema methods
+	SaveGraphQLSchema(schema *models.GraphQLSchema) error
+	GetGraphQLSchema(endpoint string) (*models.GraphQLSchema, error)
+
 	// Collection methods
 	SaveCollection(coll *models.Collection) error
 	GetCollection(id string) (*models.Collection, error)
@@ -25,4 +41,21 @@ type Storage interface {
 	GetEnvironment(id string) (*models.Environment, error)
 	GetAllEnvironments() ([]*models.Environment, error)
 	DeleteEnvironment(id string) error
+
+	// Cookie methods
+	SaveCookie(cookie *models.Cookie) error
+	GetAllCookies() ([]*models.Cookie, error)
+	DeleteCookie(domain, path, name string) error
+
+	// Certificate methods
+	SaveCertificate(cert *models.Certificate) error
+	GetAllCertificates() ([]*models.Certificate, error)
+	DeleteCertificate(id string) error
+	SaveCACertificate(ca *models.CACertificate) error
+	GetAllCACertificates() ([]*models.CACertificate, error)
+	DeleteCACertificate(id string) error
+
+	// Setting methods
+	SaveSetting(key, value string) error
+	GetSetting(key string) (string, error)
 }
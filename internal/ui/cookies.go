@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"postgirl/internal/app"
+	"postgirl/internal/models"
+)
+
+// CookieModel represents the cookie jar manager UI
+type CookieModel struct {
+	cookies    []models.Cookie
+	selected   int
+	width      int
+	height     int
+	service    *app.Service
+	error      string
+	valueInput *InputModel
+	inputMode  bool
+}
+
+// NewCookieModel creates a new cookie model
+func NewCookieModel(service *app.Service) *CookieModel {
+	return &CookieModel{
+		service:    service,
+		valueInput: NewInputModel("Cookie value"),
+	}
+}
+
+// Init initializes the cookie model
+func (c *CookieModel) Init() tea.Cmd {
+	c.refresh()
+	return nil
+}
+
+// refresh reloads the cookies from the jar
+func (c *CookieModel) refresh() {
+	c.cookies = c.service.ListCookies()
+	if c.selected >= len(c.cookies) {
+		c.selected = len(c.cookies) - 1
+	}
+	if c.selected < 0 {
+		c.selected = 0
+	}
+}
+
+// Update handles messages for the cookie model
+func (c *CookieModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Handle input mode
+	if c.inputMode {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "esc":
+				c.inputMode = false
+				c.valueInput.Blur()
+				return c, nil
+			case "enter":
+				cookie := c.cookies[c.selected]
+				cookie.Value = c.valueInput.Value()
+				c.inputMode = false
+				c.valueInput.Blur()
+				if err := c.service.SaveCookie(cookie); err != nil {
+					c.error = err.Error()
+				} else {
+					c.error = ""
+				}
+				c.refresh()
+				return c, nil
+			}
+		}
+
+		// Update the input model
+		model, cmd := c.valueInput.Update(msg)
+		c.valueInput = model.(*InputModel)
+		return c, cmd
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return c, nil
+		case "up", "k":
+			if c.selected > 0 {
+				c.selected--
+			}
+		case "down", "j":
+			if c.selected < len(c.cookies)-1 {
+				c.selected++
+			}
+		case "enter":
+			if len(c.cookies) > 0 {
+				c.inputMode = true
+				c.valueInput.SetValue(c.cookies[c.selected].Value)
+				c.valueInput.Focus()
+			}
+		case "d":
+			if len(c.cookies) > 0 {
+				cookie := c.cookies[c.selected]
+				if err := c.service.DeleteCookie(cookie.Domain, cookie.Path, cookie.Name); err != nil {
+					c.error = err.Error()
+				} else {
+					c.error = ""
+				}
+				c.refresh()
+			}
+		case "r":
+			c.refresh()
+		}
+	}
+
+	return c, nil
+}
+
+// View renders the cookie model
+func (c *CookieModel) View() string {
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Background(lipgloss.Color("#7D56F4")).
+		Padding(0, 1).
+		Render("Cookies")
+
+	var items []string
+	for i, cookie := range c.cookies {
+		style := lipgloss.NewStyle()
+		if i == c.selected {
+			style = style.Bold(true).Foreground(lipgloss.Color("#7D56F4"))
+		}
+
+		value := cookie.Value
+		if c.inputMode && i == c.selected {
+			value = c.valueInput.View()
+		}
+
+		var flags []string
+		if cookie.Secure {
+			flags = append(flags, "Secure")
+		}
+		if cookie.HTTPOnly {
+			flags = append(flags, "HttpOnly")
+		}
+		if !cookie.Expires.IsZero() {
+			flags = append(flags, "expires "+cookie.Expires.Local().Format("2006-01-02 15:04"))
+		} else {
+			flags = append(flags, "session")
+		}
+
+		item := style.Render(fmt.Sprintf("%s%s  %s=%s  [%s]", cookie.Domain, cookie.Path, cookie.Name, value, strings.Join(flags, ", ")))
+		items = append(items, item)
+	}
+
+	content := strings.Join(items, "\n")
+	if len(items) == 0 {
+		content = "No cookies stored"
+	}
+
+	if c.error != "" {
+		content += fmt.Sprintf("\n\nError: %s", c.error)
+	}
+
+	menu := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#874BFD")).
+		Padding(1, 2).
+		Render(content)
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#626262")).
+		Render("Use arrow keys to navigate, Enter to edit value, 'd' to delete, 'r' to refresh, Esc to go back")
+
+	return lipgloss.JoinVertical(
+		lipgloss.Center,
+		title,
+		"",
+		menu,
+		"",
+		help,
+	)
+}
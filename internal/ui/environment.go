@@ -13,6 +13,7 @@ import (
 type EnvironmentModel struct {
 	environments []*models.Environment
 	selected     int
+	variable     int // variable of the selected environment under the cursor
 	width        int
 	height       int
 }
@@ -24,13 +25,16 @@ func NewEnvironmentModel() *EnvironmentModel {
 			{
 				ID:        "1",
 				Name:      "Development",
-				Variables: map[string]string{"base_url": "http://localhost:3000"},
+				Variables: models.EnvironmentVariables{
+					{Key: "base_url", Value: "http://localhost:3000", Enabled: true},
+					{Key: "debug", Value: "true", Description: "Verbose server logging", Enabled: false},
+				},
 				IsActive:  true,
 			},
 			{
 				ID:        "2",
 				Name:      "Production",
-				Variables: map[string]string{"base_url": "https://api.example.com"},
+				Variables: models.EnvironmentVariables{{Key: "base_url", Value: "https://api.example.com", Enabled: true}},
 				IsActive:  false,
 			},
 		},
@@ -53,10 +57,26 @@ func (e *EnvironmentModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "up", "k":
 			if e.selected > 0 {
 				e.selected--
+				e.variable = 0
 			}
 		case "down", "j":
 			if e.selected < len(e.environments)-1 {
 				e.selected++
+				e.variable = 0
+			}
+		case "left":
+			if e.variable > 0 {
+				e.variable--
+			}
+		case "right":
+			if e.selected < len(e.environments) && e.variable < len(e.environments[e.selected].Variables)-1 {
+				e.variable++
+			}
+		case " ":
+			// Toggle the variable under the cursor
+			if e.selected < len(e.environments) && e.variable < len(e.environments[e.selected].Variables) {
+				variable := &e.environments[e.selected].Variables[e.variable]
+				variable.Enabled = !variable.Enabled
 			}
 		case "enter":
 			// TODO: Implement environment selection
@@ -91,6 +111,9 @@ func (e *EnvironmentModel) View() string {
 		
 		item := style.Render(fmt.Sprintf("%s (%s) - %d variables", env.Name, status, len(env.Variables)))
 		items = append(items, item)
+		if i == e.selected {
+			items = append(items, e.variablesView(env)...)
+		}
 	}
 
 	content := strings.Join(items, "\n")
@@ -106,7 +129,7 @@ func (e *EnvironmentModel) View() string {
 
 	help := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#626262")).
-		Render("Use arrow keys to navigate, Enter to select, 'n' for new, Esc to go back")
+		Render("Use arrow keys to navigate, ←/→ to pick a variable, Space to toggle it, Enter to select, 'n' for new, Esc to go back")
 
 	return lipgloss.JoinVertical(
 		lipgloss.Center,
@@ -117,3 +140,31 @@ func (e *EnvironmentModel) View() string {
 		help,
 	)
 }
+
+// variablesView renders an environment's variables one per line, greying out
+// disabled ones and marking the cursor
+func (e *EnvironmentModel) variablesView(env *models.Environment) []string {
+	disabledStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
+	descriptionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+
+	lines := make([]string, 0, len(env.Variables))
+	for i, variable := range env.Variables {
+		cursor := "    "
+		if i == e.variable {
+			cursor = "  > "
+		}
+		check := "[x] "
+		if !variable.Enabled {
+			check = "[ ] "
+		}
+		text := check + variable.Key + " = " + variable.Value
+		if !variable.Enabled {
+			text = disabledStyle.Render(text)
+		}
+		if variable.Description != "" {
+			text += descriptionStyle.Render("  # " + variable.Description)
+		}
+		lines = append(lines, cursor+text)
+	}
+	return lines
+}
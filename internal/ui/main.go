@@ -6,6 +6,7 @@ import (
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"postgirl/internal/app"
+	"postgirl/internal/storage"
 	"postgirl/internal/storage/sqlite"
 )
 
@@ -18,6 +19,8 @@ const (
 	StateResponse
 	StateCollection
 	StateEnvironment
+	StateCookies
+	StateWebSocket
 )
 
 // App represents the main application
@@ -27,30 +30,44 @@ type App struct {
 	response    *ResponseModel
 	collection  *CollectionModel
 	environment *EnvironmentModel
+	cookies     *CookieModel
+	websocket   *WebSocketModel
 	width       int
 	height      int
 	service     *app.Service
+	// warning reports stored settings that failed to load
+	warning string
 }
 
 // NewApp creates a new application instance
 func NewApp() *App {
 	// Initialize storage
-	storage, err := sqlite.NewSQLiteStorage("postgirl.db")
+	var storageInstance storage.Storage
+	sqliteStorage, err := sqlite.NewSQLiteStorage("postgirl.db")
 	if err != nil {
-		// For now, continue without storage
-		storage = nil
+		// Fall back to in-memory storage
+		storageInstance = storage.NewMemoryStorage()
+	} else {
+		storageInstance = sqliteStorage
 	}
 	
 	// Initialize service
-	service := app.NewService(storage)
+	service, err := app.NewService(storageInstance)
+	var warning string
+	if err != nil {
+		warning = "Warning: " + err.Error()
+	}
 	
 	return &App{
 		state:       StateMain,
 		request:     NewRequestModel(service),
-		response:    NewResponseModel(),
+		response:    NewResponseModel(service),
 		collection:  NewCollectionModel(),
 		environment: NewEnvironmentModel(),
+		cookies:     NewCookieModel(service),
+		websocket:   NewWebSocketModel(service),
 		service:     service,
+		warning:     warning,
 	}
 }
 
@@ -61,9 +78,26 @@ func (a *App) Init() tea.Cmd {
 		a.response.Init(),
 		a.collection.Init(),
 		a.environment.Init(),
+		a.cookies.Init(),
+		a.websocket.Init(),
 	)
 }
 
+// editing reports whether the current view has a focused text field
+func (a *App) editing() bool {
+	switch a.state {
+	case StateRequest:
+		return a.request.inputMode
+	case StateResponse:
+		return a.response.inputMode
+	case StateCookies:
+		return a.cookies.inputMode
+	case StateWebSocket:
+		return a.websocket.inputMode
+	}
+	return false
+}
+
 // Update handles messages and updates the application state
 func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -73,6 +107,10 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, nil
 
 	case tea.KeyMsg:
+		// While a text field has focus, keys other than ctrl+c belong to it
+		if msg.String() != "ctrl+c" && a.editing() {
+			break
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return a, tea.Quit
@@ -84,9 +122,61 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.state = StateCollection
 		case "4":
 			a.state = StateEnvironment
+		case "5":
+			a.state = StateCookies
+			a.cookies.refresh()
+		case "6":
+			a.state = StateWebSocket
+			a.websocket.SetRequest(a.request.request)
 		case "esc":
 			a.state = StateMain
 		}
+
+	case EventReceivedMsg:
+		// Show streamed events live, whichever view is open
+		a.response.AddEvent(msg.Event)
+		a.request.events++
+		return a, msg.Next()
+
+	case OpenWebSocketMsg:
+		// ws:// and wss:// requests open a session in the WebSocket view
+		a.state = StateWebSocket
+		a.websocket.SetRequest(msg.Request)
+		if a.websocket.open() || a.websocket.connecting {
+			return a, nil
+		}
+		return a, a.websocket.connect()
+
+	case WebSocketOpenedMsg:
+		a.websocket.Opened(msg)
+		if msg.Session == nil {
+			return a, nil
+		}
+		return a, msg.Next()
+
+	case WebSocketMessageMsg:
+		a.websocket.AddMessage(msg.Message)
+		return a, msg.Next()
+
+	case WebSocketClosedMsg:
+		a.websocket.Closed(msg.Session)
+		return a, nil
+
+	case GraphQLSchemaMsg:
+		a.request.SchemaFetched(msg)
+		return a, nil
+
+	case GRPCServicesMsg:
+		a.request.ServicesLoaded(msg)
+		return a, nil
+
+	case RequestSentMsg:
+		// Keep the response viewer in sync with the last executed request
+		if msg.Execution != nil {
+			a.response.SetExecution(msg.Execution)
+		}
+		// The response may have set or expired cookies
+		a.cookies.refresh()
 	}
 
 	// Update current model based on state
@@ -107,6 +197,14 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		model, cmd := a.environment.Update(msg)
 		a.environment = model.(*EnvironmentModel)
 		return a, cmd
+	case StateCookies:
+		model, cmd := a.cookies.Update(msg)
+		a.cookies = model.(*CookieModel)
+		return a, cmd
+	case StateWebSocket:
+		model, cmd := a.websocket.Update(msg)
+		a.websocket = model.(*WebSocketModel)
+		return a, cmd
 	}
 
 	return a, nil
@@ -125,6 +223,10 @@ func (a *App) View() string {
 		return a.collection.View()
 	case StateEnvironment:
 		return a.environment.View()
+	case StateCookies:
+		return a.cookies.View()
+	case StateWebSocket:
+		return a.websocket.View()
 	default:
 		return "Unknown state"
 	}
@@ -153,6 +255,8 @@ func (a *App) mainView() string {
 				"2. Response Viewer",
 				"3. Collections",
 				"4. Environments",
+				"5. Cookies",
+				"6. WebSocket",
 				"",
 				"Press 'q' to quit",
 			}, "\n"),
@@ -162,13 +266,9 @@ func (a *App) mainView() string {
 		Foreground(lipgloss.Color("#626262")).
 		Render("Press a number to navigate, 'q' to quit")
 
-	return lipgloss.JoinVertical(
-		lipgloss.Center,
-		title,
-		subtitle,
-		"",
-		menu,
-		"",
-		help,
-	)
+	lines := []string{title, subtitle, "", menu, "", help}
+	if a.warning != "" {
+		lines = append(lines, "", lipgloss.NewStyle().Foreground(lipgloss.Color("#FF9800")).Render(a.warning))
+	}
+	return lipgloss.JoinVertical(lipgloss.Center, lines...)
 }
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -12,41 +13,75 @@ import (
 
 // RequestModel represents the request builder UI
 type RequestModel struct {
-	request   *models.Request
-	method    string
-	url       string
-	headers   map[string]string
-	body      string
-	bodyType  string
-	selected  int
-	width     int
-	height    int
-	service   *app.Service
-	loading   bool
-	response  *models.Response
-	error     string
-	urlInput  *InputModel
-	inputMode bool
+	request    *models.Request
+	method     string
+	url        string
+	headers    models.KeyValues
+	params     models.KeyValues
+	body       string
+	bodyType   string
+	parts      []models.BodyPart
+	fields     models.KeyValues
+	filePath   string
+	variables  string // GraphQL variables, a JSON object
+	operation  string // GraphQL operation name
+	schema     *models.GraphQLSchema // cached schema of the GraphQL endpoint
+	schemaInfo string               // progress or error of fetching the schema
+	services   []models.GRPCService // services of the gRPC server
+	grpcInfo   string               // progress or error of loading the services
+	selected   int
+	entry      int // header or query parameter under the cursor
+	width      int
+	height     int
+	service    *app.Service
+	loading    bool
+	events     int // events streamed so far by the request in flight
+	cancel     context.CancelFunc // cancels the request in flight
+	execution  *models.Execution // the last run of the request
+	error      string
+	urlInput   *InputModel
+	entryInput *InputModel // adds a header or query parameter
+	bodyInput  *InputModel
+	inputMode  bool
 }
 
+// bodyTypes lists the body types in the order "t" cycles through them
+var bodyTypes = []string{"json", "xml", "form", "raw", "multipart", "urlencoded", "binary", "graphql"}
+
 // NewRequestModel creates a new request model
 func NewRequestModel(service *app.Service) *RequestModel {
 	req := service.CreateNewRequest()
-	return &RequestModel{
-		request:   req,
-		method:    req.Method,
-		url:       req.URL,
-		headers:   req.Headers,
-		body:      "",
-		bodyType:  "json",
-		selected:  0,
-		service:   service,
-		loading:   false,
-		response:  nil,
-		error:     "",
-		urlInput:  NewInputModel("Enter URL (e.g., https://httpbin.org/get)"),
-		inputMode: false,
+	r := &RequestModel{
+		request:    req,
+		method:     req.Method,
+		url:        req.URL,
+		headers:    req.Headers,
+		params:     req.QueryParams,
+		body:       "",
+		bodyType:   "json",
+		selected:   0,
+		service:    service,
+		loading:    false,
+		execution:  nil,
+		error:      "",
+		urlInput:   NewInputModel("Enter URL (e.g., https://httpbin.org/get)"),
+		entryInput: NewInputModel(""),
+		bodyInput:  NewInputModel("Enter request body"),
+		inputMode:  false,
+	}
+	if req.Body != nil {
+		r.bodyType = req.Body.Type
+		r.body = req.Body.Content
+		r.parts = req.Body.Parts
+		r.fields = req.Body.Fields
+		r.filePath = req.Body.FilePath
+		r.variables = req.Body.Variables
+		r.operation = req.Body.OperationName
+		if req.Body.Type == "graphql" {
+			r.body = req.Body.Query
+		}
 	}
+	return r
 }
 
 // Init initializes the request model
@@ -64,17 +99,47 @@ func (r *RequestModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "esc":
 				r.inputMode = false
 				r.urlInput.Blur()
+				r.entryInput.Blur()
+				r.bodyInput.Blur()
 				return r, nil
 			case "enter":
-				r.url = r.urlInput.Value()
+				switch r.selected {
+				case 2, 3:
+					if err := r.applyEntryInput(r.entryInput.Value()); err != nil {
+						r.error = err.Error()
+						return r, nil
+					}
+					r.error = ""
+				case 4:
+					if err := r.applyBodyInput(r.bodyInput.Value()); err != nil {
+						r.error = err.Error()
+						return r, nil
+					}
+					r.error = ""
+				default:
+					r.url = r.urlInput.Value()
+					r.loadSchema()
+				}
 				r.inputMode = false
 				r.urlInput.Blur()
+				r.entryInput.Blur()
+				r.bodyInput.Blur()
 				r.updateRequest()
 				return r, nil
 			}
 		}
-		
+
 		// Update the input model
+		switch r.selected {
+		case 2, 3:
+			model, cmd := r.entryInput.Update(msg)
+			r.entryInput = model.(*InputModel)
+			return r, cmd
+		case 4:
+			model, cmd := r.bodyInput.Update(msg)
+			r.bodyInput = model.(*InputModel)
+			return r, cmd
+		}
 		model, cmd := r.urlInput.Update(msg)
 		r.urlInput = model.(*InputModel)
 		return r, cmd
@@ -88,10 +153,67 @@ func (r *RequestModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "up", "k":
 			if r.selected > 0 {
 				r.selected--
+				r.entry = 0
 			}
 		case "down", "j":
-			if r.selected < 4 {
+			if r.selected < 5 {
 				r.selected++
+				r.entry = 0
+			}
+		case "left":
+			if r.entry > 0 {
+				r.entry--
+			}
+		case "right":
+			if entries := r.selectedEntries(); entries != nil && r.entry < len(*entries)-1 {
+				r.entry++
+			}
+		case " ":
+			// Toggle the header or query parameter under the cursor
+			if entries := r.selectedEntries(); entries != nil && r.entry < len(*entries) {
+				(*entries)[r.entry].Enabled = !(*entries)[r.entry].Enabled
+				r.updateRequest()
+			}
+		case "x":
+			// Abort the request in flight
+			if r.loading && r.cancel != nil {
+				r.cancel()
+			}
+		case "t":
+			if r.selected == 4 {
+				r.bodyType = r.cycleBodyType()
+				r.updateRequest()
+				r.loadSchema()
+			}
+		case "i":
+			// Fetch the schema of the GraphQL endpoint, or list the methods
+			// of the gRPC server
+			if r.selected == 4 && r.bodyType == "graphql" && r.schemaInfo != "Fetching schema..." {
+				return r, r.fetchSchema()
+			}
+			if r.selected == 1 && r.request.IsGRPC() && r.grpcInfo != "Loading services..." {
+				return r, r.loadServices()
+			}
+		case "backspace", "d":
+			// Remove the header or query parameter under the cursor, or the
+			// last multipart part or urlencoded field
+			switch r.selected {
+			case 2, 3:
+				if entries := r.selectedEntries(); r.entry < len(*entries) {
+					*entries = append((*entries)[:r.entry], (*entries)[r.entry+1:]...)
+					if r.entry > 0 && r.entry >= len(*entries) {
+						r.entry--
+					}
+				}
+				r.updateRequest()
+			case 4:
+				switch {
+				case r.bodyType == "multipart" && len(r.parts) > 0:
+					r.parts = r.parts[:len(r.parts)-1]
+				case r.bodyType == "urlencoded" && len(r.fields) > 0:
+					r.fields = r.fields[:len(r.fields)-1]
+				}
+				r.updateRequest()
 			}
 		case "enter":
 			switch r.selected {
@@ -103,23 +225,50 @@ func (r *RequestModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				r.urlInput.SetValue(r.url)
 				r.urlInput.Focus()
 			case 2: // Headers
-				// TODO: Implement header editing
-			case 3: // Body
-				// TODO: Implement body editing
-			case 4: // Send
+				r.inputMode = true
+				r.entryInput.placeholder = "Name: value"
+				r.entryInput.SetValue("")
+				r.entryInput.Focus()
+			case 3: // Query parameters
+				r.inputMode = true
+				r.entryInput.placeholder = "key=value"
+				r.entryInput.SetValue("")
+				r.entryInput.Focus()
+			case 4: // Body
+				r.inputMode = true
+				switch r.bodyType {
+				case "multipart":
+					r.bodyInput.placeholder = "name=value or name=@path, optionally ;type=... ;filename=..."
+					r.bodyInput.SetValue("")
+				case "urlencoded":
+					r.bodyInput.placeholder = "key=value"
+					r.bodyInput.SetValue("")
+				case "binary":
+					r.bodyInput.placeholder = "Path of the file to send"
+					r.bodyInput.SetValue(r.filePath)
+				case "graphql":
+					r.bodyInput.placeholder = "Query, or variables {...}, or operation Name"
+					r.bodyInput.SetValue(r.body)
+				default:
+					r.bodyInput.placeholder = "Enter request body"
+					r.bodyInput.SetValue(r.body)
+				}
+				r.bodyInput.Focus()
+			case 5: // Send
 				if !r.loading {
 					return r, r.sendRequest()
 				}
 			}
 		}
 	case RequestSentMsg:
-		if msg.Response != nil {
-			r.response = msg.Response
+		if msg.Execution != nil {
+			r.execution = msg.Execution
 			r.error = ""
 		} else {
 			r.error = msg.Error
 		}
 		r.loading = false
+		r.cancel = nil
 	}
 
 	return r, nil
@@ -146,37 +295,93 @@ func (r *RequestModel) View() string {
 	if r.selected == 1 {
 		urlStyle = urlStyle.Bold(true).Foreground(lipgloss.Color("#7D56F4"))
 	}
-	
+
 	var urlText string
-	if r.inputMode {
+	if r.inputMode && r.selected == 1 {
 		urlText = urlStyle.Render("URL: " + r.urlInput.View())
 	} else {
 		urlText = urlStyle.Render(fmt.Sprintf("URL: %s", r.url))
 	}
+	if r.request.IsGRPC() {
+		urlText += "\n" + r.servicesView()
+	}
 
 	// Headers
 	headersStyle := lipgloss.NewStyle()
 	if r.selected == 2 {
 		headersStyle = headersStyle.Bold(true).Foreground(lipgloss.Color("#7D56F4"))
 	}
-	headersText := headersStyle.Render("Headers: (Press Enter to edit)")
+	var headersText string
+	if r.inputMode && r.selected == 2 {
+		headersText = headersStyle.Render("Headers: " + r.entryInput.View())
+	} else {
+		lines := []string{headersStyle.Render(fmt.Sprintf("Headers: %d (Press Enter to add)", len(r.headers)))}
+		lines = append(lines, r.entriesView(r.headers, ": ", r.selected == 2)...)
+		headersText = strings.Join(lines, "\n")
+	}
+
+	// Query parameters
+	paramsStyle := lipgloss.NewStyle()
+	if r.selected == 3 {
+		paramsStyle = paramsStyle.Bold(true).Foreground(lipgloss.Color("#7D56F4"))
+	}
+	var paramsText string
+	if r.inputMode && r.selected == 3 {
+		paramsText = paramsStyle.Render("Params: " + r.entryInput.View())
+	} else {
+		lines := []string{paramsStyle.Render(fmt.Sprintf("Params: %d (Press Enter to add)", len(r.params)))}
+		lines = append(lines, r.entriesView(r.params, "=", r.selected == 3)...)
+		paramsText = strings.Join(lines, "\n")
+	}
 
 	// Body
 	bodyStyle := lipgloss.NewStyle()
-	if r.selected == 3 {
+	if r.selected == 4 {
 		bodyStyle = bodyStyle.Bold(true).Foreground(lipgloss.Color("#7D56F4"))
 	}
-	bodyText := bodyStyle.Render(fmt.Sprintf("Body (%s): %s", r.bodyType, r.body))
+	var bodyText string
+	switch {
+	case r.inputMode && r.selected == 4:
+		bodyText = bodyStyle.Render(fmt.Sprintf("Body (%s): %s", r.bodyType, r.bodyInput.View()))
+	case r.bodyType == "multipart":
+		lines := []string{bodyStyle.Render(fmt.Sprintf("Body (%s): %d parts", r.bodyType, len(r.parts)))}
+		for _, part := range r.parts {
+			lines = append(lines, "  "+formatBodyPart(part))
+		}
+		bodyText = strings.Join(lines, "\n")
+	case r.bodyType == "urlencoded":
+		lines := []string{bodyStyle.Render(fmt.Sprintf("Body (%s): %d fields", r.bodyType, len(r.fields)))}
+		for _, field := range r.fields {
+			lines = append(lines, fmt.Sprintf("  %s=%s", field.Key, field.Value))
+		}
+		bodyText = strings.Join(lines, "\n")
+	case r.bodyType == "binary":
+		bodyText = bodyStyle.Render(fmt.Sprintf("Body (%s): %s", r.bodyType, r.filePath))
+	case r.bodyType == "graphql":
+		lines := []string{bodyStyle.Render(fmt.Sprintf("Body (%s): %s", r.bodyType, r.body))}
+		if r.variables != "" {
+			lines = append(lines, "  variables "+r.variables)
+		}
+		if r.operation != "" {
+			lines = append(lines, "  operation "+r.operation)
+		}
+		lines = append(lines, "  "+r.schemaView())
+		bodyText = strings.Join(lines, "\n")
+	default:
+		bodyText = bodyStyle.Render(fmt.Sprintf("Body (%s): %s", r.bodyType, r.body))
+	}
 
 	// Send button
 	sendStyle := lipgloss.NewStyle()
-	if r.selected == 4 {
+	if r.selected == 5 {
 		sendStyle = sendStyle.Bold(true).Foreground(lipgloss.Color("#7D56F4"))
 	}
-	
+
 	sendText := "Send Request"
-	if r.loading {
-		sendText = "Sending..."
+	if r.loading && r.events > 0 {
+		sendText = fmt.Sprintf("Streaming... %d events (x to cancel)", r.events)
+	} else if r.loading {
+		sendText = "Sending... (x to cancel)"
 	}
 	sendText = sendStyle.Render(sendText)
 
@@ -184,20 +389,28 @@ func (r *RequestModel) View() string {
 		methodText,
 		urlText,
 		headersText,
+		paramsText,
 		bodyText,
 		sendText,
 	}, "\n")
 
 	// Add response/error display
-	if r.response != nil {
-		bodyPreview := r.response.Body
+	if r.execution != nil {
+		response := r.execution.Response
+		bodyPreview := response.Body
+		if response.Binary {
+			bodyPreview = fmt.Sprintf("binary %s, %d bytes", response.ContentType, response.Size)
+		}
 		if len(bodyPreview) > 100 {
 			bodyPreview = bodyPreview[:100] + "..."
 		}
-		responseText := fmt.Sprintf("\n\nResponse: %d - %s", r.response.StatusCode, bodyPreview)
+		responseText := fmt.Sprintf("\n\nResponse: %d - %s", response.StatusCode, bodyPreview)
+		if summary := testSummary(r.execution); summary != "" {
+			responseText += "\n" + summary
+		}
 		content += responseText
 	}
-	
+
 	if r.error != "" {
 		errorText := fmt.Sprintf("\n\nError: %s", r.error)
 		content += errorText
@@ -211,7 +424,7 @@ func (r *RequestModel) View() string {
 
 	help := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#626262")).
-		Render("Use arrow keys to navigate, Enter to select, Esc to go back")
+		Render("Use arrow keys to navigate, Enter to select, ←/→ to pick a header or param, Space to toggle it, d to remove it, t to change body type, i to fetch a GraphQL schema or gRPC services, Esc to go back")
 
 	return lipgloss.JoinVertical(
 		lipgloss.Center,
@@ -235,51 +448,402 @@ func (r *RequestModel) cycleMethod() string {
 	return "GET"
 }
 
-// sendRequest sends the HTTP request
+// cycleBodyType cycles through body types
+func (r *RequestModel) cycleBodyType() string {
+	for i, bodyType := range bodyTypes {
+		if bodyType == r.bodyType {
+			return bodyTypes[(i+1)%len(bodyTypes)]
+		}
+	}
+	return bodyTypes[0]
+}
+
+// selectedEntries returns the headers or query parameters when their row is
+// selected, or nil
+func (r *RequestModel) selectedEntries() *models.KeyValues {
+	switch r.selected {
+	case 2:
+		return &r.headers
+	case 3:
+		return &r.params
+	}
+	return nil
+}
+
+// entriesView renders headers or query parameters one per line, greying out
+// disabled ones and marking the cursor when the list is focused
+func (r *RequestModel) entriesView(entries models.KeyValues, separator string, focused bool) []string {
+	disabledStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
+	descriptionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+
+	lines := make([]string, 0, len(entries))
+	for i, entry := range entries {
+		cursor := "  "
+		if focused && i == r.entry {
+			cursor = "> "
+		}
+		check := "[x] "
+		if !entry.Enabled {
+			check = "[ ] "
+		}
+		text := check + entry.Key + separator + entry.Value
+		if !entry.Enabled {
+			text = disabledStyle.Render(text)
+		}
+		if entry.Description != "" {
+			text += descriptionStyle.Render("  # " + entry.Description)
+		}
+		lines = append(lines, cursor+text)
+	}
+	return lines
+}
+
+// applyEntryInput adds the entry editor's value as a header ("Name: value") or
+// query parameter ("key=value"), depending on the selected row
+func (r *RequestModel) applyEntryInput(value string) error {
+	if value == "" {
+		return nil
+	}
+	if r.selected == 2 {
+		name, val, ok := strings.Cut(value, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("header must be Name: value")
+		}
+		r.headers.Add(strings.TrimSpace(name), strings.TrimSpace(val))
+		return nil
+	}
+	key, val, _ := strings.Cut(value, "=")
+	r.params.Add(key, val)
+	return nil
+}
+
+// applyBodyInput stores the body editor's value: the content for text bodies,
+// or one more part or field for multipart and urlencoded bodies
+func (r *RequestModel) applyBodyInput(value string) error {
+	switch r.bodyType {
+	case "multipart":
+		if value == "" {
+			return nil
+		}
+		part, err := parseBodyPart(value)
+		if err != nil {
+			return err
+		}
+		r.parts = append(r.parts, part)
+	case "urlencoded":
+		if value == "" {
+			return nil
+		}
+		key, val, _ := strings.Cut(value, "=")
+		r.fields.Add(key, val)
+	case "binary":
+		r.filePath = strings.TrimSpace(value)
+	case "graphql":
+		if v, ok := strings.CutPrefix(value, "variables "); ok {
+			r.variables = strings.TrimSpace(v)
+		} else if v, ok := strings.CutPrefix(value, "operation "); ok {
+			r.operation = strings.TrimSpace(v)
+		} else {
+			r.body = value
+		}
+	default:
+		r.body = value
+	}
+	return nil
+}
+
+// loadSchema looks up the cached schema of the GraphQL endpoint
+func (r *RequestModel) loadSchema() {
+	r.schema, r.schemaInfo = nil, ""
+	if r.bodyType != "graphql" || r.url == "" {
+		return
+	}
+	schema, err := r.service.GetGraphQLSchema(r.url)
+	if err != nil {
+		r.schemaInfo = err.Error()
+		return
+	}
+	r.schema = schema
+}
+
+// fetchSchema introspects the GraphQL endpoint in the background
+func (r *RequestModel) fetchSchema() tea.Cmd {
+	r.updateRequest()
+	r.schemaInfo = "Fetching schema..."
+	request := r.request.Clone()
+	return func() tea.Msg {
+		schema, err := r.service.FetchGraphQLSchema(context.Background(), request)
+		if err != nil {
+			return GraphQLSchemaMsg{URL: request.URL, Error: err.Error()}
+		}
+		return GraphQLSchemaMsg{URL: request.URL, Schema: schema}
+	}
+}
+
+// SchemaFetched records the result of fetching a GraphQL schema, unless the
+// URL has changed since
+func (r *RequestModel) SchemaFetched(msg GraphQLSchemaMsg) {
+	if msg.URL != r.url {
+		return
+	}
+	r.schema = msg.Schema
+	r.schemaInfo = msg.Error
+}
+
+// schemaView describes the cached GraphQL schema
+func (r *RequestModel) schemaView() string {
+	switch {
+	case r.schemaInfo != "":
+		return "schema: " + r.schemaInfo
+	case r.schema == nil:
+		return "schema: not fetched, queries are sent without validation (i to fetch)"
+	}
+	types := 0
+	for _, t := range r.schema.Types {
+		if !strings.HasPrefix(t.Name, "__") {
+			types++
+		}
+	}
+	return fmt.Sprintf("schema: %d types, fetched %s (i to refetch)", types, r.schema.FetchedAt.Format("2006-01-02 15:04"))
+}
+
+// loadServices describes the services of the gRPC server in the background
+func (r *RequestModel) loadServices() tea.Cmd {
+	r.updateRequest()
+	r.grpcInfo = "Loading services..."
+	request := r.request.Clone()
+	return func() tea.Msg {
+		services, err := r.service.DescribeGRPC(context.Background(), request)
+		if err != nil {
+			return GRPCServicesMsg{URL: request.URL, Error: err.Error()}
+		}
+		return GRPCServicesMsg{URL: request.URL, Services: services}
+	}
+}
+
+// ServicesLoaded records the services of a gRPC server, unless the URL has
+// changed since
+func (r *RequestModel) ServicesLoaded(msg GRPCServicesMsg) {
+	if msg.URL != r.url {
+		return
+	}
+	r.services = msg.Services
+	r.grpcInfo = msg.Error
+}
+
+// servicesView lists the methods of the gRPC server, which the URL's path names
+func (r *RequestModel) servicesView() string {
+	switch {
+	case r.grpcInfo != "":
+		return "  services: " + r.grpcInfo
+	case r.services == nil:
+		return "  services: not loaded (i to load)"
+	}
+	var lines []string
+	for _, service := range r.services {
+		for _, method := range service.Methods {
+			kind := "unary"
+			switch {
+			case method.ClientStreaming && method.ServerStreaming:
+				kind = "bidi streaming"
+			case method.ClientStreaming:
+				kind = "client streaming"
+			case method.ServerStreaming:
+				kind = "server streaming"
+			}
+			lines = append(lines, fmt.Sprintf("  /%s (%s, %s)", method.FullName, method.InputType, kind))
+		}
+	}
+	if len(lines) == 0 {
+		return "  services: none"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseBodyPart parses a multipart part written like curl's -F option:
+// "name=value" for text, "name=@path" for a file, followed by optional
+// ";type=content/type" and ";filename=name" attributes
+func parseBodyPart(spec string) (models.BodyPart, error) {
+	name, rest, ok := strings.Cut(spec, "=")
+	if !ok || name == "" {
+		return models.BodyPart{}, fmt.Errorf("part must be name=value or name=@path")
+	}
+
+	part := models.BodyPart{Name: name, Type: "text"}
+	if strings.HasPrefix(rest, "@") {
+		part.Type = "file"
+		rest = rest[1:]
+	}
+
+	// Only split attributes off when they are recognised, so text values may contain ';'
+	value := rest
+	for {
+		i := strings.LastIndex(value, ";")
+		if i < 0 {
+			break
+		}
+		attr := value[i+1:]
+		if v, ok := strings.CutPrefix(attr, "type="); ok {
+			part.ContentType = v
+		} else if v, ok := strings.CutPrefix(attr, "filename="); ok {
+			part.Filename = v
+		} else {
+			break
+		}
+		value = value[:i]
+	}
+
+	if part.Type == "file" {
+		part.FilePath = value
+	} else {
+		part.Value = value
+	}
+	return part, nil
+}
+
+// formatBodyPart renders a multipart part in the syntax parseBodyPart accepts
+func formatBodyPart(part models.BodyPart) string {
+	text := part.Name + "=" + part.Value
+	if part.Type == "file" {
+		text = part.Name + "=@" + part.FilePath
+	}
+	if part.ContentType != "" {
+		text += ";type=" + part.ContentType
+	}
+	if part.Filename != "" {
+		text += ";filename=" + part.Filename
+	}
+	return text
+}
+
+// sendRequest sends the HTTP request. Events of a streamed response arrive as
+// EventReceivedMsg before the final RequestSentMsg. A ws:// or wss:// request
+// opens a session in the WebSocket view instead.
 func (r *RequestModel) sendRequest() tea.Cmd {
+	r.updateRequest()
+	if r.request.IsWebSocket() {
+		request := r.request
+		return func() tea.Msg {
+			return OpenWebSocketMsg{Request: request}
+		}
+	}
+
 	r.loading = true
+	r.events = 0
 	r.error = ""
-	
-	return func() tea.Msg {
-		// Update request with current values
-		r.updateRequest()
-		
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	// Update request with current values
+	r.updateRequest()
+	request := r.request
+
+	updates := make(chan tea.Msg, 64)
+	go func() {
+		defer close(updates)
+		defer cancel()
+
+		ctx := app.WithEventHandler(ctx, func(event models.ServerSentEvent) {
+			updates <- EventReceivedMsg{Event: event, updates: updates}
+		})
+
 		// Execute the request
-		response, err := r.service.ExecuteRequest(r.request)
-		
+		execution, err := r.service.ExecuteRequest(ctx, request)
+
 		if err != nil {
-			return RequestSentMsg{
-				Request:  r.request,
-				Response: nil,
-				Error:    err.Error(),
+			updates <- RequestSentMsg{
+				Request:   request,
+				Execution: nil,
+				Error:     err.Error(),
 			}
+			return
 		}
-		
-		return RequestSentMsg{
-			Request:  r.request,
-			Response: response,
-			Error:    "",
+
+		updates <- RequestSentMsg{
+			Request:   request,
+			Execution: execution,
+			Error:     "",
 		}
+	}()
+	return waitForUpdate(updates)
+}
+
+// waitForUpdate delivers the next message about the request in flight
+func waitForUpdate(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
 	}
 }
 
 // RequestSentMsg represents a message when a request is sent
 type RequestSentMsg struct {
-	Request  *models.Request
-	Response *models.Response
+	Request   *models.Request
+	Execution *models.Execution
+	Error     string
+}
+
+// GraphQLSchemaMsg carries the result of fetching a GraphQL schema
+type GraphQLSchemaMsg struct {
+	URL    string
+	Schema *models.GraphQLSchema
+	Error  string
+}
+
+// GRPCServicesMsg carries the result of describing a gRPC server's services
+type GRPCServicesMsg struct {
+	URL      string
+	Services []models.GRPCService
 	Error    string
 }
 
+// EventReceivedMsg carries an event of a streamed response as it arrives
+type EventReceivedMsg struct {
+	Event   models.ServerSentEvent
+	updates <-chan tea.Msg
+}
+
+// Next waits for the following message about the same request
+func (m EventReceivedMsg) Next() tea.Cmd {
+	return waitForUpdate(m.updates)
+}
+
 // updateRequest updates the request model with current values
 func (r *RequestModel) updateRequest() {
 	r.request.Method = r.method
 	r.request.URL = r.url
 	r.request.Headers = r.headers
-	
-	if r.body != "" {
+	r.request.QueryParams = r.params
+
+	switch {
+	case r.bodyType == "multipart" && len(r.parts) > 0:
+		r.request.Body = &models.RequestBody{
+			Type:  r.bodyType,
+			Parts: r.parts,
+		}
+	case r.bodyType == "urlencoded" && len(r.fields) > 0:
+		r.request.Body = &models.RequestBody{
+			Type:   r.bodyType,
+			Fields: r.fields,
+		}
+	case r.bodyType == "binary" && r.filePath != "":
+		r.request.Body = &models.RequestBody{
+			Type:     r.bodyType,
+			FilePath: r.filePath,
+		}
+	case r.bodyType == "graphql" && r.body != "":
+		r.request.Body = &models.RequestBody{
+			Type:          r.bodyType,
+			Query:         r.body,
+			Variables:     r.variables,
+			OperationName: r.operation,
+		}
+	case r.bodyType != "multipart" && r.bodyType != "urlencoded" && r.bodyType != "binary" && r.bodyType != "graphql" && r.body != "":
 		r.request.Body = &models.RequestBody{
 			Type:    r.bodyType,
 			Content: r.body,
 		}
+	default:
+		r.request.Body = nil
 	}
 }
@@ -2,34 +2,55 @@ package ui
 
 import (
 	"fmt"
+	"mime"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"postgirl/internal/app"
 	"postgirl/internal/models"
 )
 
 // ResponseModel represents the response viewer UI
 type ResponseModel struct {
-	response *models.Response
-	selected int
-	width    int
-	height   int
+	response  *models.Response
+	execution *models.Execution // the run the response came from, with its test results
+	selected  int
+	tab       int
+	width     int
+	height    int
+	service   *app.Service
+	saveInput *InputModel
+	inputMode bool
+	status    string
+	live      []models.ServerSentEvent // events of the stream in flight
+	wire      bool                     // show the body as received, before decoding
 }
 
+// responseTabs are the views of the response viewer, switched with Tab
+var responseTabs = []string{"Overview", "Tests", "Console", "Sent", "Connection", "Redirects", "Events", "gRPC"}
+
+// maxEventLines bounds how many of the latest events the Events view shows
+const maxEventLines = 20
+
+// maxWireLines bounds how many lines of the hex dump of a raw body are shown
+const maxWireLines = 16
+
 // NewResponseModel creates a new response model
-func NewResponseModel() *ResponseModel {
+func NewResponseModel(service *app.Service) *ResponseModel {
 	return &ResponseModel{
 		response: &models.Response{
 			ID:         "new",
 			RequestID:  "new",
 			StatusCode: 200,
-			Headers:    make(map[string]string),
 			Body:       "No response yet",
 			Size:       0,
 			Duration:   0,
 		},
-		selected: 0,
+		selected:  0,
+		service:   service,
+		saveInput: NewInputModel("Save body to file"),
 	}
 }
 
@@ -38,13 +59,71 @@ func (r *ResponseModel) Init() tea.Cmd {
 	return nil
 }
 
+// SetExecution replaces the run being viewed
+func (r *ResponseModel) SetExecution(exec *models.Execution) {
+	r.execution = exec
+	r.response = exec.Response
+	r.status = strings.Join(exec.Warnings, "\n")
+	r.live = nil
+	r.wire = false
+}
+
+// AddEvent shows an event of the stream in flight until its response arrives
+func (r *ResponseModel) AddEvent(event models.ServerSentEvent) {
+	r.live = append(r.live, event)
+}
+
 // Update handles messages for the response model
 func (r *ResponseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Handle the save-to-file prompt
+	if r.inputMode {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "esc":
+				r.inputMode = false
+				r.saveInput.Blur()
+				return r, nil
+			case "enter":
+				r.inputMode = false
+				r.saveInput.Blur()
+				path := strings.TrimSpace(r.saveInput.Value())
+				if path == "" {
+					return r, nil
+				}
+				if err := r.service.SaveResponseBody(r.response, path); err != nil {
+					r.status = err.Error()
+				} else {
+					r.status = fmt.Sprintf("Saved %d bytes to %s", r.response.Size, path)
+				}
+				return r, nil
+			}
+		}
+
+		model, cmd := r.saveInput.Update(msg)
+		r.saveInput = model.(*InputModel)
+		return r, cmd
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "esc":
 			return r, nil
+		case "s":
+			if r.service != nil && r.response.ID != "new" {
+				r.inputMode = true
+				r.saveInput.SetValue(defaultResponseFilename(r.response))
+				r.saveInput.Focus()
+			}
+		case "w":
+			// Toggle the raw view of a compressed body
+			if r.response.ContentEncoding != "" {
+				r.wire = !r.wire
+			}
+		case "tab":
+			r.tab = (r.tab + 1) % len(responseTabs)
+		case "shift+tab":
+			r.tab = (r.tab + len(responseTabs) - 1) % len(responseTabs)
 		case "up", "k":
 			if r.selected > 0 {
 				r.selected--
@@ -75,7 +154,14 @@ func (r *ResponseModel) View() string {
 	if r.selected == 0 {
 		statusStyle = statusStyle.Bold(true).Foreground(lipgloss.Color("#7D56F4"))
 	}
-	statusText := statusStyle.Render(fmt.Sprintf("Status: %d", r.response.StatusCode))
+	status := fmt.Sprintf("Status: %d", r.response.StatusCode)
+	if r.response.GRPC != nil {
+		status = fmt.Sprintf("Status: %s (%d)", r.response.GRPC.Status, r.response.GRPC.Code)
+	}
+	if r.response.Error != "" {
+		status += " (" + r.response.Error + ")"
+	}
+	statusText := statusStyle.Render(status)
 
 	// Headers
 	headersStyle := lipgloss.NewStyle()
@@ -89,13 +175,56 @@ func (r *ResponseModel) View() string {
 	if r.selected == 2 {
 		bodyStyle = bodyStyle.Bold(true).Foreground(lipgloss.Color("#7D56F4"))
 	}
-	bodyText := bodyStyle.Render(fmt.Sprintf("Body: %s", r.response.Body))
+	var bodyText string
+	if r.wire {
+		bodyText = bodyStyle.Render("Body: "+r.response.ContentEncoding+" as received (w to decode)") + "\n" + r.wireView()
+	} else if r.response.Binary {
+		bodyText = bodyStyle.Render(fmt.Sprintf("Body: binary %s, %d bytes (press s to save)", r.response.ContentType, r.response.Size))
+	} else {
+		bodyText = bodyStyle.Render(fmt.Sprintf("Body: %s", r.response.Body))
+	}
 
-	content := strings.Join([]string{
+	lines := []string{
 		statusText,
 		headersText,
 		bodyText,
-	}, "\n")
+		"",
+		r.sizesView(),
+		"",
+		r.timingView(),
+	}
+	if r.inputMode {
+		lines = append(lines, "", "Save to: "+r.saveInput.View())
+	} else if r.status != "" {
+		lines = append(lines, "", r.status)
+	}
+	content := strings.Join(lines, "\n")
+	switch responseTabs[r.tab] {
+	case "Tests":
+		content = r.testsView()
+	case "Console":
+		content = r.consoleView()
+	case "Sent":
+		content = r.sentView()
+	case "Connection":
+		content = r.connectionView()
+	case "Redirects":
+		content = r.redirectsView()
+	case "Events":
+		content = r.eventsView()
+	case "gRPC":
+		content = r.grpcView()
+	}
+
+	var tabs []string
+	for i, name := range responseTabs {
+		style := lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("#626262"))
+		if i == r.tab {
+			style = style.Bold(true).Foreground(lipgloss.Color("#7D56F4")).Underline(true)
+		}
+		tabs = append(tabs, style.Render(name))
+	}
+	content = lipgloss.JoinHorizontal(lipgloss.Top, tabs...) + "\n\n" + content
 
 	menu := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
@@ -105,7 +234,7 @@ func (r *ResponseModel) View() string {
 
 	help := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#626262")).
-		Render("Use arrow keys to navigate, Enter to select, Esc to go back")
+		Render("Use arrow keys to navigate, Tab to switch view, Enter to select, s to save the body, w to view a compressed body raw, Esc to go back")
 
 	return lipgloss.JoinVertical(
 		lipgloss.Center,
@@ -116,3 +245,398 @@ func (r *ResponseModel) View() string {
 		help,
 	)
 }
+
+// sizesView renders the decoded, wire and header sizes of the response
+func (r *ResponseModel) sizesView() string {
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+	row := func(name, value string) string {
+		return fmt.Sprintf("  %s %s", labelStyle.Render(fmt.Sprintf("%-18s", name)), value)
+	}
+
+	lines := []string{"Size:", row("Body", fmt.Sprintf("%d bytes", r.response.Size))}
+	if r.response.ContentEncoding != "" {
+		ratio := 100
+		if r.response.Size > 0 {
+			ratio = int(100 * r.response.WireSize / r.response.Size)
+		}
+		lines = append(lines, row("On the wire", fmt.Sprintf("%d bytes %s (%d%%)", r.response.WireSize, r.response.ContentEncoding, ratio)))
+	}
+	if r.response.HeaderSize > 0 {
+		lines = append(lines, row("Headers", fmt.Sprintf("%d bytes", r.response.HeaderSize)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wireView renders the start of the body as received as a hex dump
+func (r *ResponseModel) wireView() string {
+	wire, err := r.service.ResponseWireBody(r.response)
+	if err != nil {
+		return "  " + err.Error()
+	}
+
+	var lines []string
+	for offset := 0; offset < len(wire) && len(lines) < maxWireLines; offset += 16 {
+		row := wire[offset:min(offset+16, len(wire))]
+		text := append([]byte(nil), row...)
+		for i, b := range text {
+			if b < 0x20 || b >= 0x7f {
+				text[i] = '.'
+			}
+		}
+		lines = append(lines, fmt.Sprintf("  %08x  %-47s  %s", offset, fmt.Sprintf("% x", row), text))
+	}
+	if remaining := len(wire) - 16*len(lines); remaining > 0 {
+		lines = append(lines, fmt.Sprintf("  ... %d more bytes", remaining))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// testsView renders the results of the request's tests, and errors of its
+// post-response script
+func (r *ResponseModel) testsView() string {
+	if r.execution == nil {
+		return "No tests have run"
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+	statusStyles := map[string]lipgloss.Style{
+		models.TestPassed:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#4CAF50")),
+		models.TestFailed:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#F44336")),
+		models.TestError:   lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF9800")),
+		models.TestSkipped: labelStyle,
+	}
+
+	var lines []string
+	for _, scriptErr := range r.execution.ScriptErrors {
+		lines = append(lines, statusStyles[models.TestError].Render("! "+scriptErr.Script+" script: ")+scriptErr.Message)
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+
+	results := r.execution.TestResults
+	if len(results) == 0 {
+		return strings.Join(append(lines, "No tests have run"), "\n")
+	}
+	lines = append(lines, testSummary(r.execution))
+	test := ""
+	for _, result := range results {
+		if result.Test != test {
+			test = result.Test
+			lines = append(lines, "", labelStyle.Render(test))
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s", statusStyles[result.Status].Render(fmt.Sprintf("%-7s", result.Status)), result.Name))
+		if result.Message != "" {
+			lines = append(lines, labelStyle.Render("          "+result.Message))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// testSummary counts a run's test results by status, or is empty if no
+// tests ran
+func testSummary(exec *models.Execution) string {
+	if len(exec.TestResults) == 0 {
+		return ""
+	}
+	counts := make(map[string]int)
+	for _, result := range exec.TestResults {
+		counts[result.Status]++
+	}
+	summary := fmt.Sprintf("Tests: %d/%d passed", counts[models.TestPassed], len(exec.TestResults))
+	for _, status := range []string{models.TestFailed, models.TestError, models.TestSkipped} {
+		if counts[status] > 0 {
+			summary += fmt.Sprintf(", %d %s", counts[status], status)
+		}
+	}
+	return summary
+}
+
+// consoleView renders what the request's scripts logged
+func (r *ResponseModel) consoleView() string {
+	if r.execution == nil || len(r.execution.Console) == 0 {
+		return "No console output"
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+	levelStyles := map[string]lipgloss.Style{
+		"warn":  lipgloss.NewStyle().Foreground(lipgloss.Color("#FF9800")),
+		"error": lipgloss.NewStyle().Foreground(lipgloss.Color("#F44336")),
+	}
+
+	lines := []string{"Console:"}
+	for _, entry := range r.execution.Console {
+		message := entry.Message
+		if style, ok := levelStyles[entry.Level]; ok {
+			message = style.Render(message)
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render(fmt.Sprintf("[%s]", entry.Script)), message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sentView renders the request as it was sent, after variables were
+// substituted and the pre-request script ran
+func (r *ResponseModel) sentView() string {
+	if r.execution == nil || r.execution.Request == nil {
+		return "No request has been sent"
+	}
+	req := r.execution.Request
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+	methodStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4"))
+
+	lines := []string{methodStyle.Render(req.Method) + " " + req.URL}
+	for _, param := range req.QueryParams.Enabled() {
+		lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render("?"+param.Key+"="), param.Value))
+	}
+	if headers := req.Headers.Enabled(); len(headers) > 0 {
+		lines = append(lines, "", "Headers:")
+		for _, header := range headers {
+			lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render(header.Key+":"), header.Value))
+		}
+	}
+	if req.Body != nil && req.Body.Content != "" {
+		lines = append(lines, "", fmt.Sprintf("Body (%s):", req.Body.Type), req.Body.Content)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// timingView renders the per-phase timing breakdown
+func (r *ResponseModel) timingView() string {
+	timing := r.response.Timing
+	phases := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"DNS Lookup", timing.DNSLookup},
+		{"TCP Connection", timing.TCPConnection},
+		{"TLS Handshake", timing.TLSHandshake},
+		{"Server Processing", timing.ServerProcessing},
+		{"Content Transfer", timing.ContentTransfer},
+		{"Total", timing.Total},
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+
+	lines := []string{"Timing:"}
+	for _, phase := range phases {
+		lines = append(lines, fmt.Sprintf("  %s %s",
+			labelStyle.Render(fmt.Sprintf("%-18s", phase.name)),
+			phase.duration.Round(time.Microsecond)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// connectionView renders the connection, TLS session and peer certificates
+func (r *ResponseModel) connectionView() string {
+	conn := r.response.Connection
+	if conn == nil {
+		return "No connection details"
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+	warningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFAA00")).Bold(true)
+	row := func(name, value string) string {
+		return fmt.Sprintf("  %s %s", labelStyle.Render(fmt.Sprintf("%-18s", name)), value)
+	}
+
+	var lines []string
+	warnings := append([]string(nil), conn.Warnings...)
+	if conn.TLS != nil {
+		warnings = append(warnings, conn.TLS.Warnings...)
+	}
+	for _, warning := range warnings {
+		lines = append(lines, warningStyle.Render("! "+warning))
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+
+	remote := conn.RemoteIP
+	if conn.RemotePort != 0 {
+		remote = fmt.Sprintf("%s:%d", conn.RemoteIP, conn.RemotePort)
+	}
+	lines = append(lines,
+		"Connection:",
+		row("Remote Address", remote),
+		row("Protocol", conn.Protocol),
+		row("Reused", fmt.Sprintf("%t", conn.Reused)),
+	)
+	if conn.Proxy != "" {
+		lines = append(lines, row("Proxy", conn.Proxy))
+	}
+
+	tls := conn.TLS
+	if tls == nil {
+		return strings.Join(lines, "\n")
+	}
+
+	alpn := tls.ALPN
+	if alpn == "" {
+		alpn = "-"
+	}
+	lines = append(lines,
+		"",
+		"TLS:",
+		row("Version", tls.Version),
+		row("Cipher Suite", tls.CipherSuite),
+		row("ALPN", alpn),
+		row("Server Name", tls.ServerName),
+		row("Resumed", fmt.Sprintf("%t", tls.Resumed)),
+	)
+
+	for i, cert := range tls.PeerCertificates {
+		lines = append(lines, "", fmt.Sprintf("Certificate %d:", i))
+		lines = append(lines,
+			row("Subject", cert.Subject),
+			row("Issuer", cert.Issuer),
+		)
+		if len(cert.SANs) > 0 {
+			lines = append(lines, row("SANs", strings.Join(cert.SANs, ", ")))
+		}
+		lines = append(lines,
+			row("Not Before", cert.NotBefore.Format(time.RFC1123)),
+			row("Not After", fmt.Sprintf("%s (%d days)", cert.NotAfter.Format(time.RFC1123), cert.DaysUntilExpiry)),
+			row("SHA-256", cert.FingerprintSHA256),
+		)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// redirectsView renders the redirect chain as a timeline ending in the final response
+func (r *ResponseModel) redirectsView() string {
+	hops := r.response.Redirects
+	if len(hops) == 0 {
+		return "No redirects were followed"
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+	statusStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF9800"))
+	repeatStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#F44336"))
+
+	lines := []string{"Redirects:"}
+	seen := make(map[string]bool)
+	for i, hop := range hops {
+		target := hop.Method + " " + hop.URL
+		line := fmt.Sprintf("  %2d. %s %s  %s",
+			i+1, statusStyle.Render(fmt.Sprintf("%d", hop.StatusCode)), target,
+			labelStyle.Render(hop.Timing.Total.Round(time.Microsecond).String()))
+		if seen[target] {
+			line += " " + repeatStyle.Render("(repeated)")
+		}
+		seen[target] = true
+		lines = append(lines, line, labelStyle.Render("      -> "+hop.Location))
+	}
+
+	finalStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#4CAF50"))
+	lines = append(lines, fmt.Sprintf("  %2d. %s final response  %s",
+		len(hops)+1, finalStyle.Render(fmt.Sprintf("%d", r.response.StatusCode)),
+		labelStyle.Render("total "+r.response.Timing.Total.Round(time.Microsecond).String())))
+	if r.response.Error != "" {
+		lines = append(lines, "", repeatStyle.Render("! "+r.response.Error))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// eventsView renders the latest events of a streamed response, live while it
+// is still streaming
+func (r *ResponseModel) eventsView() string {
+	events := r.response.Events
+	header := fmt.Sprintf("Events: %d", len(events))
+	if r.live != nil {
+		events = r.live
+		header = fmt.Sprintf("Streaming: %d events", len(events))
+	}
+	if len(events) == 0 {
+		return "No events were streamed"
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+	typeStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4"))
+
+	lines := []string{header}
+	start := max(len(events)-maxEventLines, 0)
+	if start > 0 {
+		lines = append(lines, labelStyle.Render(fmt.Sprintf("  ... %d earlier events", start)))
+	}
+	for _, event := range events[start:] {
+		eventType := event.Event
+		if eventType == "" {
+			eventType = "message"
+		}
+		line := fmt.Sprintf("  %s %s", labelStyle.Render(event.ReceivedAt.Format("15:04:05.000")), typeStyle.Render(eventType))
+		if event.ID != "" {
+			line += labelStyle.Render(" id=" + event.ID)
+		}
+		lines = append(lines, line)
+		for _, data := range strings.Split(event.Data, "\n") {
+			lines = append(lines, "      "+data)
+		}
+	}
+	if r.live == nil && r.response.Error != "" {
+		lines = append(lines, "", labelStyle.Render(r.response.Error))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// grpcView renders the status, trailers and messages of a gRPC call
+func (r *ResponseModel) grpcView() string {
+	result := r.response.GRPC
+	if result == nil {
+		return "Not a gRPC call"
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A8A8A8"))
+	statusStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#4CAF50"))
+	if result.Code != 0 {
+		statusStyle = statusStyle.Foreground(lipgloss.Color("#F44336"))
+	}
+	directionStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4"))
+
+	lines := []string{
+		fmt.Sprintf("%s %s", statusStyle.Render(fmt.Sprintf("%s (%d)", result.Status, result.Code)), labelStyle.Render(result.Method)),
+	}
+	if result.Message != "" {
+		lines = append(lines, "  "+result.Message)
+	}
+	for _, detail := range result.Details {
+		lines = append(lines, labelStyle.Render("  "+detail))
+	}
+
+	if len(result.Trailers) > 0 {
+		lines = append(lines, "", "Trailers:")
+		for _, trailer := range result.Trailers {
+			lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render(trailer.Key+":"), trailer.Value))
+		}
+	}
+
+	messages := result.Messages
+	lines = append(lines, "", fmt.Sprintf("Messages: %d", len(messages)))
+	start := max(len(messages)-maxEventLines, 0)
+	if start > 0 {
+		lines = append(lines, labelStyle.Render(fmt.Sprintf("  ... %d earlier messages", start)))
+	}
+	for _, message := range messages[start:] {
+		lines = append(lines, fmt.Sprintf("  %s %s %s",
+			labelStyle.Render(message.Time.Format("15:04:05.000")),
+			directionStyle.Render(message.Direction),
+			message.Data))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// defaultResponseFilename suggests a file name for saving a response body,
+// with an extension matching its content type
+func defaultResponseFilename(resp *models.Response) string {
+	name := "response-" + resp.ID
+	mediaType, _, err := mime.ParseMediaType(resp.ContentType)
+	if err != nil {
+		return name
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return name + exts[0]
+	}
+	return name
+}
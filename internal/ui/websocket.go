@@ -0,0 +1,374 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"postgirl/internal/app"
+	"postgirl/internal/models"
+)
+
+// webSocketLogLines is how many messages of the log are shown
+const webSocketLogLines = 20
+
+// WebSocketModel represents the WebSocket session UI
+type WebSocketModel struct {
+	request      *models.Request
+	session      *models.WebSocketSession
+	messages     []models.WebSocketMessage
+	template     int // next template "t" loads
+	connecting   bool
+	width        int
+	height       int
+	service      *app.Service
+	error        string
+	urlInput     *InputModel
+	messageInput *InputModel
+	editingURL   bool
+	inputMode    bool
+}
+
+// NewWebSocketModel creates a new WebSocket model
+func NewWebSocketModel(service *app.Service) *WebSocketModel {
+	return &WebSocketModel{
+		service:      service,
+		urlInput:     NewInputModel("ws://localhost:8080/socket"),
+		messageInput: NewInputModel("Message, with {{variables}}"),
+	}
+}
+
+// Init initializes the WebSocket model
+func (w *WebSocketModel) Init() tea.Cmd {
+	return nil
+}
+
+// SetRequest uses the request builder's request for the next session
+func (w *WebSocketModel) SetRequest(req *models.Request) {
+	w.request = req
+	if w.urlInput.Value() == "" || req.IsWebSocket() {
+		w.urlInput.SetValue(req.URL)
+	}
+}
+
+// open reports whether a session is open
+func (w *WebSocketModel) open() bool {
+	return w.session != nil && w.session.Open
+}
+
+// Update handles messages for the WebSocket model
+func (w *WebSocketModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Handle input mode
+	if w.inputMode {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "esc":
+				w.inputMode = false
+				w.urlInput.Blur()
+				w.messageInput.Blur()
+				return w, nil
+			case "enter":
+				w.inputMode = false
+				w.urlInput.Blur()
+				w.messageInput.Blur()
+				if w.editingURL {
+					return w, nil
+				}
+				w.send(w.messageInput.Value())
+				return w, nil
+			}
+		}
+
+		// Update the input model
+		input := w.messageInput
+		if w.editingURL {
+			input = w.urlInput
+		}
+		_, cmd := input.Update(msg)
+		return w, cmd
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return w, nil
+		case "u":
+			if !w.open() {
+				w.inputMode = true
+				w.editingURL = true
+				w.urlInput.Focus()
+			}
+		case "c":
+			if w.open() {
+				w.disconnect()
+				return w, nil
+			}
+			if !w.connecting {
+				return w, w.connect()
+			}
+		case "enter":
+			if w.open() {
+				w.inputMode = true
+				w.editingURL = false
+				w.messageInput.Focus()
+			}
+		case "t":
+			// Load the next saved template into the message
+			if templates := w.templates(); len(templates) > 0 {
+				w.template %= len(templates)
+				w.messageInput.SetValue(templates[w.template].Content)
+				w.template++
+			}
+		case "a":
+			// Save the message as a template on the request
+			if w.request != nil && w.messageInput.Value() != "" {
+				if w.request.WebSocket == nil {
+					w.request.WebSocket = &models.WebSocketConfig{}
+				}
+				w.request.WebSocket.Templates = append(w.request.WebSocket.Templates, models.MessageTemplate{
+					Name:    fmt.Sprintf("Template %d", len(w.request.WebSocket.Templates)+1),
+					Content: w.messageInput.Value(),
+				})
+			}
+		case "p":
+			if w.open() {
+				if err := w.service.PingWebSocket(w.session.ID); err != nil {
+					w.error = err.Error()
+				}
+			}
+		}
+	}
+
+	return w, nil
+}
+
+// templates returns the saved message templates of the request
+func (w *WebSocketModel) templates() []models.MessageTemplate {
+	if w.request == nil || w.request.WebSocket == nil {
+		return nil
+	}
+	return w.request.WebSocket.Templates
+}
+
+// send sends a message on the open session
+func (w *WebSocketModel) send(text string) {
+	if !w.open() {
+		return
+	}
+	if err := w.service.SendWebSocketMessage(w.session.ID, text); err != nil {
+		w.error = err.Error()
+		return
+	}
+	w.error = ""
+}
+
+// connect opens a session for the request with the entered URL. The session's
+// messages arrive as WebSocketMessageMsg until WebSocketClosedMsg.
+func (w *WebSocketModel) connect() tea.Cmd {
+	if w.request == nil {
+		w.error = "No request to connect with"
+		return nil
+	}
+	req := w.request.Clone()
+	req.Type = models.RequestTypeWebSocket
+	req.URL = w.urlInput.Value()
+
+	w.connecting = true
+	w.error = ""
+	service := w.service
+
+	updates := make(chan tea.Msg, 64)
+	go func() {
+		defer close(updates)
+
+		session, err := service.OpenWebSocket(context.Background(), req)
+		if err != nil {
+			updates <- WebSocketOpenedMsg{Error: err.Error(), updates: updates}
+			return
+		}
+		updates <- WebSocketOpenedMsg{Session: session, updates: updates}
+
+		seen := 0
+		for {
+			messages, open, changed, err := service.WatchWebSocket(session.ID, seen)
+			if err != nil {
+				updates <- WebSocketClosedMsg{Session: session}
+				return
+			}
+			for _, message := range messages {
+				updates <- WebSocketMessageMsg{Message: message, updates: updates}
+			}
+			seen += len(messages)
+			if !open {
+				if closed, err := service.GetWebSocketSession(session.ID); err == nil {
+					session = closed
+				}
+				updates <- WebSocketClosedMsg{Session: session}
+				return
+			}
+			<-changed
+		}
+	}()
+	return waitForUpdate(updates)
+}
+
+// disconnect closes the open session; WebSocketClosedMsg follows
+func (w *WebSocketModel) disconnect() {
+	go w.service.CloseWebSocket(w.session.ID)
+}
+
+// Opened records a session that opened, or the error that stopped it
+func (w *WebSocketModel) Opened(msg WebSocketOpenedMsg) {
+	w.connecting = false
+	if msg.Session == nil {
+		w.error = msg.Error
+		return
+	}
+	w.session = msg.Session
+	w.messages = nil
+	w.error = ""
+}
+
+// AddMessage appends a message to the log
+func (w *WebSocketModel) AddMessage(message models.WebSocketMessage) {
+	w.messages = append(w.messages, message)
+	if w.session != nil && message.Type == "pong" && message.RTT > 0 {
+		w.session.LastRTT = message.RTT
+	}
+}
+
+// Closed records that the session has closed
+func (w *WebSocketModel) Closed(session *models.WebSocketSession) {
+	w.session = session
+	w.session.Open = false
+	w.inputMode = false
+	w.messageInput.Blur()
+}
+
+// WebSocketOpenedMsg reports the outcome of opening a session
+type WebSocketOpenedMsg struct {
+	Session *models.WebSocketSession
+	Error   string
+	updates <-chan tea.Msg
+}
+
+// Next waits for the following message about the same session
+func (m WebSocketOpenedMsg) Next() tea.Cmd {
+	return waitForUpdate(m.updates)
+}
+
+// WebSocketMessageMsg carries a message sent or received on a session
+type WebSocketMessageMsg struct {
+	Message models.WebSocketMessage
+	updates <-chan tea.Msg
+}
+
+// Next waits for the following message about the same session
+func (m WebSocketMessageMsg) Next() tea.Cmd {
+	return waitForUpdate(m.updates)
+}
+
+// WebSocketClosedMsg reports that a session has closed
+type WebSocketClosedMsg struct {
+	Session *models.WebSocketSession
+}
+
+// OpenWebSocketMsg asks to open a WebSocket session for a request
+type OpenWebSocketMsg struct {
+	Request *models.Request
+}
+
+// View renders the WebSocket model
+func (w *WebSocketModel) View() string {
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Background(lipgloss.Color("#7D56F4")).
+		Padding(0, 1).
+		Render("WebSocket")
+
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
+
+	var status string
+	switch {
+	case w.connecting:
+		status = "Connecting..."
+	case w.open():
+		status = lipgloss.NewStyle().Foreground(lipgloss.Color("#04B575")).Render("Open")
+		if w.session.Subprotocol != "" {
+			status += fmt.Sprintf("  subprotocol %s", w.session.Subprotocol)
+		}
+		if w.session.LastRTT > 0 {
+			status += fmt.Sprintf("  last ping %s", w.session.LastRTT.Round(time.Microsecond))
+		}
+	case w.session != nil && w.session.Error != "":
+		status = "Closed: " + w.session.Error
+	case w.session != nil:
+		status = "Closed"
+	default:
+		status = "Disconnected"
+	}
+
+	lines := []string{
+		"URL: " + w.urlInput.View(),
+		"Status: " + status,
+		"",
+	}
+
+	messages := w.messages
+	if len(messages) > webSocketLogLines {
+		messages = messages[len(messages)-webSocketLogLines:]
+	}
+	for _, message := range messages {
+		arrow := "↓"
+		if message.Direction == "sent" {
+			arrow = "↑"
+		}
+		line := fmt.Sprintf("%s %s %-6s %s", message.Time.Format("15:04:05.000"), arrow, message.Type, message.Data)
+		if message.RTT > 0 {
+			line += fmt.Sprintf(" (%s)", message.RTT.Round(time.Microsecond))
+		}
+		if message.Type != "text" && message.Type != "binary" {
+			line = muted.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if len(w.messages) == 0 {
+		lines = append(lines, muted.Render("No messages yet"))
+	}
+
+	lines = append(lines, "", "Message: "+w.messageInput.View())
+	if templates := w.templates(); len(templates) > 0 {
+		names := make([]string, len(templates))
+		for i, template := range templates {
+			names[i] = template.Name
+		}
+		lines = append(lines, muted.Render("Templates: "+strings.Join(names, ", ")))
+	}
+
+	if w.error != "" {
+		lines = append(lines, "", fmt.Sprintf("Error: %s", w.error))
+	}
+
+	content := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#874BFD")).
+		Padding(1, 2).
+		Render(strings.Join(lines, "\n"))
+
+	help := muted.Render("'u' to edit URL, 'c' to connect/disconnect, Enter to type a message, 't' to load a template, 'a' to save it as one, 'p' to ping, Esc to go back")
+
+	return lipgloss.JoinVertical(
+		lipgloss.Center,
+		title,
+		"",
+		content,
+		"",
+		help,
+	)
+}
@@ -0,0 +1,156 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"postgirl/internal/models"
+)
+
+// finishedFeedTTL is how long the events of a finished execution stay
+// available to a browser that subscribes late
+const finishedFeedTTL = time.Minute
+
+// eventFeeds relays the server-sent events of running executions to the
+// browser, keyed by execution ID
+type eventFeeds struct {
+	mu    sync.Mutex
+	feeds map[string]*eventFeed
+}
+
+// eventFeed holds the events of one execution and wakes subscribers when more
+// arrive
+type eventFeed struct {
+	mu      sync.Mutex
+	events  []models.ServerSentEvent
+	started bool
+	done    bool
+	changed chan struct{}
+}
+
+// newEventFeeds creates an empty feed registry
+func newEventFeeds() *eventFeeds {
+	return &eventFeeds{feeds: make(map[string]*eventFeed)}
+}
+
+// get returns the feed for an execution, creating it if needed; the browser
+// may subscribe before or after the execution starts
+func (f *eventFeeds) get(id string) *eventFeed {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	feed, exists := f.feeds[id]
+	if !exists {
+		feed = &eventFeed{changed: make(chan struct{})}
+		f.feeds[id] = feed
+	}
+	return feed
+}
+
+// start returns the feed for an execution that is starting, and a function
+// that marks it finished
+func (f *eventFeeds) start(id string) (*eventFeed, func()) {
+	feed := f.get(id)
+	feed.mu.Lock()
+	if feed.done {
+		// The ID is being reused; start over
+		feed.mu.Unlock()
+		f.remove(id, feed)
+		feed = f.get(id)
+		feed.mu.Lock()
+	}
+	feed.started = true
+	feed.mu.Unlock()
+
+	return feed, func() {
+		feed.mu.Lock()
+		feed.done = true
+		close(feed.changed)
+		feed.mu.Unlock()
+		time.AfterFunc(finishedFeedTTL, func() { f.remove(id, feed) })
+	}
+}
+
+// leave drops a feed its subscriber gave up on before the execution started
+func (f *eventFeeds) leave(id string, feed *eventFeed) {
+	feed.mu.Lock()
+	started := feed.started
+	feed.mu.Unlock()
+	if !started {
+		f.remove(id, feed)
+	}
+}
+
+// remove drops a feed if it is still the one registered under id
+func (f *eventFeeds) remove(id string, feed *eventFeed) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.feeds[id] == feed {
+		delete(f.feeds, id)
+	}
+}
+
+// publish adds an event and wakes subscribers
+func (e *eventFeed) publish(event models.ServerSentEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done {
+		return
+	}
+	e.events = append(e.events, event)
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// since returns the events after the first n, whether the feed is finished,
+// and a channel closed on the next change
+func (e *eventFeed) since(n int) ([]models.ServerSentEvent, bool, <-chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.events[n:], e.done, e.changed
+}
+
+// handleExecutionEvents streams the events of an execution to the browser as
+// server-sent events: an "event" per streamed event, then "done"
+func (s *Server) handleExecutionEvents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	feed := s.events.get(id)
+	defer s.events.leave(id, feed)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sent := 0
+	for {
+		events, done, changed := feed.since(sent)
+		for _, event := range events {
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: event\ndata: %s\n\n", data)
+		}
+		sent += len(events)
+		if done {
+			fmt.Fprint(w, "event: done\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
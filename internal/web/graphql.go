@@ -0,0 +1,72 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handleFetchGraphQLSchema introspects the GraphQL endpoint of a request and caches its schema
+func (s *Server) handleFetchGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	req, err := s.app.GetRequest(id)
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+
+	schema, err := s.app.FetchGraphQLSchema(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schema)
+}
+
+// handleGraphQLSchema returns the cached schema of the GraphQL endpoint at ?url=
+func (s *Server) handleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+
+	schema, err := s.app.GetGraphQLSchema(url)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if schema == nil {
+		http.Error(w, "Schema not fetched", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schema)
+}
+
+// handleGraphQLComplete suggests completions at a byte offset of a GraphQL query
+func (s *Server) handleGraphQLComplete(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URL    string `json:"url"`
+		Query  string `json:"query"`
+		Offset int    `json:"offset"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	completion, err := s.app.CompleteGraphQL(body.URL, body.Query, body.Offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(completion)
+}
@@ -0,0 +1,28 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handleDescribeGRPC lists the services and methods of a gRPC request's server
+func (s *Server) handleDescribeGRPC(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	req, err := s.app.GetRequest(id)
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+
+	services, err := s.app.DescribeGRPC(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
@@ -1,23 +1,32 @@
 package web
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
 	"postgirl/internal/app"
+	"postgirl/internal/graphql"
 	"postgirl/internal/models"
 )
 
+// statusClientClosedRequest reports an execution that was cancelled before it completed
+const statusClientClosedRequest = 499
+
 // Server represents the web server
 type Server struct {
 	app    *app.Service
 	server *http.Server
+	events *eventFeeds
 }
 
 // NewServer creates a new web server
@@ -26,7 +35,8 @@ func NewServer(app *app.Service, port int, assets embed.FS) *Server {
 	
 	// Create server instance
 	s := &Server{
-		app: app,
+		app:    app,
+		events: newEventFeeds(),
 		server: &http.Server{
 			Addr:    fmt.Sprintf(":%d", port),
 			Handler: mux,
@@ -51,9 +61,30 @@ func (s *Server) setupRoutes(serveMux *http.ServeMux, assets embed.FS) {
 	api.HandleFunc("/requests", s.handleRequests).Methods("GET", "POST")
 	api.HandleFunc("/requests/{id}", s.handleRequest).Methods("GET", "PUT", "DELETE")
 	api.HandleFunc("/requests/{id}/execute", s.handleExecuteRequest).Methods("POST")
+	api.HandleFunc("/executions/{id}/cancel", s.handleCancelExecution).Methods("POST")
+	api.HandleFunc("/executions/{id}/events", s.handleExecutionEvents).Methods("GET")
+	
+	// WebSocket session routes
+	api.HandleFunc("/requests/{id}/websocket", s.handleOpenWebSocket).Methods("POST")
+	api.HandleFunc("/requests/{id}/websockets", s.handleWebSocketSessions).Methods("GET")
+	api.HandleFunc("/websockets/{id}", s.handleWebSocket).Methods("GET", "DELETE")
+	api.HandleFunc("/websockets/{id}/events", s.handleWebSocketEvents).Methods("GET")
+	api.HandleFunc("/websockets/{id}/messages", s.handleWebSocketMessage).Methods("POST")
+	api.HandleFunc("/websockets/{id}/ping", s.handleWebSocketPing).Methods("POST")
+	
+	// GraphQL routes
+	api.HandleFunc("/requests/{id}/graphql/schema", s.handleFetchGraphQLSchema).Methods("POST")
+	api.HandleFunc("/graphql/schema", s.handleGraphQLSchema).Methods("GET")
+	api.HandleFunc("/graphql/complete", s.handleGraphQLComplete).Methods("POST")
+
+	// gRPC routes
+	api.HandleFunc("/requests/{id}/grpc/services", s.handleDescribeGRPC).Methods("POST")
 	
 	// Response routes
 	api.HandleFunc("/requests/{id}/responses", s.handleResponses).Methods("GET")
+	api.HandleFunc("/responses/{id}", s.handleResponse).Methods("GET")
+	api.HandleFunc("/responses/{id}/body", s.handleResponseBody).Methods("GET")
+	api.HandleFunc("/responses/{id}/execution", s.handleExecution).Methods("GET")
 	
 	// Collection routes
 	api.HandleFunc("/collections", s.handleCollections).Methods("GET", "POST")
@@ -63,6 +94,18 @@ func (s *Server) setupRoutes(serveMux *http.ServeMux, assets embed.FS) {
 	api.HandleFunc("/environments", s.handleEnvironments).Methods("GET", "POST")
 	api.HandleFunc("/environments/{id}", s.handleEnvironment).Methods("GET", "PUT", "DELETE")
 	
+	// Cookie routes
+	api.HandleFunc("/cookies", s.handleCookies).Methods("GET", "POST", "PUT", "DELETE")
+	
+	// Certificate routes
+	api.HandleFunc("/certificates", s.handleCertificates).Methods("GET", "POST")
+	api.HandleFunc("/certificates/{id}", s.handleCertificate).Methods("PUT", "DELETE")
+	api.HandleFunc("/ca-certificates", s.handleCACertificates).Methods("GET", "POST")
+	api.HandleFunc("/ca-certificates/{id}", s.handleCACertificate).Methods("PUT", "DELETE")
+	
+	// Settings routes
+	api.HandleFunc("/settings/proxy", s.handleProxySettings).Methods("GET", "PUT")
+	
 	// Health check
 	router.HandleFunc("/health", s.handleHealth).Methods("GET")
 	
@@ -140,16 +183,56 @@ func (s *Server) handleExecuteRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// Track the execution so it can be cancelled by ID; it also stops if the
+	// client goes away
+	executionID := r.URL.Query().Get("execution_id")
+	if executionID == "" {
+		executionID = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	ctx, done, err := s.app.StartExecution(r.Context(), executionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	defer done()
+	w.Header().Set("X-Execution-ID", executionID)
+
+	// Relay streamed events to the browser while the request runs
+	feed, finish := s.events.start(executionID)
+	defer finish()
+	ctx = app.WithEventHandler(ctx, feed.publish)
+	
 	// Execute the request
-	resp, err := s.app.ExecuteRequest(req)
+	exec, err := s.app.ExecuteRequest(ctx, req)
+	if errors.Is(err, context.Canceled) {
+		http.Error(w, err.Error(), statusClientClosedRequest)
+		return
+	}
+	var invalid graphql.Errors
+	if errors.As(err, &invalid) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	
-	// Return the response
+	// Return the run: the response with the request as sent and the results
+	// of its scripts and tests
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(exec)
+}
+
+// handleCancelExecution cancels a running request execution
+func (s *Server) handleCancelExecution(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := s.app.CancelExecution(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // getRequests returns all requests
@@ -235,6 +318,77 @@ func (s *Server) handleResponses(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(responses)
 }
 
+// handleResponse returns a single response
+func (s *Server) handleResponse(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	resp, err := s.app.GetResponse(id)
+	if err != nil {
+		http.Error(w, "Response not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleExecution returns the run a response came from: the request as sent
+// and the results of its scripts and tests
+func (s *Server) handleExecution(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	exec, err := s.app.GetExecution(id)
+	if err != nil {
+		http.Error(w, "Execution not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exec)
+}
+
+// handleResponseBody serves the raw bytes of a response body with its content
+// type; ?download=1 asks the browser to save it as a file, and ?wire=1 serves
+// the body as it was received, still compressed
+func (s *Server) handleResponseBody(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	resp, err := s.app.GetResponse(id)
+	if err != nil {
+		http.Error(w, "Response not found", http.StatusNotFound)
+		return
+	}
+	wire := r.URL.Query().Get("wire") != "" && resp.ContentEncoding != ""
+	var body []byte
+	if wire {
+		body, err = s.app.ResponseWireBody(resp)
+	} else {
+		body, err = s.app.ResponseBody(resp)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := resp.ContentType
+	if contentType == "" || wire {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	// Never let a saved response run as part of this origin
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	if r.URL.Query().Get("download") != "" {
+		filename := "response-" + resp.ID
+		if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+			filename += exts[0]
+		}
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	w.Write(body)
+}
+
 // handleCollections handles collection operations
 func (s *Server) handleCollections(w http.ResponseWriter, r *http.Request) {
 	// TODO: Implement collection operations
@@ -250,13 +404,308 @@ func (s *Server) handleCollection(w http.ResponseWriter, r *http.Request) {
 
 // handleEnvironments handles environment operations
 func (s *Server) handleEnvironments(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement environment operations
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode([]models.Environment{})
+	switch r.Method {
+	case "GET":
+		environments := s.app.ListEnvironments()
+		views := make([]*models.Environment, len(environments))
+		for i, env := range environments {
+			views[i] = environmentView(env)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(views)
+	case "POST":
+		// TODO: Implement environment creation
+		http.Error(w, "Not implemented", http.StatusNotImplemented)
+	}
 }
 
 // handleEnvironment handles individual environment operations
 func (s *Server) handleEnvironment(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement environment operations
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	id := mux.Vars(r)["id"]
+
+	switch r.Method {
+	case "GET":
+		env, err := s.app.GetEnvironment(id)
+		if err != nil {
+			http.Error(w, "Environment not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(environmentView(env))
+	case "PUT":
+		s.updateEnvironment(w, r, id)
+	case "DELETE":
+		// TODO: Implement environment deletion
+		http.Error(w, "Not implemented", http.StatusNotImplemented)
+	}
+}
+
+// environmentView returns an environment as sent to clients, without its
+// proxy password
+func environmentView(env *models.Environment) *models.Environment {
+	view := *env
+	view.Proxy = env.Proxy.WithoutPassword()
+	return &view
+}
+
+// updateEnvironment replaces an environment's variables, including whether
+// each one is enabled, its proxy override, host overrides and DNS server, and
+// saves it
+func (s *Server) updateEnvironment(w http.ResponseWriter, r *http.Request, id string) {
+	env, err := s.app.GetEnvironment(id)
+	if err != nil {
+		http.Error(w, "Environment not found", http.StatusNotFound)
+		return
+	}
+
+	// Hosts and DNSServer are pointers so that leaving them out keeps them
+	// while sending them empty clears them. Proxy is kept raw for the same
+	// reason, as null there removes the override.
+	var update struct {
+		models.Environment
+		Hosts     *map[string]string `json:"hosts"`
+		DNSServer *string            `json:"dns_server"`
+		Proxy     json.RawMessage    `json:"proxy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	updated := *env
+	if update.Name != "" {
+		updated.Name = update.Name
+	}
+	if update.Variables != nil {
+		updated.Variables = update.Variables
+	}
+	if update.Hosts != nil {
+		updated.Hosts = *update.Hosts
+	}
+	if update.DNSServer != nil {
+		updated.DNSServer = *update.DNSServer
+	}
+	if update.Proxy != nil {
+		var proxy *models.ProxyConfig
+		if err := json.Unmarshal(update.Proxy, &proxy); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		proxy.KeepPassword(env.Proxy)
+		updated.Proxy = proxy
+	}
+	if err := s.app.ValidateEnvironment(&updated); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.app.SaveEnvironmentToDB(&updated); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.app.SetEnvironment(&updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(environmentView(&updated))
+}
+
+// handleCookies handles cookie jar operations
+func (s *Server) handleCookies(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.getCookies(w, r)
+	case "POST", "PUT":
+		s.saveCookie(w, r)
+	case "DELETE":
+		s.deleteCookies(w, r)
+	}
+}
+
+// getCookies returns the cookies in the jar, optionally filtered by domain
+func (s *Server) getCookies(w http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	
+	cookies := []models.Cookie{}
+	for _, cookie := range s.app.ListCookies() {
+		if domain == "" || cookie.Domain == domain {
+			cookies = append(cookies, cookie)
+		}
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cookies)
+}
+
+// saveCookie adds or replaces a cookie
+func (s *Server) saveCookie(w http.ResponseWriter, r *http.Request) {
+	var cookie models.Cookie
+	if err := json.NewDecoder(r.Body).Decode(&cookie); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	
+	if err := s.app.SaveCookie(cookie); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cookie)
+}
+
+// deleteCookies deletes one cookie when domain, path and name are given,
+// all cookies for a domain when only the domain is given, or every cookie
+func (s *Server) deleteCookies(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	domain, path, name := query.Get("domain"), query.Get("path"), query.Get("name")
+	
+	var err error
+	if name != "" {
+		if path == "" {
+			path = "/"
+		}
+		err = s.app.DeleteCookie(domain, path, name)
+	} else {
+		err = s.app.ClearCookies(domain)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCertificates lists and creates client certificates
+func (s *Server) handleCertificates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		// Private keys, bundles and passphrases are never sent back
+		certs := s.app.ListCertificates()
+		summaries := make([]models.CertificateSummary, len(certs))
+		for i := range certs {
+			summaries[i] = certs[i].Summary()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summaries)
+	case "POST":
+		s.saveCertificate(w, r, "")
+	}
+}
+
+// handleCertificate updates and deletes a client certificate
+func (s *Server) handleCertificate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	
+	switch r.Method {
+	case "PUT":
+		s.saveCertificate(w, r, id)
+	case "DELETE":
+		if err := s.app.DeleteCertificate(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// saveCertificate saves a client certificate from the request body
+func (s *Server) saveCertificate(w http.ResponseWriter, r *http.Request, id string) {
+	var cert models.Certificate
+	if err := json.NewDecoder(r.Body).Decode(&cert); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	cert.ID = id
+	
+	err := s.app.SaveCertificate(&cert)
+	if errors.Is(err, app.ErrCertificateNotFound) {
+		http.Error(w, "Certificate not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cert.Summary())
+}
+
+// handleCACertificates lists and creates trusted CA bundles
+func (s *Server) handleCACertificates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.app.ListCACertificates())
+	case "POST":
+		s.saveCACertificate(w, r, "")
+	}
+}
+
+// handleCACertificate updates and deletes a trusted CA bundle
+func (s *Server) handleCACertificate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	
+	switch r.Method {
+	case "PUT":
+		s.saveCACertificate(w, r, id)
+	case "DELETE":
+		if err := s.app.DeleteCACertificate(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// saveCACertificate saves a CA bundle from the request body
+func (s *Server) saveCACertificate(w http.ResponseWriter, r *http.Request, id string) {
+	var ca models.CACertificate
+	if err := json.NewDecoder(r.Body).Decode(&ca); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	ca.ID = id
+	
+	err := s.app.SaveCACertificate(&ca)
+	if errors.Is(err, app.ErrCertificateNotFound) {
+		http.Error(w, "Certificate not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ca)
+}
+
+// handleProxySettings returns or replaces the global proxy settings
+func (s *Server) handleProxySettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		proxy := s.app.ProxySettings()
+		if proxy == nil {
+			proxy = &models.ProxyConfig{Mode: "system"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(proxy.WithoutPassword())
+	case "PUT":
+		var proxy models.ProxyConfig
+		if err := json.NewDecoder(r.Body).Decode(&proxy); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		
+		if err := s.app.SaveProxySettings(&proxy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(proxy.WithoutPassword())
+	}
 }
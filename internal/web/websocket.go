@@ -0,0 +1,147 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handleOpenWebSocket opens a WebSocket session for a request
+func (s *Server) handleOpenWebSocket(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	req, err := s.app.GetRequest(id)
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+
+	session, err := s.app.OpenWebSocket(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// handleWebSocketSessions returns the saved WebSocket sessions of a request
+func (s *Server) handleWebSocketSessions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	sessions, err := s.app.ListWebSocketSessions(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// handleWebSocket returns or closes a WebSocket session
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	switch r.Method {
+	case "GET":
+		session, err := s.app.GetWebSocketSession(id)
+		if err != nil {
+			http.Error(w, "WebSocket session not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(session)
+	case "DELETE":
+		if err := s.app.CloseWebSocket(id); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleWebSocketMessage sends a text message on an open WebSocket session
+func (s *Server) handleWebSocketMessage(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var message struct {
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.app.SendWebSocketMessage(id, message.Data); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWebSocketPing sends a ping on an open WebSocket session
+func (s *Server) handleWebSocketPing(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := s.app.PingWebSocket(id); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWebSocketEvents streams the message log of a WebSocket session to the
+// browser as server-sent events: a "message" per logged message, then
+// "closed" with the session once it has closed
+func (s *Server) handleWebSocketEvents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	if _, _, _, err := s.app.WatchWebSocket(id, 0); err != nil {
+		http.Error(w, "WebSocket session not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sent := 0
+	for {
+		messages, open, changed, err := s.app.WatchWebSocket(id, sent)
+		if err != nil {
+			return
+		}
+		for _, message := range messages {
+			data, _ := json.Marshal(message)
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		}
+		sent += len(messages)
+		if !open {
+			if session, err := s.app.GetWebSocketSession(id); err == nil {
+				data, _ := json.Marshal(session)
+				fmt.Fprintf(w, "event: closed\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}